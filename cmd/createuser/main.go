@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/config"
+	"github.com/kitakitabauer/gin-sample-app/internal/database"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+// createuser registers a user who can log in with POST /auth/login.
+// The password is read from the first line of stdin so that it does not
+// show up in the process list or shell history:
+//
+//	echo 'correct horse battery staple' | go run ./cmd/createuser -username alice -role editor
+func main() {
+	var (
+		username string
+		role     string
+		timeout  time.Duration
+	)
+
+	flag.StringVar(&username, "username", "", "name used to log in")
+	flag.StringVar(&role, "role", "author", "role of the user: admin, editor or author")
+	flag.DurationVar(&timeout, "timeout", 5*time.Second, "database connection timeout")
+	flag.Parse()
+
+	if username == "" {
+		log.Fatal("-username is required")
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		log.Fatalf("failed to read password from stdin: %v", err)
+	}
+	password = strings.TrimRight(password, "\r\n")
+
+	config.Load()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	db, err := database.Open(ctx, database.Config{
+		Driver: config.AppConfig.DatabaseDriver,
+		DSN:    config.AppConfig.DatabaseDSN,
+	})
+	if err != nil {
+		log.Fatalf("failed to connect database: %v", err)
+	}
+	defer db.Close()
+
+	users := repository.NewSQLUserRepository(db, config.AppConfig.DatabaseDriver)
+	user, err := service.NewAuthService(users, service.AuthConfig{}).CreateUser(ctx, username, password, role)
+	if err != nil {
+		log.Fatalf("failed to create user: %v", err)
+	}
+	fmt.Fprintf(os.Stdout, "created user %q (id=%d, role=%s)\n", user.Username, user.ID, user.Role)
+}
@@ -2,17 +2,80 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Env            string
-	Port           string
-	LogLevel       string
+	Env      string
+	Port     string
+	LogLevel string
+	// APIKey is an optional bootstrap key granted every scope, used to issue
+	// the first stored API keys.
 	APIKey         string
 	DatabaseDriver string
 	DatabaseDSN    string
+	// AuthDisabled lets unauthenticated callers use protected post routes,
+	// for local development only. Admin routes still require credentials.
+	AuthDisabled bool
+	// PublishInterval is how often scheduled posts are checked; 0 disables the scheduler.
+	PublishInterval time.Duration
+	// JWTSecret signs access and refresh tokens. When empty a random secret
+	// is generated at startup, so tokens do not survive a restart.
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// APIKeyEncryptionKey encrypts the HMAC signing keys stored with API
+	// keys. When empty a random key is generated at startup, so keys issued
+	// before a restart can no longer sign requests.
+	APIKeyEncryptionKey string
+	// HMACClockSkew is how far the timestamp of an HMAC signed request may
+	// be from the server clock.
+	HMACClockSkew time.Duration
+	// RateLimitRead and RateLimitWrite are how many reads (GET, HEAD and
+	// OPTIONS) and writes each client may make per minute; 0 disables the limit.
+	RateLimitRead  int
+	RateLimitWrite int
+	// RateLimitStore is "memory" for per-process limits or "sql" to share
+	// limits between replicas using the same database.
+	RateLimitStore string
+	// TrustedProxies are the proxy IPs and CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers are believed when resolving the client IP used for
+	// rate limiting and logging. By default no proxy is trusted and the
+	// remote address is used.
+	TrustedProxies []string
+	// IdempotencyTTL is how long responses to requests with an
+	// Idempotency-Key are kept for replay.
+	IdempotencyTTL time.Duration
+	// MetricsEnabled exposes Prometheus metrics on /metrics.
+	MetricsEnabled bool
+	// MetricsAddr serves /metrics on a separate listener (for example
+	// ":9090") without authentication. When empty, /metrics is served on the
+	// main port and requires an admin with the admin:metrics scope.
+	MetricsAddr string
+	// TracingExporter is where OpenTelemetry spans are sent: "none" (the
+	// default) only propagates traceparent, "otlp" exports over OTLP/HTTP and
+	// "stdout" writes JSON spans to TracingOutput.
+	TracingExporter string
+	// TracingEndpoint is the OTLP/HTTP collector host and port; when empty
+	// the standard OTEL_EXPORTER_OTLP_* variables apply.
+	TracingEndpoint string
+	TracingInsecure bool
+	// TracingOutput is the file the stdout exporter appends to; standard
+	// output is used when empty.
+	TracingOutput string
+	// TracingSampleRatio is the fraction of new traces that are recorded.
+	TracingSampleRatio float64
+	// MaxBodyBytes is the largest request body accepted; larger requests are
+	// rejected with 413. 0 disables the limit.
+	MaxBodyBytes int
+	// PostTitleMaxLength and PostContentMaxLength are the most characters a
+	// post title and content may have after normalisation; 0 disables the limit.
+	PostTitleMaxLength   int
+	PostContentMaxLength int
 }
 
 var AppConfig *Config
@@ -21,12 +84,34 @@ func Load() {
 	_ = godotenv.Load()
 
 	AppConfig = &Config{
-		Env:            getEnv("APP_ENV", "dev"),
-		Port:           getEnv("PORT", "8080"),
-		LogLevel:       getEnv("LOG_LEVEL", "debug"),
-		APIKey:         os.Getenv("API_KEY"),
-		DatabaseDriver: getEnv("DB_DRIVER", "sqlite"),
-		DatabaseDSN:    getEnv("DB_DSN", "file:tmp/app.db?_foreign_keys=1"),
+		Env:                  getEnv("APP_ENV", "dev"),
+		Port:                 getEnv("PORT", "8080"),
+		LogLevel:             getEnv("LOG_LEVEL", "debug"),
+		APIKey:               os.Getenv("API_KEY"),
+		DatabaseDriver:       getEnv("DB_DRIVER", "sqlite"),
+		DatabaseDSN:          getEnv("DB_DSN", "file:tmp/app.db?_foreign_keys=1"),
+		AuthDisabled:         getBool("AUTH_DISABLED", false),
+		PublishInterval:      getDuration("PUBLISH_SCHEDULER_INTERVAL", 30*time.Second),
+		JWTSecret:            os.Getenv("JWT_SECRET"),
+		AccessTokenTTL:       getDuration("JWT_ACCESS_TTL", 15*time.Minute),
+		RefreshTokenTTL:      getDuration("JWT_REFRESH_TTL", 7*24*time.Hour),
+		APIKeyEncryptionKey:  os.Getenv("API_KEY_ENCRYPTION_KEY"),
+		HMACClockSkew:        getDuration("HMAC_CLOCK_SKEW", 5*time.Minute),
+		RateLimitRead:        getInt("RATE_LIMIT_READ", 300),
+		RateLimitWrite:       getInt("RATE_LIMIT_WRITE", 60),
+		RateLimitStore:       getEnv("RATE_LIMIT_STORE", "memory"),
+		TrustedProxies:       getList("TRUSTED_PROXIES"),
+		IdempotencyTTL:       getDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		MetricsEnabled:       getBool("METRICS_ENABLED", true),
+		MetricsAddr:          os.Getenv("METRICS_ADDR"),
+		TracingExporter:      getEnv("TRACING_EXPORTER", "none"),
+		TracingEndpoint:      os.Getenv("TRACING_OTLP_ENDPOINT"),
+		TracingInsecure:      getBool("TRACING_OTLP_INSECURE", false),
+		TracingOutput:        os.Getenv("TRACING_OUTPUT"),
+		TracingSampleRatio:   getFloat("TRACING_SAMPLE_RATIO", 1),
+		MaxBodyBytes:         getInt("MAX_BODY_BYTES", 1<<20),
+		PostTitleMaxLength:   getInt("POST_TITLE_MAX_LENGTH", 200),
+		PostContentMaxLength: getInt("POST_CONTENT_MAX_LENGTH", 100000),
 	}
 }
 
@@ -36,3 +121,50 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getList splits a comma separated variable, dropping empty entries.
+func getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	if val := os.Getenv(key); val != "" {
+		if d, err := time.ParseDuration(val); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+func getInt(key string, fallback int) int {
+	if val := os.Getenv(key); val != "" {
+		if n, err := strconv.Atoi(val); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getBool(key string, fallback bool) bool {
+	if val := os.Getenv(key); val != "" {
+		if b, err := strconv.ParseBool(val); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func getFloat(key string, fallback float64) float64 {
+	if val := os.Getenv(key); val != "" {
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
@@ -2,32 +2,43 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
 	"github.com/kitakitabauer/gin-sample-app/logger"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
 	"go.uber.org/zap"
 )
 
-type AdminHandler struct{}
+type AdminHandler struct {
+	posts *service.PostService
+}
 
-func NewAdminHandler() *AdminHandler {
-	return &AdminHandler{}
+func NewAdminHandler(posts *service.PostService) *AdminHandler {
+	return &AdminHandler{posts: posts}
 }
 
 func (h *AdminHandler) RegisterRoutes(router *gin.Engine) {
 	admin := router.Group("/admin")
-	admin.Use(middleware.RequireAPIKey())
 
-	admin.GET("/log-level", h.getLogLevel)
-	admin.PUT("/log-level", h.updateLogLevel)
+	logLevel := admin.Group("/log-level", middleware.RequireAuth(model.APIKeyScopeAdminLogLevel), middleware.RequireAdmin())
+	logLevel.GET("", h.getLogLevel)
+	logLevel.PUT("", h.updateLogLevel)
+
+	posts := admin.Group("/posts", middleware.RequireAuth(model.APIKeyScopeAdminPosts), middleware.RequireAdmin())
+	posts.GET("/trash", h.listTrash)
+	posts.POST("/:id/restore", h.restorePost)
+	posts.DELETE("/:id/purge", h.purgePost)
 }
 
 func (h *AdminHandler) getLogLevel(c *gin.Context) {
 	level, err := logger.CurrentLevel()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, "failed to get log level", err)
 		return
 	}
 
@@ -35,34 +46,38 @@ func (h *AdminHandler) getLogLevel(c *gin.Context) {
 }
 
 type updateLogLevelRequest struct {
-	Level string `json:"level" binding:"required"`
+	Level string `json:"level"`
 }
 
 func (h *AdminHandler) updateLogLevel(c *gin.Context) {
 	var req updateLogLevelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		invalidBody(c, err)
+		return
+	}
+	if req.Level == "" {
+		invalidField(c, "level", "required", "level is required")
 		return
 	}
 
 	oldLevel, err := logger.CurrentLevel()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, "failed to get log level", err)
 		return
 	}
 
 	if err := logger.SetLevel(req.Level); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		invalidField(c, "level", "invalid", err.Error())
 		return
 	}
 
 	newLevel, err := logger.CurrentLevel()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		internalError(c, "failed to get log level", err)
 		return
 	}
 
-	logger.Log.Info("log level updated",
+	logger.FromContext(c.Request.Context()).Info("log level updated",
 		zap.String("old_level", oldLevel.String()),
 		zap.String("new_level", newLevel.String()),
 		zap.String("requested_level", req.Level),
@@ -74,3 +89,64 @@ func (h *AdminHandler) updateLogLevel(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"level": newLevel.String()})
 }
+
+func (h *AdminHandler) listTrash(c *gin.Context) {
+	query := repository.PostQuery{Cursor: c.Query("cursor")}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			invalidField(c, "limit", "invalid", "invalid limit")
+			return
+		}
+		query.Limit = limit
+	}
+
+	page, err := h.posts.Trash(c.Request.Context(), query)
+	if err != nil {
+		respondError(c, err, "failed to list trash")
+		return
+	}
+
+	resp := gin.H{"posts": page.Posts, "next_cursor": nil}
+	if page.NextCursor != "" {
+		resp["next_cursor"] = page.NextCursor
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AdminHandler) restorePost(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	post, err := h.posts.Restore(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed to restore post")
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+func (h *AdminHandler) purgePost(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	if err := h.posts.Purge(c.Request.Context(), id); err != nil {
+		respondError(c, err, "failed to purge post")
+		return
+	}
+
+	logger.FromContext(c.Request.Context()).Info("post purged",
+		zap.Int64("post_id", id),
+		zap.String("actor", middleware.Actor(c)),
+		zap.String("client_ip", c.ClientIP()),
+	)
+
+	c.Status(http.StatusNoContent)
+}
@@ -2,16 +2,24 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/kitakitabauer/gin-sample-app/config"
+	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
 	"github.com/kitakitabauer/gin-sample-app/logger"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
 )
 
 func setAdminAPIKey(t *testing.T, key string) func() {
@@ -33,10 +41,17 @@ func initLoggerForTest(t *testing.T, level string) {
 }
 
 func setupAdminRouter(t *testing.T) *gin.Engine {
+	router, _ := setupAdminRouterWithService(t)
+	return router
+}
+
+func setupAdminRouterWithService(t *testing.T) (*gin.Engine, *service.PostService) {
 	gin.SetMode(gin.TestMode)
+	postRepository := repository.NewInMemoryPostRepository()
+	svc := service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))
 	router := gin.New()
-	NewAdminHandler().RegisterRoutes(router)
-	return router
+	NewAdminHandler(svc).RegisterRoutes(router)
+	return router, svc
 }
 
 func TestAdminHandler_GetLogLevel(t *testing.T) {
@@ -108,12 +123,12 @@ func TestAdminHandler_UpdateLogLevel_InvalidPayload(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, resp.Code)
 	}
 
-	var body map[string]string
+	var body problem.Details
 	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
 		t.Fatalf("failed to unmarshal response: %v", err)
 	}
-	if body["error"] == "" {
-		t.Fatal("expected error message in response")
+	if body.Code != problem.CodeValidationFailed || len(body.Errors) != 1 || body.Errors[0].Field != "level" {
+		t.Fatalf("expected a validation problem for level, got %s", resp.Body.String())
 	}
 }
 
@@ -131,3 +146,103 @@ func TestAdminHandler_Unauthorized(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, resp.Code)
 	}
 }
+
+func TestAdminHandler_TrashRestorePurge(t *testing.T) {
+	t.Cleanup(setAdminAPIKey(t, "secret"))
+	initLoggerForTest(t, "info")
+
+	router, svc := setupAdminRouterWithService(t)
+	ctx := context.Background()
+
+	post, err := svc.Create(ctx, service.CreatePostInput{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("failed to create post: %v", err)
+	}
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-API-Key", "secret")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		return resp
+	}
+
+	if resp := do(http.MethodPost, "/admin/posts/1/restore"); resp.Code != http.StatusConflict {
+		t.Fatalf("expected status %d restoring an active post, got %d", http.StatusConflict, resp.Code)
+	}
+
+	if err := svc.Delete(ctx, post.ID); err != nil {
+		t.Fatalf("failed to delete post: %v", err)
+	}
+
+	resp := do(http.MethodGet, "/admin/posts/trash")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.Code)
+	}
+	var trash struct {
+		Posts      []model.Post `json:"posts"`
+		NextCursor *string      `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &trash); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(trash.Posts) != 1 || trash.Posts[0].DeletedAt == nil || trash.NextCursor != nil {
+		t.Fatalf("expected deleted post in trash, got %s", resp.Body.String())
+	}
+	for _, path := range []string{"/admin/posts/trash?limit=101", "/admin/posts/trash?limit=x", "/admin/posts/trash?cursor=%25%25"} {
+		if resp := do(http.MethodGet, path); resp.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", path, http.StatusBadRequest, resp.Code)
+		}
+	}
+
+	if resp := do(http.MethodPost, "/admin/posts/1/restore"); resp.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.Code)
+	}
+	if _, err := svc.Get(ctx, post.ID); err != nil {
+		t.Fatalf("expected restored post to be visible, got %v", err)
+	}
+
+	if resp := do(http.MethodDelete, "/admin/posts/1/purge"); resp.Code != http.StatusConflict {
+		t.Fatalf("expected status %d purging an active post, got %d", http.StatusConflict, resp.Code)
+	}
+	if err := svc.Delete(ctx, post.ID); err != nil {
+		t.Fatalf("failed to delete post: %v", err)
+	}
+	if resp := do(http.MethodDelete, "/admin/posts/1/purge"); resp.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, resp.Code)
+	}
+	if resp := do(http.MethodPost, "/admin/posts/1/restore"); resp.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d after purge, got %d", http.StatusNotFound, resp.Code)
+	}
+}
+
+func TestAdminHandler_UpdateLogLevel_LogsRequestID(t *testing.T) {
+	t.Cleanup(setAdminAPIKey(t, "secret"))
+	initLoggerForTest(t, "info")
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	old := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = old })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	postRepository := repository.NewInMemoryPostRepository()
+	NewAdminHandler(service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))).RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodPut, "/admin/log-level", bytes.NewBufferString(`{"level":"debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", "secret")
+	req.Header.Set(middleware.RequestIDHeader, "req-123")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, resp.Code, resp.Body.String())
+	}
+	entries := logs.FilterMessage("log level updated").All()
+	if len(entries) != 1 || entries[0].ContextMap()["request_id"] != "req-123" {
+		t.Fatalf("expected the log line to carry the request ID, got %+v", entries)
+	}
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+type APIKeyHandler struct {
+	service *service.APIKeyService
+}
+
+func NewAPIKeyHandler(service *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+func (h *APIKeyHandler) RegisterRoutes(router *gin.Engine) {
+	keys := router.Group("/admin/api-keys", middleware.RequireAuth(model.APIKeyScopeAdminAPIKeys), middleware.RequireAdmin())
+	keys.POST("", h.createAPIKey)
+	keys.GET("", h.listAPIKeys)
+	keys.DELETE("/:id", h.revokeAPIKey)
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// createAPIKeyResponseは作成したAPIキーとキー本体です。キー本体を返すのはこの応答だけです。
+type createAPIKeyResponse struct {
+	model.APIKey
+	Secret string `json:"secret"`
+}
+
+func (h *APIKeyHandler) createAPIKey(c *gin.Context) {
+	var req createAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	key, secret, err := h.service.Create(c.Request.Context(), service.CreateAPIKeyInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		respondError(c, err, "failed to create api key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, createAPIKeyResponse{APIKey: key, Secret: secret})
+}
+
+func (h *APIKeyHandler) listAPIKeys(c *gin.Context) {
+	keys, err := h.service.List(c.Request.Context())
+	if err != nil {
+		internalError(c, "failed to list api keys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+func (h *APIKeyHandler) revokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	if _, err := h.service.Revoke(c.Request.Context(), id); err != nil {
+		respondError(c, err, "failed to revoke api key")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
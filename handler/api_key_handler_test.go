@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/config"
+	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+func TestAPIKeyHandler_CreateUseAndRevoke(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+	gin.SetMode(gin.TestMode)
+
+	keyService := service.NewAPIKeyService(repository.NewInMemoryAPIKeyRepository())
+	postRepository := repository.NewInMemoryPostRepository()
+	postService := service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))
+
+	router := gin.New()
+	router.Use(middleware.AuthenticateAPIKey(keyService))
+	NewAPIKeyHandler(keyService).RegisterRoutes(router)
+	NewPostHandler(postService).RegisterRoutes(router)
+
+	send := func(method, path, payload, key string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(http.MethodPost, "/admin/api-keys", `{"name":"ci","scopes":["posts:write"]}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := send(http.MethodPost, "/admin/api-keys", `{"name":"ci","scopes":["posts:read"]}`, "secret"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for unknown scope, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	rec := send(http.MethodPost, "/admin/api-keys", `{"name":"ci","scopes":["posts:write"]}`, "secret")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var created struct {
+		ID     int64    `json:"id"`
+		Prefix string   `json:"prefix"`
+		Scopes []string `json:"scopes"`
+		Secret string   `json:"secret"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if created.Secret == "" || created.Prefix == "" {
+		t.Fatalf("expected secret and prefix, got %s", rec.Body.String())
+	}
+
+	if rec := send(http.MethodPost, "/posts", `{"title":"t","content":"c","author":"a"}`, created.Secret); rec.Code != http.StatusCreated {
+		t.Fatalf("expected scoped key to create posts, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = send(http.MethodGet, "/admin/api-keys", "", created.Secret)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d without admin:api-keys scope, got %d", http.StatusForbidden, rec.Code)
+	}
+	var forbidden problem.Details
+	if err := json.Unmarshal(rec.Body.Bytes(), &forbidden); err != nil || forbidden.Code != "insufficient_scope" {
+		t.Fatalf("expected code insufficient_scope, got %s", rec.Body.String())
+	}
+
+	rec = send(http.MethodGet, "/admin/api-keys", "", "secret")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var listed struct {
+		APIKeys []map[string]any `json:"api_keys"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(listed.APIKeys) != 1 || listed.APIKeys[0]["last_used_at"] == nil {
+		t.Fatalf("expected one used key, got %s", rec.Body.String())
+	}
+	if _, ok := listed.APIKeys[0]["secret"]; ok {
+		t.Fatalf("secret must not be listed: %s", rec.Body.String())
+	}
+
+	path := "/admin/api-keys/" + strconv.FormatInt(created.ID, 10)
+	if rec := send(http.MethodDelete, path, "", "secret"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := send(http.MethodDelete, "/admin/api-keys/999", "", "secret"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+	if rec := send(http.MethodPost, "/posts", `{"title":"t","content":"c","author":"a"}`, created.Secret); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked key to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestAPIKeyHandler_ScopesPerRoute(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+	initLoggerForTest(t, "info")
+	gin.SetMode(gin.TestMode)
+
+	keyService := service.NewAPIKeyService(repository.NewInMemoryAPIKeyRepository())
+	postRepository := repository.NewInMemoryPostRepository()
+	postService := service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))
+	_, logKey, err := keyService.Create(t.Context(), service.CreateAPIKeyInput{Name: "ops", Scopes: []string{model.APIKeyScopeAdminLogLevel}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.AuthenticateAPIKey(keyService))
+	NewAdminHandler(postService).RegisterRoutes(router)
+	NewPostHandler(postService).RegisterRoutes(router)
+
+	get := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-API-Key", logKey)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get("/admin/log-level"); code != http.StatusOK {
+		t.Fatalf("expected log-level key to read log level, got %d", code)
+	}
+	if code := get("/admin/posts/trash"); code != http.StatusForbidden {
+		t.Fatalf("expected status %d for trash without admin:posts, got %d", http.StatusForbidden, code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(`{"title":"t","content":"c","author":"a"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", logKey)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for post creation without posts:write, got %d", http.StatusForbidden, rec.Code)
+	}
+}
+
+func TestAPIKeyHandler_RequiresCredentialsWithoutBootstrapKey(t *testing.T) {
+	original := config.AppConfig
+	config.AppConfig = &config.Config{}
+	t.Cleanup(func() { config.AppConfig = original })
+	gin.SetMode(gin.TestMode)
+
+	keyService := service.NewAPIKeyService(repository.NewInMemoryAPIKeyRepository())
+	postRepository := repository.NewInMemoryPostRepository()
+	postService := service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))
+	_, adminKey, err := keyService.Create(t.Context(), service.CreateAPIKeyInput{Name: "ops", Scopes: []string{model.APIKeyScopeAdminAPIKeys}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middleware.AuthenticateAPIKey(keyService))
+	NewAPIKeyHandler(keyService).RegisterRoutes(router)
+	NewPostHandler(postService).RegisterRoutes(router)
+
+	send := func(method, path, payload, key string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// An unset API_KEY no longer disables authentication.
+	if code := send(http.MethodPost, "/admin/api-keys", `{"name":"ci","scopes":["posts:write"]}`, ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous admin request to be rejected, got %d", code)
+	}
+	if code := send(http.MethodPost, "/posts", `{"title":"t","content":"c","author":"a"}`, ""); code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous write to be rejected, got %d", code)
+	}
+	if code := send(http.MethodGet, "/admin/api-keys", "", adminKey); code != http.StatusOK {
+		t.Fatalf("expected stored key to be accepted, got %d", code)
+	}
+
+	// AUTH_DISABLED admits anonymous writes but never makes the caller an admin.
+	config.AppConfig = &config.Config{AuthDisabled: true}
+	if code := send(http.MethodPost, "/posts", `{"title":"t","content":"c","author":"a"}`, ""); code != http.StatusCreated {
+		t.Fatalf("expected anonymous write with auth disabled, got %d", code)
+	}
+	if code := send(http.MethodGet, "/admin/api-keys", "", ""); code != http.StatusForbidden {
+		t.Fatalf("expected anonymous admin request to be forbidden, got %d", code)
+	}
+}
@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/config"
+	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+func TestAuthHandler_LoginAndCreateOwnedPost(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+	gin.SetMode(gin.TestMode)
+
+	authService := service.NewAuthService(repository.NewInMemoryUserRepository(), service.AuthConfig{
+		Secret:     []byte("test-secret"),
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	})
+	user, err := authService.CreateUser(context.Background(), "alice", "password", "")
+	if err != nil {
+		t.Fatalf("CreateUser returned error: %v", err)
+	}
+	postRepository := repository.NewInMemoryPostRepository()
+	postService := service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))
+
+	router := gin.New()
+	router.Use(middleware.Authenticate(authService))
+	NewAuthHandler(authService).RegisterRoutes(router)
+	NewPostHandler(postService).RegisterRoutes(router)
+
+	send := func(path, payload, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send("/auth/login", `{"username":"alice","password":"nope"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	rec := send("/auth/login", `{"username":"alice","password":"password"}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var tokens tokenResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &tokens); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if tokens.TokenType != "Bearer" || tokens.ExpiresIn != 60 || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("unexpected tokens: %+v", tokens)
+	}
+
+	rec = send("/posts", `{"title":"t","content":"c","author":"a"}`, tokens.AccessToken)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var post model.Post
+	if err := json.Unmarshal(rec.Body.Bytes(), &post); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if post.OwnerID != user.ID {
+		t.Fatalf("expected post owned by %d, got %d", user.ID, post.OwnerID)
+	}
+
+	if rec := send("/posts", `{"title":"t","content":"c","author":"a"}`, "not-a-token"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d for invalid token, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	if rec := send("/auth/refresh", `{"refresh_token":"`+tokens.AccessToken+`"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d when refreshing with access token, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	rec = send("/auth/refresh", `{"refresh_token":"`+tokens.RefreshToken+`"}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+}
+
+func TestAuthHandler_RoleBasedAuthorization(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+	gin.SetMode(gin.TestMode)
+
+	authService := service.NewAuthService(repository.NewInMemoryUserRepository(), service.AuthConfig{
+		Secret:     []byte("test-secret"),
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	})
+	postRepository := repository.NewInMemoryPostRepository()
+	postService := service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))
+
+	router := gin.New()
+	router.Use(middleware.Authenticate(authService))
+	NewPostHandler(postService).RegisterRoutes(router)
+	NewAdminHandler(postService).RegisterRoutes(router)
+
+	tokens := make(map[string]string)
+	for _, u := range []struct{ name, role string }{
+		{"alice", model.UserRoleAuthor},
+		{"bob", model.UserRoleAuthor},
+		{"erin", model.UserRoleEditor},
+		{"root", model.UserRoleAdmin},
+	} {
+		if _, err := authService.CreateUser(context.Background(), u.name, "password", u.role); err != nil {
+			t.Fatalf("CreateUser returned error: %v", err)
+		}
+		pair, err := authService.Login(context.Background(), u.name, "password")
+		if err != nil {
+			t.Fatalf("Login returned error: %v", err)
+		}
+		tokens[u.name] = pair.AccessToken
+	}
+
+	send := func(method, path, payload, user string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tokens[user])
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/posts", `{"title":"t","content":"c","author":"a"}`, "alice")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var post model.Post
+	if err := json.Unmarshal(rec.Body.Bytes(), &post); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	path := "/posts/" + strconv.FormatInt(post.ID, 10)
+
+	rec = send(http.MethodPatch, path, `{"title":"bob"}`, "bob")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for other author, got %d", http.StatusForbidden, rec.Code)
+	}
+	var body problem.Details
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if body.Code != "not_post_owner" {
+		t.Fatalf("expected code not_post_owner, got %q", body.Code)
+	}
+	if rec := send(http.MethodDelete, path, "", "bob"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for other author delete, got %d", http.StatusForbidden, rec.Code)
+	}
+
+	if rec := send(http.MethodPatch, path, `{"title":"alice"}`, "alice"); rec.Code != http.StatusOK {
+		t.Fatalf("expected owner update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := send(http.MethodPatch, path, `{"title":"erin"}`, "erin"); rec.Code != http.StatusOK {
+		t.Fatalf("expected editor update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := send(http.MethodPatch, "/posts/999", `{"title":"x"}`, "bob"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for missing post, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = send(http.MethodGet, "/admin/posts/trash", "", "erin")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d for editor on admin route, got %d", http.StatusForbidden, rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Code != "admin_required" {
+		t.Fatalf("expected code admin_required, got %s", rec.Body.String())
+	}
+
+	if rec := send(http.MethodDelete, path, "", "root"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected admin delete to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := send(http.MethodGet, "/admin/posts/trash", "", "root"); rec.Code != http.StatusOK {
+		t.Fatalf("expected admin to list trash, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAuthHandler_BearerTokenRequiredWithoutAPIKey(t *testing.T) {
+	original := config.AppConfig
+	config.AppConfig = &config.Config{JWTSecret: "test-secret"}
+	t.Cleanup(func() { config.AppConfig = original })
+	gin.SetMode(gin.TestMode)
+
+	authService := service.NewAuthService(repository.NewInMemoryUserRepository(), service.AuthConfig{
+		Secret:     []byte("test-secret"),
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	})
+	if _, err := authService.CreateUser(context.Background(), "alice", "password", model.UserRoleAuthor); err != nil {
+		t.Fatalf("CreateUser returned error: %v", err)
+	}
+	pair, err := authService.Login(context.Background(), "alice", "password")
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	postRepository := repository.NewInMemoryPostRepository()
+	postService := service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))
+
+	router := gin.New()
+	router.Use(middleware.Authenticate(authService))
+	NewPostHandler(postService).RegisterRoutes(router)
+
+	send := func(method, path, payload, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/posts", `{"title":"t","content":"c","author":"a"}`, pair.AccessToken)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var post model.Post
+	if err := json.Unmarshal(rec.Body.Bytes(), &post); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+
+	// Without API_KEY the bearer token is still required, so anonymous
+	// callers can neither write nor bypass the ownership check.
+	if rec := send(http.MethodPost, "/posts", `{"title":"t","content":"c","author":"a"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous create to be rejected, got %d", rec.Code)
+	}
+	if rec := send(http.MethodPatch, "/posts/"+strconv.FormatInt(post.ID, 10), `{"title":"x"}`, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous update to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+type AuthorHandler struct {
+	authors *service.AuthorService
+	posts   *service.PostService
+}
+
+func NewAuthorHandler(authors *service.AuthorService, posts *service.PostService) *AuthorHandler {
+	return &AuthorHandler{authors: authors, posts: posts}
+}
+
+func (h *AuthorHandler) RegisterRoutes(router *gin.Engine) {
+	authors := router.Group("/authors")
+	authors.GET("", h.listAuthors)
+	authors.GET("/:id", h.getAuthor)
+	authors.GET("/:id/posts", h.listAuthorPosts)
+
+	protected := authors.Group("", middleware.RequireAuth(model.APIKeyScopePostsWrite))
+	protected.POST("", h.createAuthor)
+	protected.PATCH("/:id", h.updateAuthor)
+	protected.DELETE("/:id", h.deleteAuthor)
+}
+
+type authorRequest struct {
+	Name string `json:"name"`
+}
+
+func (h *AuthorHandler) listAuthors(c *gin.Context) {
+	authors, err := h.authors.List(c.Request.Context())
+	if err != nil {
+		internalError(c, "failed to list authors", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authors": authors})
+}
+
+func (h *AuthorHandler) getAuthor(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	author, err := h.authors.Get(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed to get author")
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+// listAuthorPostsは著者の記事一覧を返します。クエリパラメータはGET /postsと同じです。
+func (h *AuthorHandler) listAuthorPosts(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	if _, err := h.authors.Get(c.Request.Context(), id); err != nil {
+		respondError(c, err, "failed to get author")
+		return
+	}
+
+	respondPostPage(c, h.posts, repository.PostFilter{
+		Status:   visibleStatus(c, c.Query("status")),
+		AuthorID: id,
+		Tag:      c.Query("tag"),
+	})
+}
+
+func (h *AuthorHandler) createAuthor(c *gin.Context) {
+	var req authorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	author, err := h.authors.Create(c.Request.Context(), req.Name)
+	if err != nil {
+		respondError(c, err, "failed to create author")
+		return
+	}
+
+	c.JSON(http.StatusCreated, author)
+}
+
+func (h *AuthorHandler) updateAuthor(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	var req authorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	author, err := h.authors.Rename(c.Request.Context(), id, req.Name)
+	if err != nil {
+		respondError(c, err, "failed to update author")
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+func (h *AuthorHandler) deleteAuthor(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	if err := h.authors.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err, "failed to delete author")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
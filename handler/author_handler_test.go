@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+func setupAuthorRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	posts := repository.NewInMemoryPostRepository()
+	authors := repository.NewInMemoryAuthorRepository(posts)
+	postService := service.NewPostService(posts, repository.NewInMemoryRevisionRepository(), authors)
+
+	router := gin.New()
+	NewPostHandler(postService).RegisterRoutes(router)
+	NewAuthorHandler(service.NewAuthorService(authors), postService).RegisterRoutes(router)
+
+	return router
+}
+
+func TestAuthorHandler_Lifecycle(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+	router := setupAuthorRouter(t)
+
+	send := func(method, path, payload string, authenticated bool) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if authenticated {
+			req.Header.Set("X-API-Key", "secret")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(http.MethodPost, "/authors", `{"name":"Alice"}`, false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+	rec := send(http.MethodPost, "/authors", `{"name":" Alice "}`, true)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var alice model.Author
+	if err := json.Unmarshal(rec.Body.Bytes(), &alice); err != nil || alice.Name != "Alice" {
+		t.Fatalf("unexpected author %+v (err=%v)", alice, err)
+	}
+	if rec := send(http.MethodPost, "/authors", `{"name":"alice"}`, true); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	// 著者名で作成した記事は既存の著者に紐付き、著者IDを指定した記事と同じ著者になります。
+	var first model.Post
+	for i, payload := range []string{
+		`{"title":"first","content":"c","author":"ALICE","status":"published"}`,
+		fmt.Sprintf(`{"title":"second","content":"c","author_id":%d}`, alice.ID),
+	} {
+		rec := send(http.MethodPost, "/posts", payload, true)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("post %d: expected status %d, got %d: %s", i, http.StatusCreated, rec.Code, rec.Body.String())
+		}
+		var post model.Post
+		if err := json.Unmarshal(rec.Body.Bytes(), &post); err != nil {
+			t.Fatalf("unexpected response body: %v", err)
+		}
+		if post.AuthorID != alice.ID || post.Author != "Alice" {
+			t.Fatalf("post %d: expected author %d Alice, got %d %q", i, alice.ID, post.AuthorID, post.Author)
+		}
+		if i == 0 {
+			first = post
+		}
+	}
+	postURL := fmt.Sprintf("/posts/%d", first.ID)
+	etag := send(http.MethodGet, postURL, "", false).Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag on the post")
+	}
+
+	postsURL := fmt.Sprintf("/authors/%d/posts", alice.ID)
+	var page struct {
+		Posts []model.Post `json:"posts"`
+	}
+	rec = send(http.MethodGet, postsURL, "", false)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || len(page.Posts) != 1 || page.Posts[0].Title != "first" {
+		t.Fatalf("expected only the published post, got %+v (err=%v)", page.Posts, err)
+	}
+	rec = send(http.MethodGet, postsURL, "", true)
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || len(page.Posts) != 2 {
+		t.Fatalf("expected both posts when authenticated, got %+v (err=%v)", page.Posts, err)
+	}
+	if rec := send(http.MethodGet, "/authors/999/posts", "", false); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	authorURL := fmt.Sprintf("/authors/%d", alice.ID)
+	if rec := send(http.MethodPatch, authorURL, `{"name":" "}`, true); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	rec = send(http.MethodPatch, authorURL, `{"name":"Alice Smith"}`, true)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	// 改名で記事の内容が変わるため、以前のETagでは304になりません。
+	req := httptest.NewRequest(http.MethodGet, postURL, nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expected a new ETag after the rename, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+
+	rec = send(http.MethodGet, "/authors", "", false)
+	var list struct {
+		Authors []model.Author `json:"authors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list.Authors) != 1 || list.Authors[0].Name != "Alice Smith" {
+		t.Fatalf("unexpected authors: %+v (err=%v)", list.Authors, err)
+	}
+
+	rec = send(http.MethodGet, postsURL, "", true)
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || len(page.Posts) != 2 || page.Posts[0].Author != "Alice Smith" {
+		t.Fatalf("expected posts to follow the rename, got %+v (err=%v)", page.Posts, err)
+	}
+
+	// 記事が残っている著者は削除できません。
+	if rec := send(http.MethodDelete, authorURL, "", true); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	rec = send(http.MethodPost, "/authors", `{"name":"bob"}`, true)
+	var bob model.Author
+	if err := json.Unmarshal(rec.Body.Bytes(), &bob); err != nil || rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d (err=%v)", http.StatusCreated, rec.Code, err)
+	}
+	bobURL := fmt.Sprintf("/authors/%d", bob.ID)
+	if rec := send(http.MethodDelete, bobURL, "", true); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+	if rec := send(http.MethodGet, bobURL, "", false); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+type CommentHandler struct {
+	service *service.CommentService
+}
+
+func NewCommentHandler(service *service.CommentService) *CommentHandler {
+	return &CommentHandler{service: service}
+}
+
+func (h *CommentHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/posts/:id/comments", h.listComments)
+	router.GET("/posts/:id/comments/:comment_id/replies", h.listReplies)
+	router.POST("/posts/:id/comments", h.createComment)
+	router.DELETE("/comments/:id", middleware.RequireAuth(model.APIKeyScopePostsWrite), h.deleteComment)
+}
+
+type createCommentRequest struct {
+	ParentID *int64 `json:"parent_id"`
+	Author   string `json:"author"`
+	Body     string `json:"body"`
+}
+
+func (h *CommentHandler) listComments(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	query, ok := commentQuery(c, postID)
+	if !ok {
+		return
+	}
+
+	page, err := h.service.List(c.Request.Context(), query, visibleStatus(c, ""))
+	if err != nil {
+		respondError(c, err, "failed to list comments")
+		return
+	}
+	respondCommentPage(c, page)
+}
+
+func (h *CommentHandler) listReplies(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+	parentID, err := strconv.ParseInt(c.Param("comment_id"), 10, 64)
+	if err != nil {
+		invalidField(c, "comment_id", "invalid", "invalid comment_id")
+		return
+	}
+
+	query, ok := commentQuery(c, postID)
+	if !ok {
+		return
+	}
+	query.ParentID = parentID
+
+	page, err := h.service.ListReplies(c.Request.Context(), query, visibleStatus(c, ""))
+	if err != nil {
+		respondError(c, err, "failed to list replies")
+		return
+	}
+	respondCommentPage(c, page)
+}
+
+// commentQueryはクエリパラメータのlimitとcursorを読み取ります。limitが数値でない場合は400を返してfalseを返します。
+func commentQuery(c *gin.Context, postID int64) (repository.CommentQuery, bool) {
+	query := repository.CommentQuery{PostID: postID, Cursor: c.Query("cursor")}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			invalidField(c, "limit", "invalid", "invalid limit")
+			return repository.CommentQuery{}, false
+		}
+		query.Limit = limit
+	}
+	return query, true
+}
+
+// respondCommentPageはコメントのページを次のカーソルと合わせて返します。
+func respondCommentPage(c *gin.Context, page repository.CommentPage) {
+	resp := gin.H{"comments": page.Comments, "next_cursor": nil}
+	if page.NextCursor != "" {
+		resp["next_cursor"] = page.NextCursor
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *CommentHandler) createComment(c *gin.Context) {
+	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	var req createCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	comment, err := h.service.Create(c.Request.Context(), service.CreateCommentInput{
+		PostID:     postID,
+		ParentID:   req.ParentID,
+		Author:     req.Author,
+		Body:       req.Body,
+		PostStatus: visibleStatus(c, ""),
+	})
+	if err != nil {
+		respondError(c, err, "failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+func (h *CommentHandler) deleteComment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err, "failed to delete comment")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
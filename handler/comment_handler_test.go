@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+func setupCommentRouter(t *testing.T) (*gin.Engine, *repository.InMemoryPostRepository) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+
+	posts := repository.NewInMemoryPostRepository()
+	postService := service.NewPostService(posts, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(posts))
+	commentService := service.NewCommentService(repository.NewInMemoryCommentRepository(), posts)
+
+	router := gin.New()
+	NewPostHandler(postService).RegisterRoutes(router)
+	NewCommentHandler(commentService).RegisterRoutes(router)
+
+	return router, posts
+}
+
+func TestCommentHandler_Lifecycle(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+
+	router, posts := setupCommentRouter(t)
+	post, err := posts.Create(context.Background(), model.Post{Title: "t", Content: "c", Author: "a", Status: model.PostStatusPublished})
+	if err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+	commentsURL := fmt.Sprintf("/posts/%d/comments", post.ID)
+
+	postComment := func(payload string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, commentsURL, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := postComment(`{"author":"reader","body":"first!"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	var parent model.Comment
+	if err := json.Unmarshal(rec.Body.Bytes(), &parent); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+
+	if rec := postComment(fmt.Sprintf(`{"author":"a","body":"thanks","parent_id":%d}`, parent.ID)); rec.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+	if rec := postComment(`{"author":"reader","body":""}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, commentsURL+"?limit=1", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var page struct {
+		Comments   []model.Comment `json:"comments"`
+		NextCursor *string         `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(page.Comments) != 1 || len(page.Comments[0].Replies) != 1 || page.Comments[0].ReplyCount != 1 || page.NextCursor != nil {
+		t.Fatalf("unexpected comments: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s/%d/replies", commentsURL, parent.ID), nil))
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d (err=%v)", http.StatusOK, rec.Code, err)
+	}
+	if len(page.Comments) != 1 || page.Comments[0].Body != "thanks" || page.NextCursor != nil {
+		t.Fatalf("unexpected replies: %s", rec.Body.String())
+	}
+	reply := page.Comments[0]
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("%s/%d/replies", commentsURL, reply.ID), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected replies of a reply to be %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/comments/%d", parent.ID), nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/comments/%d", parent.ID), nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/comments/%d", parent.ID), nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestCommentHandler_HiddenPost(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+
+	router, posts := setupCommentRouter(t)
+	draft, err := posts.Create(context.Background(), model.Post{Title: "t", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/posts/%d/comments", draft.ID), nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/posts/%d/comments", draft.ID), nil)
+	req.Header.Set("X-API-Key", "secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
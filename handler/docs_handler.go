@@ -22,7 +22,7 @@ func (h *DocsHandler) RegisterRoutes(router *gin.Engine) {
 func (h *DocsHandler) serveYAML(c *gin.Context) {
 	data, err := docs.OpenAPIFS.ReadFile(docs.OpenAPIPath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load OpenAPI spec"})
+		internalError(c, "failed to load OpenAPI spec", err)
 		return
 	}
 	c.Data(http.StatusOK, "application/yaml", data)
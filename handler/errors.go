@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+	"github.com/kitakitabauer/gin-sample-app/logger"
+	"github.com/kitakitabauer/gin-sample-app/policy"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+	"github.com/kitakitabauer/gin-sample-app/validation"
+)
+
+// errorMappingはサービスやリポジトリのエラーと、それを返す際のステータスとcodeの対応です。
+// fieldを指定した場合はリクエストのfieldの値が不正であることを表し、
+// validation_failedの問題としてerrors[]にcodeを設定した項目を返します。
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+	field  string
+}
+
+// errorMappingsはエラーの既定の対応です。ハンドラーはrespondErrorにoverridesを渡して文脈に合わせて変更できます。
+var errorMappings = []errorMapping{
+	{err: service.ErrTitleRequired, status: http.StatusBadRequest, code: "required", field: "title"},
+	{err: service.ErrContentRequired, status: http.StatusBadRequest, code: "required", field: "content"},
+	{err: service.ErrAuthorRequired, status: http.StatusBadRequest, code: "required", field: "author"},
+	{err: service.ErrAuthorNameTooLong, status: http.StatusBadRequest, code: "too_long", field: "author"},
+	{err: service.ErrInvalidStatus, status: http.StatusBadRequest, code: "invalid", field: "status"},
+	{err: service.ErrInvalidPublishAt, status: http.StatusBadRequest, code: "invalid", field: "publish_at"},
+	{err: service.ErrInvalidTag, status: http.StatusBadRequest, code: "invalid", field: "tags"},
+	{err: service.ErrTooManyTags, status: http.StatusBadRequest, code: "too_many", field: "tags"},
+	{err: service.ErrInvalidSlug, status: http.StatusBadRequest, code: "invalid", field: "slug"},
+	{err: service.ErrInvalidLimit, status: http.StatusBadRequest, code: "out_of_range", field: "limit"},
+	{err: service.ErrQueryRequired, status: http.StatusBadRequest, code: "required", field: "q"},
+	{err: repository.ErrInvalidCursor, status: http.StatusBadRequest, code: "invalid", field: "cursor"},
+	{err: repository.ErrInvalidSort, status: http.StatusBadRequest, code: "invalid", field: "sort"},
+	{err: service.ErrEmptyBatch, status: http.StatusBadRequest, code: "required", field: "operations"},
+	{err: service.ErrTooManyOperations, status: http.StatusBadRequest, code: "too_many", field: "operations"},
+	{err: service.ErrInvalidBatchOperation, status: http.StatusBadRequest, code: "invalid", field: "op"},
+	{err: service.ErrCommentBodyRequired, status: http.StatusBadRequest, code: "required", field: "body"},
+	{err: service.ErrCommentTooLong, status: http.StatusBadRequest, code: "too_long", field: "body"},
+	{err: service.ErrInvalidParent, status: http.StatusBadRequest, code: "invalid", field: "parent_id"},
+	{err: service.ErrAPIKeyNameRequired, status: http.StatusBadRequest, code: "required", field: "name"},
+	{err: service.ErrScopesRequired, status: http.StatusBadRequest, code: "required", field: "scopes"},
+	{err: service.ErrInvalidScope, status: http.StatusBadRequest, code: "invalid", field: "scopes"},
+	{err: service.ErrInvalidExpiry, status: http.StatusBadRequest, code: "invalid", field: "expires_at"},
+	{err: service.ErrNoFieldsToUpdate, status: http.StatusBadRequest, code: "no_fields_to_update"},
+	{err: errBatchIDRequired, status: http.StatusBadRequest, code: "required", field: "id"},
+	{err: errBatchPostRequired, status: http.StatusBadRequest, code: "required", field: "post"},
+	{err: errInvalidIfMatch, status: http.StatusBadRequest, code: "invalid_if_match"},
+
+	{err: service.ErrInvalidCredentials, status: http.StatusUnauthorized, code: "invalid_credentials"},
+	{err: service.ErrInvalidToken, status: http.StatusUnauthorized, code: "invalid_token"},
+
+	{err: repository.ErrPostNotFound, status: http.StatusNotFound, code: "post_not_found"},
+	{err: repository.ErrRevisionNotFound, status: http.StatusNotFound, code: "revision_not_found"},
+	{err: repository.ErrAuthorNotFound, status: http.StatusNotFound, code: "author_not_found"},
+	{err: repository.ErrCommentNotFound, status: http.StatusNotFound, code: "comment_not_found"},
+	{err: repository.ErrAPIKeyNotFound, status: http.StatusNotFound, code: "api_key_not_found"},
+
+	{err: repository.ErrVersionConflict, status: http.StatusPreconditionFailed, code: "version_conflict"},
+	{err: service.ErrInvalidTransition, status: http.StatusConflict, code: "invalid_transition"},
+	{err: repository.ErrSlugTaken, status: http.StatusConflict, code: "slug_taken"},
+	{err: repository.ErrPostNotInTrash, status: http.StatusConflict, code: "post_not_in_trash"},
+	{err: repository.ErrAuthorNameTaken, status: http.StatusConflict, code: "author_name_taken"},
+	{err: repository.ErrAuthorHasPosts, status: http.StatusConflict, code: "author_has_posts"},
+
+	{err: service.ErrAtomicNotSupported, status: http.StatusNotImplemented, code: "atomic_not_supported"},
+}
+
+// postAuthorErrorsはPostの著者をauthor_idで指定した場合のエラーの対応です。
+// 存在しない著者の指定は、著者APIとは異なりリクエストの誤りとして扱います。
+var postAuthorErrors = []errorMapping{
+	{err: repository.ErrAuthorNotFound, status: http.StatusBadRequest, code: "not_found", field: "author_id"},
+}
+
+// versionConflictIsConflictは、If-Matchを受け付けない操作で同時に更新された場合を409として扱う対応です。
+var versionConflictIsConflict = []errorMapping{
+	{err: repository.ErrVersionConflict, status: http.StatusConflict, code: "version_conflict"},
+}
+
+// problemForはerrに対応する問題を返します。validation.Errorはすべての違反をerrors[]で返します。
+// overridesは既定の対応より先に照合します。対応が無い場合はokにfalseを返します。
+func problemFor(err error, overrides ...errorMapping) (d problem.Details, ok bool) {
+	if violations := validation.Violations(err); len(violations) > 0 {
+		errs := make([]problem.FieldError, len(violations))
+		for i, v := range violations {
+			errs[i] = problem.FieldError{Field: v.Field, Code: v.Code, Message: v.Err.Error()}
+		}
+		return problem.Validation(err.Error(), errs...), true
+	}
+
+	var perr *policy.Error
+	if errors.As(err, &perr) {
+		return problem.New(http.StatusForbidden, perr.Code, perr.Message), true
+	}
+
+	for _, mappings := range [][]errorMapping{overrides, errorMappings} {
+		for _, m := range mappings {
+			if !errors.Is(err, m.err) {
+				continue
+			}
+			if m.field != "" {
+				return problem.Validation(m.err.Error(), problem.FieldError{Field: m.field, Code: m.code, Message: m.err.Error()}), true
+			}
+			return problem.New(m.status, m.code, m.err.Error()), true
+		}
+	}
+	return problem.Details{}, false
+}
+
+// respondErrorはerrに対応する問題を返します。対応が無いエラーはinternalErrorとして扱います。
+func respondError(c *gin.Context, err error, failure string, overrides ...errorMapping) {
+	if d, ok := problemFor(err, overrides...); ok {
+		problem.Write(c, d)
+		return
+	}
+	internalError(c, failure, err)
+}
+
+// internalErrorは原因のエラーをリクエストIDとともにログへ記録し、詳細を含まないmessageで500を返します。
+func internalError(c *gin.Context, message string, err error) {
+	logger.FromContext(c.Request.Context()).Error(message, zap.Error(err))
+	problem.Write(c, problem.New(http.StatusInternalServerError, "internal_error", message))
+}
+
+// invalidFieldはリクエストボディのフィールドやパス・クエリのパラメータfieldの値が不正な場合の400を返します。
+func invalidField(c *gin.Context, field, code, message string) {
+	problem.Write(c, problem.Validation(message, problem.FieldError{Field: field, Code: code, Message: message}))
+}
+
+// invalidBodyはリクエストボディを読み込めなかった場合の400を返します。
+func invalidBody(c *gin.Context, err error) {
+	problem.Write(c, bodyProblem(err))
+}
+
+// bodyProblemはJSONのデコードに失敗した理由を、デコーダーの内部のメッセージを含めずに表します。
+// 型が一致しないフィールドはerrors[]で返し、上限を超えたボディは413として扱います。
+func bodyProblem(err error) problem.Details {
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var timeErr *time.ParseError
+	var maxErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxErr):
+		return middleware.BodyTooLarge(maxErr.Limit)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		message := fmt.Sprintf("%s must be of type %s", typeErr.Field, jsonType(typeErr.Type))
+		return problem.Validation(message, problem.FieldError{Field: typeErr.Field, Code: "invalid_type", Message: message})
+	case errors.Is(err, io.EOF):
+		return problem.New(http.StatusBadRequest, "invalid_body", "request body is required")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &typeErr):
+		return problem.New(http.StatusBadRequest, "invalid_body", "request body must be a valid JSON object")
+	case errors.As(err, &timeErr):
+		return problem.New(http.StatusBadRequest, "invalid_body", "timestamps must be RFC 3339 strings")
+	default:
+		return problem.New(http.StatusBadRequest, "invalid_body", "request body is invalid")
+	}
+}
+
+// jsonTypeはGoの型をJSONの型の名前で表します。
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	default:
+		return "number"
+	}
+}
@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+	"github.com/kitakitabauer/gin-sample-app/policy"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+func TestProblemFor(t *testing.T) {
+	cases := []struct {
+		err       error
+		overrides []errorMapping
+		status    int
+		code      string
+		field     string
+	}{
+		{fmt.Errorf("wrapped: %w", repository.ErrPostNotFound), nil, http.StatusNotFound, "post_not_found", ""},
+		{service.ErrTitleRequired, nil, http.StatusBadRequest, problem.CodeValidationFailed, "title"},
+		{policy.ErrNotPostOwner, nil, http.StatusForbidden, "not_post_owner", ""},
+		{repository.ErrVersionConflict, nil, http.StatusPreconditionFailed, "version_conflict", ""},
+		{repository.ErrVersionConflict, versionConflictIsConflict, http.StatusConflict, "version_conflict", ""},
+		{repository.ErrAuthorNotFound, nil, http.StatusNotFound, "author_not_found", ""},
+		{repository.ErrAuthorNotFound, postAuthorErrors, http.StatusBadRequest, problem.CodeValidationFailed, "author_id"},
+	}
+	for _, tc := range cases {
+		d, ok := problemFor(tc.err, tc.overrides...)
+		if !ok || d.Status != tc.status || d.Code != tc.code {
+			t.Fatalf("%v: expected %d %s, got %+v (ok %v)", tc.err, tc.status, tc.code, d, ok)
+		}
+		if tc.field != "" && (len(d.Errors) != 1 || d.Errors[0].Field != tc.field) {
+			t.Fatalf("%v: expected a field error for %s, got %+v", tc.err, tc.field, d.Errors)
+		}
+	}
+
+	if d := bodyProblem(fmt.Errorf("read: %w", &http.MaxBytesError{Limit: 10})); d.Status != http.StatusRequestEntityTooLarge || d.Code != "body_too_large" {
+		t.Fatalf("expected 413 body_too_large, got %+v", d)
+	}
+
+	if _, ok := problemFor(errors.New("boom")); ok {
+		t.Fatal("expected an unknown error not to be mapped")
+	}
+}
+
+func TestPostHandler_CreatePost_InvalidBody(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, _ := setupTestRouter(t)
+
+	cases := []struct {
+		payload string
+		code    string
+		field   string
+	}{
+		{``, "invalid_body", ""},
+		{`{"title":`, "invalid_body", ""},
+		{`{"title":1,"content":"c","author":"a"}`, problem.CodeValidationFailed, "title"},
+		{`{"title":"t","content":"c","author":"a","publish_at":"tomorrow"}`, "invalid_body", ""},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(tc.payload))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var body problem.Details
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: unexpected response body: %v", tc.payload, err)
+		}
+		if rec.Code != http.StatusBadRequest || body.Code != tc.code {
+			t.Fatalf("%s: expected %s, got %d %s", tc.payload, tc.code, rec.Code, rec.Body.String())
+		}
+		if tc.field != "" && (len(body.Errors) != 1 || body.Errors[0].Field != tc.field || body.Errors[0].Code != "invalid_type") {
+			t.Fatalf("%s: expected an invalid_type error for %s, got %+v", tc.payload, tc.field, body.Errors)
+		}
+		// The decoder's own messages must not reach the client.
+		if strings.Contains(body.Detail, "json:") || strings.Contains(body.Detail, "Go") {
+			t.Fatalf("%s: expected the decoder message not to leak, got %q", tc.payload, body.Detail)
+		}
+	}
+}
+
+func TestPostHandler_CreatePost_ReportsAllViolations(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, _ := setupTestRouter(t)
+
+	payload := fmt.Sprintf(`{"title":%q,"content":" ","author":"a","tags":["ok","bad\u0007"]}`, strings.Repeat("t", service.MaxTitleLength+1))
+	req := httptest.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var body problem.Details
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if rec.Code != http.StatusBadRequest || body.Code != problem.CodeValidationFailed {
+		t.Fatalf("expected validation_failed, got %d %s", rec.Code, rec.Body.String())
+	}
+	want := []problem.FieldError{
+		{Field: "title", Code: "too_long", Message: fmt.Sprintf("title must be at most %d characters", service.MaxTitleLength)},
+		{Field: "content", Code: "required", Message: service.ErrContentRequired.Error()},
+		{Field: "tags", Code: "invalid", Message: service.ErrInvalidTag.Error()},
+	}
+	if fmt.Sprint(body.Errors) != fmt.Sprint(want) {
+		t.Fatalf("expected %+v, got %+v", want, body.Errors)
+	}
+}
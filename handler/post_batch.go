@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+	"github.com/kitakitabauer/gin-sample-app/logger"
+	"github.com/kitakitabauer/gin-sample-app/policy"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+// requireActionは"/posts:action"のactionがactionと一致しない場合に404を返します。
+func requireAction(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Param("action") != action {
+			problem.Abort(c, problem.New(http.StatusNotFound, "not_found", "no route matches the request"))
+			return
+		}
+		c.Next()
+	}
+}
+
+type batchPostsRequest struct {
+	Operations []batchOperationRequest `json:"operations"`
+}
+
+// batchOperationRequestは1件の操作です。postはopに応じてcreatePostRequestまたはupdatePostRequestとして読み込みます。
+type batchOperationRequest struct {
+	Op        string          `json:"op"`
+	ID        int64           `json:"id"`
+	IfVersion *int64          `json:"if_version"`
+	Post      json.RawMessage `json:"post"`
+}
+
+type batchResultResponse struct {
+	Index  int              `json:"index"`
+	Op     string           `json:"op"`
+	Status int              `json:"status"`
+	Post   any              `json:"post,omitempty"`
+	Error  *problem.Details `json:"error,omitempty"`
+}
+
+var (
+	errBatchIDRequired   = errors.New("id is required for update and delete")
+	errBatchPostRequired = errors.New("post is required for create and update")
+)
+
+// batchPostsは複数のPostの作成・更新・削除をまとめて行います。
+// atomic=trueの場合はすべての操作を1つのトランザクションで実行し、失敗した操作の問題にindexを加えて応答します。
+// それ以外の場合は操作ごとの結果を200で返します。
+func (h *PostHandler) batchPosts(c *gin.Context) {
+	atomic := false
+	if raw := c.Query("atomic"); raw != "" {
+		var err error
+		if atomic, err = strconv.ParseBool(raw); err != nil {
+			invalidField(c, "atomic", "invalid", "atomic must be a boolean")
+			return
+		}
+	}
+
+	var req batchPostsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		invalidBody(c, err)
+		return
+	}
+
+	ops := make([]service.BatchOperation, len(req.Operations))
+	for i, opReq := range req.Operations {
+		op, err := batchOperation(c, opReq)
+		if err != nil {
+			d, ok := problemFor(err)
+			if !ok {
+				d = bodyProblem(err)
+			}
+			problem.Write(c, d.With("index", i))
+			return
+		}
+		ops[i] = op
+	}
+
+	principal := middleware.Principal(c)
+	results, err := h.service.Batch(c.Request.Context(), service.BatchInput{
+		Operations: ops,
+		Atomic:     atomic,
+		Authorize: func(ctx context.Context, posts *service.PostService, id int64) error {
+			return policy.NewPostPolicy(posts).CanModify(ctx, principal, id)
+		},
+	})
+	if err != nil {
+		var berr *service.BatchError
+		if errors.As(err, &berr) {
+			problem.Write(c, batchProblem(c, ops[berr.Index].Type, berr.Err).With("index", berr.Index))
+			return
+		}
+		respondError(c, err, "failed to apply batch")
+		return
+	}
+
+	response := make([]batchResultResponse, len(results))
+	for i, result := range results {
+		response[i] = batchResult(c, i, ops[i].Type, result)
+	}
+	c.JSON(http.StatusOK, gin.H{"results": response})
+}
+
+// batchOperationはリクエストの1件の操作をservice.BatchOperationに変換します。
+func batchOperation(c *gin.Context, req batchOperationRequest) (service.BatchOperation, error) {
+	op := service.BatchOperation{Type: req.Op, ID: req.ID}
+	switch req.Op {
+	case service.BatchCreate:
+		var body createPostRequest
+		if err := decodeBatchPost(req.Post, &body); err != nil {
+			return op, err
+		}
+		op.Create = service.CreatePostInput{
+			Title:     body.Title,
+			Content:   body.Content,
+			Author:    body.Author,
+			AuthorID:  body.AuthorID,
+			Status:    body.Status,
+			PublishAt: body.PublishAt,
+			Tags:      body.Tags,
+		}
+		if user, ok := middleware.CurrentUser(c); ok {
+			op.Create.OwnerID = user.ID
+		}
+	case service.BatchUpdate:
+		if req.ID <= 0 {
+			return op, errBatchIDRequired
+		}
+		var body updatePostRequest
+		if err := decodeBatchPost(req.Post, &body); err != nil {
+			return op, err
+		}
+		op.Update = service.UpdatePostInput{
+			Title:     body.Title,
+			Slug:      body.Slug,
+			Content:   body.Content,
+			Author:    body.Author,
+			AuthorID:  body.AuthorID,
+			Status:    body.Status,
+			PublishAt: body.PublishAt,
+			Tags:      body.Tags,
+			IfVersion: req.IfVersion,
+			Actor:     middleware.Actor(c),
+		}
+	case service.BatchDelete:
+		if req.ID <= 0 {
+			return op, errBatchIDRequired
+		}
+	default:
+		return op, service.ErrInvalidBatchOperation
+	}
+	return op, nil
+}
+
+func decodeBatchPost(raw json.RawMessage, dest any) error {
+	if len(raw) == 0 {
+		return errBatchPostRequired
+	}
+	if err := json.Unmarshal(raw, dest); err != nil {
+		return fmt.Errorf("invalid post: %w", err)
+	}
+	return nil
+}
+
+// batchResultは1件の操作の結果を、同じ操作を個別のエンドポイントで行った場合のステータスで表します。
+func batchResult(c *gin.Context, index int, op string, result service.BatchResult) batchResultResponse {
+	res := batchResultResponse{Index: index, Op: op}
+	if result.Err != nil {
+		d := batchProblem(c, op, result.Err)
+		res.Status = d.Status
+		res.Error = &d
+		return res
+	}
+
+	switch op {
+	case service.BatchCreate:
+		res.Status = http.StatusCreated
+		res.Post = result.Post
+	case service.BatchUpdate:
+		res.Status = http.StatusOK
+		res.Post = result.Post
+	default:
+		res.Status = http.StatusNoContent
+	}
+	return res
+}
+
+// batchProblemは1件の操作のエラーを、同じ操作を個別のエンドポイントで行った場合の問題で表します。
+// 対応が無いエラーは原因をログに記録し、詳細を含まない500として扱います。
+func batchProblem(c *gin.Context, op string, err error) problem.Details {
+	if d, ok := problemFor(err, postAuthorErrors...); ok {
+		return d
+	}
+	message := fmt.Sprintf("failed to %s post", op)
+	logger.FromContext(c.Request.Context()).Error(message, zap.Error(err))
+	return problem.New(http.StatusInternalServerError, "internal_error", message)
+}
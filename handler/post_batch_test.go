@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+)
+
+type batchResponse struct {
+	Results []struct {
+		Index  int    `json:"index"`
+		Op     string `json:"op"`
+		Status int    `json:"status"`
+		Post   *struct {
+			ID    int64  `json:"id"`
+			Title string `json:"title"`
+		} `json:"post"`
+		Error *problem.Details `json:"error"`
+	} `json:"results"`
+}
+
+func TestPostHandler_BatchPosts(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, repo := setupTestRouter(t)
+
+	serve := func(url, payload string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, url, bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("/posts:batch", `{"operations":[
+		{"op":"create","post":{"title":"one","content":"c","author":"Alice"}},
+		{"op":"create","post":{"title":"","content":"c","author":"Alice"}},
+		{"op":"update","id":1,"post":{"title":"one (edited)"}},
+		{"op":"delete","id":99}
+	]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	var body batchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	wantStatuses := []int{http.StatusCreated, http.StatusBadRequest, http.StatusOK, http.StatusNotFound}
+	if len(body.Results) != len(wantStatuses) {
+		t.Fatalf("expected %d results, got %+v", len(wantStatuses), body.Results)
+	}
+	for i, want := range wantStatuses {
+		if body.Results[i].Index != i || body.Results[i].Status != want {
+			t.Fatalf("unexpected result %d: %+v", i, body.Results[i])
+		}
+	}
+	if body.Results[2].Post == nil || body.Results[2].Post.Title != "one (edited)" {
+		t.Fatalf("expected the updated post in the result, got %+v", body.Results[2])
+	}
+	if e := body.Results[1].Error; e == nil || len(e.Errors) != 1 || e.Errors[0].Field != "title" {
+		t.Fatalf("expected a title error for the invalid create, got %+v", body.Results[1].Error)
+	}
+	if e := body.Results[3].Error; e == nil || e.Code != "post_not_found" {
+		t.Fatalf("expected post_not_found for the missing post, got %+v", body.Results[3].Error)
+	}
+
+	posts, err := repo.FindAll(t.Context())
+	if err != nil {
+		t.Fatalf("FindAll returned error: %v", err)
+	}
+	if len(posts) != 1 || posts[0].Title != "one (edited)" {
+		t.Fatalf("unexpected posts after batch: %+v", posts)
+	}
+
+	// An atomic batch responds with the failing operation and rolls back the earlier ones.
+	rec = serve("/posts:batch?atomic=true", `{"operations":[
+		{"op":"create","post":{"title":"two","content":"c","author":"Bob"}},
+		{"op":"delete","id":1},
+		{"op":"update","id":99,"post":{"title":"missing"}}
+	]}`)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"index":2`) || !strings.Contains(rec.Body.String(), `"code":"post_not_found"`) {
+		t.Fatalf("expected status %d for the third operation, got %d: %s", http.StatusNotFound, rec.Code, rec.Body.String())
+	}
+	if posts, _ := repo.FindAll(t.Context()); len(posts) != 1 || posts[0].Title != "one (edited)" {
+		t.Fatalf("expected the atomic batch to be rolled back, got %+v", posts)
+	}
+
+	for _, tc := range []struct {
+		url, payload string
+	}{
+		{"/posts:batch", `{"operations":[]}`},
+		{"/posts:batch", `{"operations":[{"op":"archive","id":1}]}`},
+		{"/posts:batch", `{"operations":[{"op":"update","post":{"title":"x"}}]}`},
+		{"/posts:batch", `{"operations":[{"op":"create"}]}`},
+		{"/posts:batch?atomic=maybe", `{"operations":[{"op":"delete","id":1}]}`},
+		{"/posts:batch", `{"operations":[` + strings.TrimSuffix(strings.Repeat(`{"op":"delete","id":1},`, 101), ",") + `]}`},
+	} {
+		if rec := serve(tc.url, tc.payload); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for %s %s, got %d", http.StatusBadRequest, tc.url, tc.payload, rec.Code)
+		}
+	}
+
+	if rec := serve("/posts:purge", `{"operations":[{"op":"delete","id":1}]}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for an unknown action, got %d", http.StatusNotFound, rec.Code)
+	}
+}
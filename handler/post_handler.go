@@ -2,149 +2,473 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/policy"
 	"github.com/kitakitabauer/gin-sample-app/repository"
 	"github.com/kitakitabauer/gin-sample-app/service"
 )
 
 type PostHandler struct {
 	service *service.PostService
+	policy  *policy.PostPolicy
 }
 
 func NewPostHandler(service *service.PostService) *PostHandler {
-	return &PostHandler{service: service}
+	return &PostHandler{service: service, policy: policy.NewPostPolicy(service)}
 }
 
 func (h *PostHandler) RegisterRoutes(router *gin.Engine) {
 	posts := router.Group("/posts")
 	posts.GET("", h.listPosts)
+	posts.GET("/search", h.searchPosts)
+	posts.GET("/by-slug/:slug", h.getPostBySlug)
 	posts.GET("/:id", h.getPost)
 
-	protected := posts.Group("", middleware.RequireAPIKey())
-	protected.POST("", h.createPost)
-	protected.PATCH("/:id", h.updatePost)
-	protected.DELETE("/:id", h.deletePost)
+	protected := posts.Group("", middleware.RequireAuth())
+	protected.GET("/:id/revisions", h.listRevisions)
+	protected.GET("/:id/revisions/:rev", h.getRevision)
+
+	write := posts.Group("", middleware.RequireAuth(model.APIKeyScopePostsWrite))
+	write.POST("", h.createPost)
+	write.PATCH("/:id", h.updatePost)
+	write.DELETE("/:id", h.deletePost)
+	write.POST("/:id/publish", h.publishPost)
+	write.POST("/:id/unpublish", h.unpublishPost)
+	write.POST("/:id/revisions/:rev/revert", h.revertRevision)
+
+	// ginは"/posts:batch"の":batch"をパスパラメータとして扱うため、値が一致する場合のみ処理します。
+	router.POST("/posts:action", requireAction(":batch"), middleware.RequireAuth(model.APIKeyScopePostsWrite), h.batchPosts)
 }
 
 type createPostRequest struct {
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Author  string `json:"author"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Author    string     `json:"author"`
+	AuthorID  int64      `json:"author_id"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+	Tags      []string   `json:"tags"`
 }
 
 type updatePostRequest struct {
-	Title   *string `json:"title"`
-	Content *string `json:"content"`
-	Author  *string `json:"author"`
+	Title     *string    `json:"title"`
+	Slug      *string    `json:"slug"`
+	Content   *string    `json:"content"`
+	Author    *string    `json:"author"`
+	AuthorID  *int64     `json:"author_id"`
+	Status    *string    `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+	Tags      *[]string  `json:"tags"`
+}
+
+type publishPostRequest struct {
+	PublishAt *time.Time `json:"publish_at"`
 }
 
 func (h *PostHandler) createPost(c *gin.Context) {
 	var req createPostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		invalidBody(c, err)
 		return
 	}
 
-	post, err := h.service.Create(c.Request.Context(), req.Title, req.Content, req.Author)
+	input := service.CreatePostInput{
+		Title:     req.Title,
+		Content:   req.Content,
+		Author:    req.Author,
+		AuthorID:  req.AuthorID,
+		Status:    req.Status,
+		PublishAt: req.PublishAt,
+		Tags:      req.Tags,
+	}
+	if user, ok := middleware.CurrentUser(c); ok {
+		input.OwnerID = user.ID
+	}
+
+	post, err := h.service.Create(c.Request.Context(), input)
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrTitleRequired),
-			errors.Is(err, service.ErrContentRequired),
-			errors.Is(err, service.ErrAuthorRequired):
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create post"})
-		}
+		respondError(c, err, "failed to create post", postAuthorErrors...)
 		return
 	}
 
+	c.Header("ETag", postETag(post))
 	c.JSON(http.StatusCreated, post)
 }
 
+// visibleStatusは呼び出し元が参照できる公開状態を返します。
+// 認証済みの場合はrequestedをそのまま返し、未認証の場合は公開済みに限定します。
+func visibleStatus(c *gin.Context, requested string) string {
+	if middleware.Authenticated(c) {
+		return requested
+	}
+	return model.PostStatusPublished
+}
+
+// postVisibleは呼び出し元がpostを参照できるかを返します。
+// 未公開のPostは存在自体を明かさないよう、未認証の呼び出しには404として扱います。
+func postVisible(c *gin.Context, post model.Post) bool {
+	status := visibleStatus(c, "")
+	return status == "" || post.Status == status
+}
+
 func (h *PostHandler) listPosts(c *gin.Context) {
-	posts, err := h.service.List(c.Request.Context())
+	respondPostPage(c, h.service, repository.PostFilter{
+		Status: visibleStatus(c, c.Query("status")),
+		Author: c.Query("author"),
+		Tag:    c.Query("tag"),
+	})
+}
+
+// respondPostPageはクエリパラメータのページング・日時の絞り込み・並び順をfilterと合わせて適用し、Post一覧を返します。
+func respondPostPage(c *gin.Context, posts *service.PostService, filter repository.PostFilter) {
+	query := repository.PostQuery{
+		Filter: filter,
+		Cursor: c.Query("cursor"),
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			invalidField(c, "limit", "invalid", "invalid limit")
+			return
+		}
+		query.Limit = limit
+	}
+
+	var err error
+	if query.Filter.CreatedAfter, err = parseTimeQuery(c, "created_after"); err != nil {
+		invalidField(c, "created_after", "invalid", err.Error())
+		return
+	}
+	if query.Filter.CreatedBefore, err = parseTimeQuery(c, "created_before"); err != nil {
+		invalidField(c, "created_before", "invalid", err.Error())
+		return
+	}
+	if query.Filter.UpdatedSince, err = parseTimeQuery(c, "updated_since"); err != nil {
+		invalidField(c, "updated_since", "invalid", err.Error())
+		return
+	}
+	if query.Sort, err = repository.ParsePostSort(c.Query("sort")); err != nil {
+		respondError(c, err, "failed to list posts")
+		return
+	}
+
+	page, err := posts.List(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list posts"})
+		respondError(c, err, "failed to list posts")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": posts})
+	resp := gin.H{"posts": page.Posts, "next_cursor": nil}
+	if page.NextCursor != "" {
+		resp["next_cursor"] = page.NextCursor
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *PostHandler) searchPosts(c *gin.Context) {
+	var limit int
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil {
+			invalidField(c, "limit", "invalid", "invalid limit")
+			return
+		}
+	}
+
+	hits, err := h.service.Search(c.Request.Context(), repository.PostSearchQuery{
+		Text:   c.Query("q"),
+		Status: visibleStatus(c, c.Query("status")),
+		Limit:  limit,
+	})
+	if err != nil {
+		respondError(c, err, "failed to search posts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": hits})
+}
+
+// postETagはPostのバージョンを強いエンティティタグとして返します。
+func postETag(post model.Post) string {
+	return strconv.Quote(strconv.FormatInt(post.Version, 10))
+}
+
+var errInvalidIfMatch = errors.New(`If-Match must be "*" or a single entity tag`)
+
+// parseIfMatchはIf-Matchヘッダーから更新条件となるバージョンを取り出します。
+// ヘッダーが無い場合と"*"の場合はnilを返します。弱いエンティティタグは強い比較で一致しないため
+// repository.ErrVersionConflictを返します。
+func parseIfMatch(header string) (*int64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	if strings.HasPrefix(header, "W/") {
+		return nil, repository.ErrVersionConflict
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return nil, errInvalidIfMatch
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil {
+		// 形式は正しいが、このAPIが発行したタグではないため一致しません。
+		return nil, repository.ErrVersionConflict
+	}
+	return &version, nil
+}
+
+// parseTimeQueryはRFC 3339形式のクエリパラメータを読み取ります。未指定の場合はnilを返します。
+func parseTimeQuery(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: must be RFC 3339 timestamp", name)
+	}
+	return &t, nil
 }
 
 func (h *PostHandler) getPost(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		invalidField(c, "id", "invalid", "invalid id")
 		return
 	}
 
 	post, err := h.service.Get(c.Request.Context(), id)
 	if err != nil {
-		switch {
-		case errors.Is(err, repository.ErrPostNotFound):
-			c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get post"})
-		}
+		respondError(c, err, "failed to get post")
+		return
+	}
+	if !postVisible(c, post) {
+		respondError(c, repository.ErrPostNotFound, "failed to get post")
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusOK, post)
+}
+
+// getPostBySlugはslugでPostを返します。変更前のslugが指定された場合は現在のslugへ301で転送します。
+func (h *PostHandler) getPostBySlug(c *gin.Context) {
+	slug := c.Param("slug")
+	post, err := h.service.GetBySlug(c.Request.Context(), slug)
+	if err != nil {
+		respondError(c, err, "failed to get post")
+		return
+	}
+	if !postVisible(c, post) {
+		respondError(c, repository.ErrPostNotFound, "failed to get post")
+		return
+	}
+
+	if post.Slug != slug {
+		c.Redirect(http.StatusMovedPermanently, "/posts/by-slug/"+url.PathEscape(post.Slug))
 		return
 	}
 
+	c.Header("ETag", postETag(post))
 	c.JSON(http.StatusOK, post)
 }
 
 func (h *PostHandler) updatePost(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	ifVersion, err := parseIfMatch(c.GetHeader("If-Match"))
+	if err != nil {
+		respondError(c, err, "failed to update post")
 		return
 	}
 
 	var req updatePostRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		invalidBody(c, err)
+		return
+	}
+	if !h.authorizeModify(c, id) {
 		return
 	}
 
-	post, err := h.service.Update(c.Request.Context(), id, req.Title, req.Content, req.Author)
+	post, err := h.service.Update(c.Request.Context(), id, service.UpdatePostInput{
+		Title:     req.Title,
+		Slug:      req.Slug,
+		Content:   req.Content,
+		Author:    req.Author,
+		AuthorID:  req.AuthorID,
+		Status:    req.Status,
+		PublishAt: req.PublishAt,
+		Tags:      req.Tags,
+		IfVersion: ifVersion,
+		Actor:     middleware.Actor(c),
+	})
 	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrNoFieldsToUpdate),
-			errors.Is(err, service.ErrTitleRequired),
-			errors.Is(err, service.ErrContentRequired),
-			errors.Is(err, service.ErrAuthorRequired):
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		case errors.Is(err, repository.ErrPostNotFound):
-			c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
-		}
+		respondError(c, err, "failed to update post", postAuthorErrors...)
 		return
 	}
 
+	c.Header("ETag", postETag(post))
 	c.JSON(http.StatusOK, post)
 }
 
 func (h *PostHandler) deletePost(c *gin.Context) {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	if !h.authorizeModify(c, id) {
 		return
 	}
 
 	if err := h.service.Delete(c.Request.Context(), id); err != nil {
-		switch {
-		case errors.Is(err, repository.ErrPostNotFound):
-			c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete post"})
-		}
+		respondError(c, err, "failed to delete post")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+func (h *PostHandler) publishPost(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	// ボディは省略可能で、その場合は即時公開します。
+	var req publishPostRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			invalidBody(c, err)
+			return
+		}
+	}
+
+	if !h.authorizeModify(c, id) {
+		return
+	}
+
+	post, err := h.service.Publish(c.Request.Context(), id, req.PublishAt, middleware.Actor(c))
+	h.respondStatusChange(c, post, err, "failed to publish post")
+}
+
+func (h *PostHandler) unpublishPost(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	if !h.authorizeModify(c, id) {
+		return
+	}
+
+	post, err := h.service.Unpublish(c.Request.Context(), id, middleware.Actor(c))
+	h.respondStatusChange(c, post, err, "failed to unpublish post")
+}
+
+// authorizeModifyは呼び出し元がidのPostを変更できるかを確認します。
+// 変更できない場合は403(Postが無い場合は404)を返してfalseを返します。
+func (h *PostHandler) authorizeModify(c *gin.Context, id int64) bool {
+	err := h.policy.CanModify(c.Request.Context(), middleware.Principal(c), id)
+	if err == nil {
+		return true
+	}
+
+	respondError(c, err, "failed to get post")
+	return false
+}
+
+// respondStatusChangeはpublish/unpublishの結果を返します。
+func (h *PostHandler) respondStatusChange(c *gin.Context, post model.Post, err error, failure string) {
+	if err != nil {
+		respondError(c, err, failure, versionConflictIsConflict...)
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusOK, post)
+}
+
+func (h *PostHandler) listRevisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return
+	}
+
+	revisions, err := h.service.Revisions(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err, "failed to list revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+func (h *PostHandler) getRevision(c *gin.Context) {
+	id, rev, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	revision, err := h.service.Revision(c.Request.Context(), id, rev)
+	if err != nil {
+		respondError(c, err, "failed to get revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+func (h *PostHandler) revertRevision(c *gin.Context) {
+	id, rev, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorizeModify(c, id) {
+		return
+	}
+
+	post, err := h.service.Revert(c.Request.Context(), id, rev, middleware.Actor(c))
+	if err != nil {
+		respondError(c, err, "failed to revert post", versionConflictIsConflict...)
+		return
+	}
+
+	c.Header("ETag", postETag(post))
+	c.JSON(http.StatusOK, post)
+}
+
+// parseRevisionParamsは:idと:revを読み取ります。不正な場合は400を返してokにfalseを返します。
+func parseRevisionParams(c *gin.Context) (id, rev int64, ok bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		invalidField(c, "id", "invalid", "invalid id")
+		return 0, 0, false
+	}
+	rev, err = strconv.ParseInt(c.Param("rev"), 10, 64)
+	if err != nil {
+		invalidField(c, "rev", "invalid", "invalid revision")
+		return 0, 0, false
+	}
+	return id, rev, true
+}
@@ -2,14 +2,19 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/kitakitabauer/gin-sample-app/config"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
 	"github.com/kitakitabauer/gin-sample-app/model"
 	"github.com/kitakitabauer/gin-sample-app/repository"
 	"github.com/kitakitabauer/gin-sample-app/service"
@@ -19,8 +24,10 @@ func setAPIKeyForTest(t *testing.T, key string) func() {
 	t.Helper()
 
 	original := config.AppConfig
+	// Without a key the tests run with authentication disabled, as a local setup would.
 	config.AppConfig = &config.Config{
-		APIKey: key,
+		APIKey:       key,
+		AuthDisabled: key == "",
 	}
 
 	return func() {
@@ -34,7 +41,10 @@ func setupTestRouter(t *testing.T) (*gin.Engine, *repository.InMemoryPostReposit
 	gin.SetMode(gin.TestMode)
 
 	repo := repository.NewInMemoryPostRepository()
-	svc := service.NewPostService(repo)
+	revisions := repository.NewInMemoryRevisionRepository()
+	authors := repository.NewInMemoryAuthorRepository(repo)
+	svc := service.NewPostService(repo, revisions, authors)
+	svc.SetTxManager(repository.NewInMemoryTxManager(repo, revisions, authors))
 	handler := NewPostHandler(svc)
 
 	router := gin.New()
@@ -138,13 +148,20 @@ func TestPostHandler_CreatePost_ValidationError(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
 	}
 
-	var body map[string]string
+	if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Fatalf("expected Content-Type %q, got %q", problem.ContentType, ct)
+	}
+	var body problem.Details
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("unexpected response body: %v", err)
 	}
 
-	if body["error"] != service.ErrTitleRequired.Error() {
-		t.Fatalf("expected error %q, got %q", service.ErrTitleRequired.Error(), body["error"])
+	if body.Status != http.StatusBadRequest || body.Code != problem.CodeValidationFailed || body.Instance != "/posts" {
+		t.Fatalf("unexpected problem: %s", rec.Body.String())
+	}
+	want := problem.FieldError{Field: "title", Code: "required", Message: service.ErrTitleRequired.Error()}
+	if len(body.Errors) != 1 || body.Errors[0] != want {
+		t.Fatalf("expected field error %+v, got %+v", want, body.Errors)
 	}
 }
 
@@ -163,13 +180,13 @@ func TestPostHandler_UpdatePost_NoFields(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
 	}
 
-	var body map[string]string
+	var body problem.Details
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("unexpected response body: %v", err)
 	}
 
-	if body["error"] != service.ErrNoFieldsToUpdate.Error() {
-		t.Fatalf("expected error %q, got %q", service.ErrNoFieldsToUpdate.Error(), body["error"])
+	if body.Code != "no_fields_to_update" || body.Detail != service.ErrNoFieldsToUpdate.Error() {
+		t.Fatalf("expected the no_fields_to_update problem, got %s", rec.Body.String())
 	}
 }
 
@@ -209,6 +226,54 @@ func TestPostHandler_UpdatePost_Success(t *testing.T) {
 	if updated.Title != "updated title" {
 		t.Fatalf("expected title to be updated, got %q", updated.Title)
 	}
+	if updated.UpdatedBy != "api-key" {
+		t.Fatalf("expected updated_by to record the API key actor, got %q", updated.UpdatedBy)
+	}
+}
+
+func TestPostHandler_UpdatePost_IfMatch(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, repo := setupTestRouter(t)
+	if _, err := repo.Create(context.Background(), model.Post{Title: "t", Content: "c", Author: "a"}); err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/1", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	etag := rec.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag %q, got %q", `"1"`, etag)
+	}
+
+	patch := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/posts/1", bytes.NewReader([]byte(`{"title":"edited"}`)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = patch(etag)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != `"2"` {
+		t.Fatalf("expected ETag %q after update, got %q", `"2"`, got)
+	}
+
+	if rec = patch(etag); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status %d for stale ETag, got %d", http.StatusPreconditionFailed, rec.Code)
+	}
+	if rec = patch(`W/"2"`); rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected status %d for weak ETag, got %d", http.StatusPreconditionFailed, rec.Code)
+	}
+	if rec = patch("2"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for malformed If-Match, got %d", http.StatusBadRequest, rec.Code)
+	}
 }
 
 func TestPostHandler_DeletePost_NotFound(t *testing.T) {
@@ -226,12 +291,423 @@ func TestPostHandler_DeletePost_NotFound(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
 	}
 
-	var body map[string]string
+	var body problem.Details
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+
+	if body.Code != "post_not_found" || body.Title != http.StatusText(http.StatusNotFound) {
+		t.Fatalf("expected the post_not_found problem, got %s", rec.Body.String())
+	}
+}
+
+func TestPostHandler_ListPosts_Pagination(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, repo := setupTestRouter(t)
+	for i := 0; i < 3; i++ {
+		if _, err := repo.Create(context.Background(), model.Post{Title: "t", Content: "c", Author: "a"}); err != nil {
+			t.Fatalf("failed to seed post: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/posts?limit=2", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var page struct {
+		Posts      []model.Post `json:"posts"`
+		NextCursor *string      `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(page.Posts) != 2 || page.NextCursor == nil {
+		t.Fatalf("unexpected first page: %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts?limit=2&cursor="+*page.NextCursor, nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	page.NextCursor = nil
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(page.Posts) != 1 || page.NextCursor != nil {
+		t.Fatalf("unexpected second page: %s", rec.Body.String())
+	}
+}
+
+func TestPostHandler_ListPosts_InvalidParams(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, _ := setupTestRouter(t)
+
+	for _, query := range []string{"limit=abc", "limit=1000", "cursor=broken", "sort=title", "created_after=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/posts?"+query, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", query, http.StatusBadRequest, rec.Code)
+		}
+	}
+}
+
+func TestPostHandler_ListPosts_FilterAndSort(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, repo := setupTestRouter(t)
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, author := range []string{"alice", "bob", "alice"} {
+		post := model.Post{Title: "t", Content: "c", Author: author, CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		if _, err := repo.Create(context.Background(), post); err != nil {
+			t.Fatalf("failed to seed post: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/posts?author=alice&created_before=2025-01-01T03:00:00Z&sort=-created_at", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var page struct {
+		Posts []model.Post `json:"posts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(page.Posts) != 2 || page.Posts[0].ID != 3 || page.Posts[1].ID != 1 {
+		t.Fatalf("unexpected posts: %+v", page.Posts)
+	}
+}
+
+func TestPostHandler_SearchPosts(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, repo := setupTestRouter(t)
+	if _, err := repo.Create(context.Background(), model.Post{Title: "Hello Gin", Content: "c", Author: "a"}); err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/posts/search?q=gin", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	var body struct {
+		Results []model.PostSearchHit `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(body.Results) != 1 || body.Results[0].Highlight.Title != "Hello <mark>Gin</mark>" {
+		t.Fatalf("unexpected results: %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts/search", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPostHandler_Revisions(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+
+	router, repo := setupTestRouter(t)
+	if _, err := repo.Create(context.Background(), model.Post{Title: "original", Content: "c", Author: "a"}); err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+
+	do := func(method, path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPatch, "/posts/1", []byte(`{"title":"edited"}`)); rec.Code != http.StatusOK {
+		t.Fatalf("failed to update post, status %d body %s", rec.Code, rec.Body.String())
+	}
+
+	rec := do(http.MethodGet, "/posts/1/revisions", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var list struct {
+		Revisions []model.PostRevision `json:"revisions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(list.Revisions) != 1 || list.Revisions[0].Title != "original" || list.Revisions[0].Actor != "api-key" {
+		t.Fatalf("unexpected revisions: %s", rec.Body.String())
+	}
+
+	if rec := do(http.MethodGet, "/posts/1/revisions/1", nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	if rec := do(http.MethodGet, "/posts/1/revisions/9", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+
+	rec = do(http.MethodPost, "/posts/1/revisions/1/revert", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var reverted model.Post
+	if err := json.Unmarshal(rec.Body.Bytes(), &reverted); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if reverted.Title != "original" {
+		t.Fatalf("expected title to be reverted, got %q", reverted.Title)
+	}
+}
+
+func TestPostHandler_PublicVisibility(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+
+	router, repo := setupTestRouter(t)
+	ctx := context.Background()
+	draft, err := repo.Create(ctx, model.Post{Title: "draft post", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+	published, err := repo.Create(ctx, model.Post{Title: "published post", Content: "c", Author: "a", Status: model.PostStatusPublished})
+	if err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+
+	listIDs := func(target, apiKey string) []int64 {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status %d, got %d", target, http.StatusOK, rec.Code)
+		}
+		var page struct {
+			Posts []model.Post `json:"posts"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("unexpected response body: %v", err)
+		}
+		ids := make([]int64, 0, len(page.Posts))
+		for _, post := range page.Posts {
+			ids = append(ids, post.ID)
+		}
+		return ids
+	}
+
+	if ids := listIDs("/posts?status=draft", ""); len(ids) != 1 || ids[0] != published.ID {
+		t.Fatalf("expected public list to show only published posts, got %v", ids)
+	}
+	if ids := listIDs("/posts", "secret"); len(ids) != 2 {
+		t.Fatalf("expected authenticated list to show all posts, got %v", ids)
+	}
+	if ids := listIDs("/posts?status=draft", "secret"); len(ids) != 1 || ids[0] != draft.ID {
+		t.Fatalf("expected status filter to select drafts, got %v", ids)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/posts/%d", draft.ID), nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected draft to be hidden from public, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts/search?q=post", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var body struct {
+		Results []model.PostSearchHit `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(body.Results) != 1 || body.Results[0].Post.ID != published.ID {
+		t.Fatalf("expected public search to show only published posts, got %s", rec.Body.String())
+	}
+}
+
+func TestPostHandler_PublishAndUnpublish(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, "secret"))
+
+	router, repo := setupTestRouter(t)
+	created, err := repo.Create(context.Background(), model.Post{Title: "t", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+
+	do := func(target, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	publishURL := fmt.Sprintf("/posts/%d/publish", created.ID)
+	unpublishURL := fmt.Sprintf("/posts/%d/unpublish", created.ID)
+
+	future := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	rec := do(publishURL, fmt.Sprintf(`{"publish_at":%q}`, future))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var post model.Post
+	if err := json.Unmarshal(rec.Body.Bytes(), &post); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if post.Status != model.PostStatusScheduled {
+		t.Fatalf("expected scheduled post, got %+v", post)
+	}
+
+	if rec := do(publishURL, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := do(publishURL, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d when already published, got %d", http.StatusConflict, rec.Code)
+	}
+	if rec := do(unpublishURL, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec := do("/posts/999/unpublish", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestPostHandler_Tags(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	gin.SetMode(gin.TestMode)
+	postRepository := repository.NewInMemoryPostRepository()
+	svc := service.NewPostService(postRepository, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(postRepository))
+	router := gin.New()
+	NewPostHandler(svc).RegisterRoutes(router)
+	NewTagHandler(svc).RegisterRoutes(router)
+
+	for _, payload := range []string{
+		`{"title":"one","content":"c","author":"a","tags":["Go","gin"]}`,
+		`{"title":"two","content":"c","author":"a","tags":["go"]}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/posts", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/tags", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	var body struct {
+		Tags []model.Tag `json:"tags"`
+	}
 	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
 		t.Fatalf("unexpected response body: %v", err)
 	}
+	if len(body.Tags) != 2 || body.Tags[0] != (model.Tag{Name: "go", PostCount: 2}) {
+		t.Fatalf("unexpected tags: %+v", body.Tags)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts?tag=gin", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var page struct {
+		Posts []model.Post `json:"posts"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("unexpected response body: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].Title != "one" {
+		t.Fatalf("unexpected posts for tag: %+v", page.Posts)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/posts/%d", page.Posts[0].ID), bytes.NewBufferString(`{"tags":[""]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an empty tag, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestPostHandler_GetPostBySlug(t *testing.T) {
+	t.Cleanup(setAPIKeyForTest(t, ""))
+
+	router, repo := setupTestRouter(t)
+	created, err := repo.Create(context.Background(), model.Post{Title: "はじめての投稿", Slug: "はじめての投稿", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+	if _, err := repo.Create(context.Background(), model.Post{Title: "other", Slug: "other", Content: "c", Author: "a"}); err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
 
-	if body["error"] != "post not found" {
-		t.Fatalf("expected error 'post not found', got %q", body["error"])
+	req := httptest.NewRequest(http.MethodGet, "/posts/by-slug/"+url.PathEscape(created.Slug), nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/posts/%d", created.ID), bytes.NewBufferString(`{"slug":"First Post"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts/by-slug/"+url.PathEscape(created.Slug), nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected status %d, got %d", http.StatusMovedPermanently, rec.Code)
+	}
+	if location := rec.Header().Get("Location"); location != "/posts/by-slug/first-post" {
+		t.Fatalf("unexpected redirect location: %q", location)
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/posts/%d", created.ID), bytes.NewBufferString(`{"slug":"other"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/posts/by-slug/missing", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
 	}
 }
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+type TagHandler struct {
+	posts *service.PostService
+}
+
+func NewTagHandler(posts *service.PostService) *TagHandler {
+	return &TagHandler{posts: posts}
+}
+
+func (h *TagHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/tags", h.listTags)
+}
+
+func (h *TagHandler) listTags(c *gin.Context) {
+	tags, err := h.posts.Tags(c.Request.Context(), visibleStatus(c, c.Query("status")))
+	if err != nil {
+		respondError(c, err, "failed to list tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
@@ -2,6 +2,7 @@ package integration
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -33,7 +34,10 @@ func newIntegrationService(t *testing.T) (*service.PostService, func()) {
 	}
 
 	repo := repository.NewSQLPostRepository(db, "sqlite")
-	svc := service.NewPostService(repo)
+	revisions := repository.NewSQLRevisionRepository(db, "sqlite")
+	authors := repository.NewSQLAuthorRepository(db, "sqlite")
+	svc := service.NewPostService(repo, revisions, authors)
+	svc.SetTxManager(repository.NewSQLTxManager(db))
 
 	cleanup := func() {
 		db.Close()
@@ -52,7 +56,7 @@ func TestPostLifecycleIntegration(t *testing.T) {
 		Author:  "Alice",
 	}
 
-	created, err := svc.Create(ctx, original.Title, original.Content, original.Author)
+	created, err := svc.Create(ctx, service.CreatePostInput{Title: original.Title, Content: original.Content, Author: original.Author})
 	if err != nil {
 		t.Fatalf("Create failed: %v", err)
 	}
@@ -61,12 +65,12 @@ func TestPostLifecycleIntegration(t *testing.T) {
 		t.Fatalf("expected created post to have an ID")
 	}
 
-	posts, err := svc.List(ctx)
+	page, err := svc.List(ctx, repository.PostQuery{})
 	if err != nil {
 		t.Fatalf("List failed: %v", err)
 	}
-	if len(posts) != 1 {
-		t.Fatalf("expected 1 post, got %d", len(posts))
+	if len(page.Posts) != 1 {
+		t.Fatalf("expected 1 post, got %d", len(page.Posts))
 	}
 
 	fetched, err := svc.Get(ctx, created.ID)
@@ -79,7 +83,7 @@ func TestPostLifecycleIntegration(t *testing.T) {
 
 	newTitle := "Updated Post"
 	newContent := "Updated Content"
-	updated, err := svc.Update(ctx, created.ID, &newTitle, &newContent, nil)
+	updated, err := svc.Update(ctx, created.ID, service.UpdatePostInput{Title: &newTitle, Content: &newContent})
 	if err != nil {
 		t.Fatalf("Update failed: %v", err)
 	}
@@ -95,3 +99,60 @@ func TestPostLifecycleIntegration(t *testing.T) {
 		t.Fatalf("expected ErrPostNotFound after delete, got %v", err)
 	}
 }
+
+func TestPostBatchIntegration(t *testing.T) {
+	ctx := context.Background()
+	svc, cleanup := newIntegrationService(t)
+	defer cleanup()
+
+	existing, err := svc.Create(ctx, service.CreatePostInput{Title: "Existing", Content: "Body", Author: "Alice"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	title := "Renamed"
+	operations := []service.BatchOperation{
+		{Type: service.BatchCreate, Create: service.CreatePostInput{Title: "Batch", Content: "Body", Author: "Bob"}},
+		{Type: service.BatchUpdate, ID: existing.ID, Update: service.UpdatePostInput{Title: &title}},
+		{Type: service.BatchDelete, ID: 999},
+	}
+
+	// 3件目が失敗するため、1件目の作成と2件目の更新も取り消されます。
+	_, err = svc.Batch(ctx, service.BatchInput{Operations: operations, Atomic: true})
+	var berr *service.BatchError
+	if !errors.As(err, &berr) || berr.Index != 2 || !errors.Is(err, repository.ErrPostNotFound) {
+		t.Fatalf("expected BatchError for operation 2, got %v", err)
+	}
+	page, err := svc.List(ctx, repository.PostQuery{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].Title != "Existing" || page.Posts[0].Version != 1 {
+		t.Fatalf("expected the atomic batch to be rolled back, got %+v", page.Posts)
+	}
+	revisions, err := svc.Revisions(ctx, existing.ID)
+	if err != nil {
+		t.Fatalf("Revisions failed: %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Fatalf("expected revisions to be rolled back, got %+v", revisions)
+	}
+
+	// 同じBatchで作成したPostを後続の操作で更新できます。
+	operations[2] = service.BatchOperation{Type: service.BatchUpdate, ID: existing.ID + 1, Update: service.UpdatePostInput{Title: &title}}
+	operations[1].Update = service.UpdatePostInput{Content: &title}
+	results, err := svc.Batch(ctx, service.BatchInput{Operations: operations, Atomic: true})
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+	if len(results) != 3 || results[0].Post.ID != existing.ID+1 || results[2].Post.Title != title || results[2].Post.Version != 2 {
+		t.Fatalf("unexpected batch results: %+v", results)
+	}
+	revisions, err = svc.Revisions(ctx, existing.ID)
+	if err != nil {
+		t.Fatalf("Revisions failed: %v", err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("expected one revision after the batch, got %+v", revisions)
+	}
+}
@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+func TestPostTracingIntegration(t *testing.T) {
+	ctx := context.Background()
+	svc, cleanup := newIntegrationService(t)
+	defer cleanup()
+
+	created, err := svc.Create(ctx, service.CreatePostInput{Title: "Traced", Content: "content", Author: "Alice"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	title := "Traced (edited)"
+	if _, err := svc.Update(ctx, created.ID, service.UpdatePostInput{Title: &title}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	// Updateのspanの下に、同じトレースで発行したクエリのspanが記録されます。
+	var root sdktrace.ReadOnlySpan
+	operations := map[string]int{}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "PostService.Update" {
+			root = span
+		}
+	}
+	if root == nil {
+		t.Fatalf("expected a PostService.Update span")
+	}
+	for _, span := range recorder.Ended() {
+		if span == root {
+			continue
+		}
+		if span.SpanContext().TraceID() != root.SpanContext().TraceID() {
+			t.Fatalf("expected span %q in the same trace", span.Name())
+		}
+		operations[span.Name()]++
+	}
+	for _, op := range []string{"SELECT", "UPDATE", "INSERT"} {
+		if operations[op] == 0 {
+			t.Fatalf("expected a %s span, got %v", op, operations)
+		}
+	}
+}
@@ -27,7 +27,12 @@ func Open(ctx context.Context, cfg Config) (*sql.DB, error) {
 		return nil, err
 	}
 
-	db, err := sql.Open(driverName, cfg.DSN)
+	dsn := cfg.DSN
+	if driverName == "sqlite" {
+		dsn = sqliteDSN(dsn)
+	}
+
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, err
 	}
@@ -64,3 +69,16 @@ func normalizeDriver(name string) (string, error) {
 		return name, nil
 	}
 }
+
+// sqliteDSN は接続ごとに外部キー制約が有効になるよう _pragma=foreign_keys(1) を付与します。
+// modernc.org/sqlite は mattn/go-sqlite3 形式の _foreign_keys=1 を解釈しないためです。
+func sqliteDSN(dsn string) string {
+	if strings.Contains(dsn, "foreign_keys(") {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=foreign_keys(1)"
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_posts_updated_at;
+ALTER TABLE posts DROP COLUMN IF EXISTS updated_by;
+ALTER TABLE posts DROP COLUMN IF EXISTS updated_at;
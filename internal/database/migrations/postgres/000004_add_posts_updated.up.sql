@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS updated_by TEXT NOT NULL DEFAULT '';
+UPDATE posts SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE posts ALTER COLUMN updated_at SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_updated_at ON posts (updated_at);
@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'
+    CONSTRAINT posts_status_check CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+UPDATE posts SET publish_at = created_at WHERE publish_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_status_publish_at ON posts (status, publish_at);
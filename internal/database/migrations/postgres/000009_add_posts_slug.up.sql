@@ -0,0 +1,9 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS slug TEXT;
+UPDATE posts SET slug = 'post-' || id WHERE slug IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_posts_slug ON posts (slug);
+CREATE TABLE IF NOT EXISTS post_slugs (
+    slug TEXT PRIMARY KEY,
+    post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_post_slugs_post_id ON post_slugs (post_id);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS comments (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL REFERENCES posts (id) ON DELETE CASCADE,
+    parent_id BIGINT REFERENCES comments (id) ON DELETE CASCADE,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_comments_post_id_parent_id ON comments (post_id, parent_id, id);
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments (parent_id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS authors (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_authors_name ON authors (LOWER(name COLLATE "C"));
+UPDATE posts SET author = BTRIM(REGEXP_REPLACE(author, '\s+', ' ', 'g'));
+INSERT INTO authors (name, created_at, updated_at)
+SELECT MIN(author), MIN(created_at), MIN(created_at)
+FROM posts
+WHERE author <> ''
+GROUP BY LOWER(author COLLATE "C");
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS author_id BIGINT REFERENCES authors (id);
+UPDATE posts p SET author_id = a.id, author = a.name
+FROM authors a
+WHERE LOWER(a.name COLLATE "C") = LOWER(p.author COLLATE "C");
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts (author_id);
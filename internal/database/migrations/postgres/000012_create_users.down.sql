@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_owner_id;
+ALTER TABLE posts DROP COLUMN IF EXISTS owner_id;
+DROP INDEX IF EXISTS idx_users_username;
+DROP TABLE IF EXISTS users;
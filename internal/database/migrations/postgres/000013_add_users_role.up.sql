@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'author'
+    CONSTRAINT users_role_check CHECK (role IN ('admin', 'editor', 'author'));
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS hmac_nonces (
+    nonce TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_hmac_nonces_expires_at ON hmac_nonces (expires_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_post_revisions_author_id;
+ALTER TABLE post_revisions DROP COLUMN IF EXISTS author_id;
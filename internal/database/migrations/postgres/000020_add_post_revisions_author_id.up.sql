@@ -0,0 +1,5 @@
+ALTER TABLE post_revisions ADD COLUMN IF NOT EXISTS author_id BIGINT REFERENCES authors (id) ON DELETE SET NULL;
+UPDATE post_revisions r SET author_id = a.id
+FROM authors a
+WHERE LOWER(a.name COLLATE "C") = LOWER(BTRIM(REGEXP_REPLACE(r.author, '\s+', ' ', 'g')) COLLATE "C");
+CREATE INDEX IF NOT EXISTS idx_post_revisions_author_id ON post_revisions (author_id);
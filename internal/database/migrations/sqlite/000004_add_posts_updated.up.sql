@@ -0,0 +1,4 @@
+ALTER TABLE posts ADD COLUMN updated_at TIMESTAMP;
+ALTER TABLE posts ADD COLUMN updated_by TEXT NOT NULL DEFAULT '';
+UPDATE posts SET updated_at = created_at WHERE updated_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_updated_at ON posts (updated_at);
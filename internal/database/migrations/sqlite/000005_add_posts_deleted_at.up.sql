@@ -0,0 +1,2 @@
+ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON posts (deleted_at);
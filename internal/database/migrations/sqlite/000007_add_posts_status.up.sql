@@ -0,0 +1,5 @@
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE posts ADD COLUMN publish_at TIMESTAMP;
+UPDATE posts SET publish_at = created_at WHERE publish_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_status_publish_at ON posts (status, publish_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_post_slugs_post_id;
+DROP TABLE IF EXISTS post_slugs;
+DROP INDEX IF EXISTS idx_posts_slug;
+ALTER TABLE posts DROP COLUMN slug;
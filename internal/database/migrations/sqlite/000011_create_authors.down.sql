@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_author_id;
+ALTER TABLE posts DROP COLUMN author_id;
+DROP INDEX IF EXISTS idx_authors_name;
+DROP TABLE IF EXISTS authors;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS authors (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL COLLATE NOCASE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_authors_name ON authors (name);
+UPDATE posts SET author = TRIM(REPLACE(REPLACE(REPLACE(
+    REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(author, char(9), ' '), char(10), ' '), char(11), ' '), char(12), ' '), char(13), ' '),
+    ' ', ' ' || char(1)), char(1) || ' ', ''), char(1), ''));
+INSERT INTO authors (name, created_at, updated_at)
+SELECT MIN(author), MIN(created_at), MIN(created_at)
+FROM posts
+WHERE author <> ''
+GROUP BY author COLLATE NOCASE;
+ALTER TABLE posts ADD COLUMN author_id INTEGER REFERENCES authors (id);
+UPDATE posts SET
+    author_id = (SELECT a.id FROM authors a WHERE a.name = posts.author),
+    author = COALESCE((SELECT a.name FROM authors a WHERE a.name = posts.author), author);
+CREATE INDEX IF NOT EXISTS idx_posts_author_id ON posts (author_id);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    bucket TEXT PRIMARY KEY,
+    tokens REAL NOT NULL,
+    refilled_at TIMESTAMP NOT NULL,
+    version INTEGER NOT NULL
+);
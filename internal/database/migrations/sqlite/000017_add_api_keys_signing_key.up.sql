@@ -0,0 +1 @@
+ALTER TABLE api_keys ADD COLUMN signing_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_rate_limits_full_at;
+ALTER TABLE rate_limits DROP COLUMN full_at;
@@ -0,0 +1,2 @@
+ALTER TABLE rate_limits ADD COLUMN full_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+CREATE INDEX IF NOT EXISTS idx_rate_limits_full_at ON rate_limits (full_at);
@@ -0,0 +1,8 @@
+ALTER TABLE post_revisions ADD COLUMN author_id INTEGER REFERENCES authors (id) ON DELETE SET NULL;
+UPDATE post_revisions SET author_id = (
+    SELECT a.id FROM authors a
+    WHERE a.name = TRIM(REPLACE(REPLACE(REPLACE(
+        REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(post_revisions.author, char(9), ' '), char(10), ' '), char(11), ' '), char(12), ' '), char(13), ' '),
+        ' ', ' ' || char(1)), char(1) || ' ', ''), char(1), ''))
+);
+CREATE INDEX IF NOT EXISTS idx_post_revisions_author_id ON post_revisions (author_id);
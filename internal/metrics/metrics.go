@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gin_sample_app"
+
+// Metrics holds the Prometheus collectors exposed on /metrics. It uses its
+// own registry so that several instances (for example in tests) do not clash.
+type Metrics struct {
+	registry         *prometheus.Registry
+	httpRequests     *prometheus.CounterVec
+	httpDuration     *prometheus.HistogramVec
+	repoDuration     *prometheus.HistogramVec
+	migrationVersion prometheus.Gauge
+	migrationDirty   prometheus.Gauge
+}
+
+// New registers the HTTP, repository and migration collectors together with
+// the Go runtime, process and sql.DB connection pool collectors. db may be
+// nil, in which case no pool statistics are exported.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by route template, method and status.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		repoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "repository_operation_duration_seconds",
+			Help:      "Repository operation latency by repository and operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"repository", "operation"}),
+		migrationVersion: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "migration_version",
+			Help:      "Database migration version applied at startup.",
+		}),
+		migrationDirty: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "migration_dirty",
+			Help:      "1 if the last migration failed and left the database dirty.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.repoDuration,
+		m.migrationVersion,
+		m.migrationDirty,
+	)
+	if db != nil {
+		m.registry.MustRegister(collectors.NewDBStatsCollector(db, "main"))
+	}
+	return m
+}
+
+// Handler serves the registered metrics in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records one HTTP request. route is the route template such
+// as "/posts/:id", never the raw path, to keep label cardinality bounded.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, code).Inc()
+	m.httpDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// RepositoryObserver returns a function recording operation latencies for
+// the named repository, for use with repository.NewInstrumentedPostRepository.
+func (m *Metrics) RepositoryObserver(repository string) func(operation string, duration time.Duration) {
+	return func(operation string, duration time.Duration) {
+		m.repoDuration.WithLabelValues(repository, operation).Observe(duration.Seconds())
+	}
+}
+
+// SetMigrationVersion records the migration version reported by the migrator.
+func (m *Metrics) SetMigrationVersion(version uint, dirty bool) {
+	m.migrationVersion.Set(float64(version))
+	if dirty {
+		m.migrationDirty.Set(1)
+	} else {
+		m.migrationDirty.Set(0)
+	}
+}
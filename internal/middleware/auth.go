@@ -1,34 +1,236 @@
 package middleware
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kitakitabauer/gin-sample-app/config"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/policy"
 )
 
-const apiKeyHeader = "X-API-Key"
+const (
+	apiKeyHeader = "X-API-Key"
+	actorKey     = "actor"
+	userKey      = "user"
+	apiKeyKey    = "api_key"
+	bearerPrefix = "Bearer "
 
-func RequireAPIKey() gin.HandlerFunc {
+	// AnonymousActor is recorded for unauthenticated callers, which only
+	// reach protected routes when AUTH_DISABLED is set.
+	AnonymousActor = "anonymous"
+	// APIKeyActor is recorded for requests authenticated with the bootstrap
+	// API_KEY. Stored keys are recorded as "api-key:<name>".
+	APIKeyActor = "api-key"
+)
+
+// UserAuthenticator resolves the user an access token was issued to.
+type UserAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (model.User, error)
+}
+
+// Authenticate stores the user of an "Authorization: Bearer" access token in
+// the context, where RequireAuth, Authenticated and CurrentUser find it.
+// Requests without a bearer token pass through untouched; an invalid or
+// expired token is rejected with 401 so clients notice they must refresh.
+func Authenticate(authn UserAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if config.AppConfig == nil {
+		header := c.GetHeader("Authorization")
+		if !strings.HasPrefix(header, bearerPrefix) {
 			c.Next()
 			return
 		}
 
-		expected := config.AppConfig.APIKey
-		if expected == "" {
+		user, err := authn.Authenticate(c.Request.Context(), strings.TrimSpace(strings.TrimPrefix(header, bearerPrefix)))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			problem.Abort(c, problem.New(http.StatusUnauthorized, "invalid_token", "invalid token"))
+			return
+		}
+
+		c.Set(userKey, user)
+		c.Set(actorKey, user.Username)
+		c.Next()
+	}
+}
+
+// CurrentUser returns the user authenticated by a bearer token. It reports
+// false for anonymous requests and requests authenticated with the API key.
+func CurrentUser(c *gin.Context) (model.User, bool) {
+	value, ok := c.Get(userKey)
+	if !ok {
+		return model.User{}, false
+	}
+	user, ok := value.(model.User)
+	return user, ok
+}
+
+// Actor returns the name of the authenticated caller: the username for
+// bearer tokens, or APIKeyActor for the shared API key.
+func Actor(c *gin.Context) string {
+	if actor := c.GetString(actorKey); actor != "" {
+		return actor
+	}
+	return AnonymousActor
+}
+
+// APIKeyAuthenticator resolves the stored API key matching a secret.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, secret string) (model.APIKey, error)
+}
+
+// AuthenticateAPIKey looks up the X-API-Key header among the stored keys
+// and keeps the matching key in the context for RequireAPIKey. Unknown,
+// expired and revoked keys pass through untouched, so public routes keep
+// treating the caller as anonymous while protected routes reject it.
+func AuthenticateAPIKey(keys APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(apiKeyHeader)
+		if secret == "" || isBootstrapKey(secret) {
+			c.Next()
+			return
+		}
+
+		if key, err := keys.AuthenticateAPIKey(c.Request.Context(), secret); err == nil {
+			c.Set(apiKeyKey, key)
+			c.Set(actorKey, APIKeyActor+":"+key.Name)
+		}
+		c.Next()
+	}
+}
+
+// CurrentAPIKey returns the stored API key the request was authenticated
+// with. It reports false for the bootstrap API_KEY.
+func CurrentAPIKey(c *gin.Context) (model.APIKey, bool) {
+	value, ok := c.Get(apiKeyKey)
+	if !ok {
+		return model.APIKey{}, false
+	}
+	key, ok := value.(model.APIKey)
+	return key, ok
+}
+
+// Authenticated reports whether the request would pass RequireAuth.
+// Public routes use it to decide how much to reveal; it is always true
+// when AUTH_DISABLED is set.
+func Authenticated(c *gin.Context) bool {
+	return authenticated(c) || !authEnabled()
+}
+
+// authenticated reports whether the caller presented valid credentials: a
+// bearer token, a stored API key or the bootstrap API_KEY.
+func authenticated(c *gin.Context) bool {
+	if _, ok := CurrentUser(c); ok {
+		return true
+	}
+	if _, ok := CurrentAPIKey(c); ok {
+		return true
+	}
+	return isBootstrapKey(c.GetHeader(apiKeyHeader))
+}
+
+// authEnabled reports whether protected routes require credentials. It is
+// only false when AUTH_DISABLED is set explicitly; an unset API_KEY merely
+// disables the bootstrap key.
+func authEnabled() bool {
+	return config.AppConfig == nil || !config.AppConfig.AuthDisabled
+}
+
+// isBootstrapKey compares secret with the configured API_KEY in constant
+// time. Hashing first keeps the comparison independent of the key length.
+func isBootstrapKey(secret string) bool {
+	if config.AppConfig == nil || config.AppConfig.APIKey == "" || secret == "" {
+		return false
+	}
+	got := sha256.Sum256([]byte(secret))
+	want := sha256.Sum256([]byte(config.AppConfig.APIKey))
+	return subtle.ConstantTimeCompare(got[:], want[:]) == 1
+}
+
+// RequireAPIKey admits requests carrying the bootstrap API_KEY, which is
+// granted every scope, or a stored key (see AuthenticateAPIKey) holding all
+// of the given scopes. A valid key without the scopes is rejected with 403,
+// and a request without a key with 401 unless AUTH_DISABLED is set.
+func RequireAPIKey(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isBootstrapKey(c.GetHeader(apiKeyHeader)) {
+			c.Set(actorKey, APIKeyActor)
 			c.Next()
 			return
 		}
 
-		if provided := c.GetHeader(apiKeyHeader); provided != expected {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-				"error": "unauthorized",
-			})
+		key, ok := CurrentAPIKey(c)
+		if !ok {
+			if !authEnabled() {
+				c.Next()
+				return
+			}
+			problem.Abort(c, problem.New(http.StatusUnauthorized, "unauthorized", "a bearer token or an API key is required"))
+			return
+		}
+		if !key.HasScopes(scopes...) {
+			Forbid(c, policy.ErrInsufficientScope)
 			return
 		}
+		c.Next()
+	}
+}
 
+// RequireAuth admits requests carrying a valid bearer token (see
+// Authenticate) or an API key with the given scopes, and rejects
+// everything else with 401 unless AUTH_DISABLED is set. Scopes only
+// restrict API keys; users are governed by their role.
+func RequireAuth(scopes ...string) gin.HandlerFunc {
+	apiKey := RequireAPIKey(scopes...)
+	return func(c *gin.Context) {
+		if _, ok := CurrentUser(c); ok {
+			c.Next()
+			return
+		}
+		apiKey(c)
+	}
+}
+
+// Principal describes the caller for authorization decisions. Bearer token
+// users carry their own role; callers admitted by an API key act as admins,
+// as they did before roles existed, and are restricted by scopes instead.
+// Anonymous callers have no role, so they are never admins: with
+// AUTH_DISABLED they act as editors and may modify every post, but admin
+// routes still require credentials.
+func Principal(c *gin.Context) policy.Principal {
+	if user, ok := CurrentUser(c); ok {
+		return policy.Principal{UserID: user.ID, Role: user.Role}
+	}
+	if authenticated(c) {
+		return policy.Principal{Role: model.UserRoleAdmin}
+	}
+	if !authEnabled() {
+		return policy.Principal{Role: model.UserRoleEditor}
+	}
+	return policy.Principal{}
+}
+
+// Forbid aborts the request with a 403 problem carrying the code of a
+// policy error.
+func Forbid(c *gin.Context, err *policy.Error) {
+	problem.Abort(c, problem.New(http.StatusForbidden, err.Code, err.Message))
+}
+
+// RequireAdmin rejects authenticated callers without the admin role. It
+// must run after RequireAuth.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var perr *policy.Error
+		if err := policy.RequireAdmin(Principal(c)); errors.As(err, &perr) {
+			Forbid(c, perr)
+			return
+		}
 		c.Next()
 	}
 }
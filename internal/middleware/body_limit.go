@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+)
+
+// BodyLimit rejects request bodies larger than maxBytes with 413. A declared
+// Content-Length over the limit is rejected before the handler runs; other
+// bodies are wrapped with http.MaxBytesReader, so reading past the limit
+// fails with *http.MaxBytesError (see BodyTooLarge). maxBytes <= 0 disables
+// the limit. It must run before any middleware that reads the body.
+func BodyLimit(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxBytes <= 0 || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBytes {
+			problem.Abort(c, BodyTooLarge(maxBytes))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		c.Next()
+	}
+}
+
+// BodyTooLarge returns the 413 problem for a body over limit bytes.
+func BodyTooLarge(limit int64) problem.Details {
+	return problem.New(http.StatusRequestEntityTooLarge, "body_too_large", fmt.Sprintf("request body must be at most %d bytes", limit))
+}
+
+// bodyReadProblem returns the problem for a failure to read the request body.
+func bodyReadProblem(err error) problem.Details {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return BodyTooLarge(maxErr.Limit)
+	}
+	return problem.New(http.StatusBadRequest, "invalid_body", "failed to read request body")
+}
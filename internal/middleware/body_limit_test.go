@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(BodyLimit(8))
+	router.POST("/", func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			problem.Abort(c, bodyReadProblem(err))
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	serve := func(body string, chunked bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if chunked {
+			// Without a Content-Length the limit is only enforced while reading.
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("12345678", false); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected a body at the limit to be accepted, got %d", rec.Code)
+	}
+	for _, chunked := range []bool{false, true} {
+		rec := serve("123456789", chunked)
+		var body problem.Details
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("unexpected response body: %v", err)
+		}
+		if rec.Code != http.StatusRequestEntityTooLarge || body.Code != "body_too_large" {
+			t.Fatalf("chunked %v: expected 413 body_too_large, got %d %s", chunked, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/config"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/service"
+)
+
+func signedRequest(t *testing.T, keyID int64, secret, method, target, body string, ts time.Time, nonce string) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(service.HMACSigningKey(secret)))
+	mac.Write([]byte(HMACStringToSign(method, req.URL.RequestURI(), []byte(body), timestamp, nonce)))
+	req.Header.Set("Authorization", "HMAC-SHA256 keyId="+strconv.FormatInt(keyID, 10)+
+		",timestamp="+timestamp+",nonce="+nonce+",signature="+base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestAuthenticateHMAC(t *testing.T) {
+	original := config.AppConfig
+	config.AppConfig = &config.Config{APIKey: "bootstrap"}
+	t.Cleanup(func() { config.AppConfig = original })
+	gin.SetMode(gin.TestMode)
+
+	keys := service.NewAPIKeyService(repository.NewInMemoryAPIKeyRepository())
+	writer, writerSecret, err := keys.Create(context.Background(), service.CreateAPIKeyInput{Name: "importer", Scopes: []string{model.APIKeyScopePostsWrite}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	reader, readerSecret, err := keys.Create(context.Background(), service.CreateAPIKeyInput{Name: "ops", Scopes: []string{model.APIKeyScopeAdminLogLevel}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	now := time.Unix(1_750_000_000, 0)
+	originalNow := hmacNow
+	hmacNow = func() time.Time { return now }
+	t.Cleanup(func() { hmacNow = originalNow })
+
+	router := gin.New()
+	router.Use(AuthenticateHMAC(keys, repository.NewInMemoryNonceRepository(), 5*time.Minute))
+	router.POST("/posts", RequireAPIKey(model.APIKeyScopePostsWrite), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, Actor(c)+" "+string(body))
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(signedRequest(t, writer.ID, writerSecret, http.MethodPost, "/posts?draft=1", `{"title":"t"}`, now, "n-1"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	if rec.Body.String() != `api-key:importer {"title":"t"}` {
+		t.Fatalf("expected handler to see the actor and body, got %q", rec.Body.String())
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"replayed nonce", signedRequest(t, writer.ID, writerSecret, http.MethodPost, "/posts?draft=1", `{"title":"t"}`, now, "n-1"), http.StatusUnauthorized},
+		{"wrong secret", signedRequest(t, writer.ID, readerSecret, http.MethodPost, "/posts", `{}`, now, "n-2"), http.StatusUnauthorized},
+		{"unknown key", signedRequest(t, 999, writerSecret, http.MethodPost, "/posts", `{}`, now, "n-3"), http.StatusUnauthorized},
+		{"stale timestamp", signedRequest(t, writer.ID, writerSecret, http.MethodPost, "/posts", `{}`, now.Add(-6*time.Minute), "n-4"), http.StatusUnauthorized},
+		{"future timestamp", signedRequest(t, writer.ID, writerSecret, http.MethodPost, "/posts", `{}`, now.Add(6*time.Minute), "n-5"), http.StatusUnauthorized},
+		{"missing scope", signedRequest(t, reader.ID, readerSecret, http.MethodPost, "/posts", `{}`, now, "n-6"), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := serve(tt.req); rec.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("tampered body", func(t *testing.T) {
+		req := signedRequest(t, writer.ID, writerSecret, http.MethodPost, "/posts", `{"title":"t"}`, now, "n-7")
+		req.Body = io.NopCloser(strings.NewReader(`{"title":"x"}`))
+		if rec := serve(req); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+	"github.com/kitakitabauer/gin-sample-app/logger"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"go.uber.org/zap"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+	// idempotencyLockTimeout is how long a claim may stay in flight before
+	// another request may take it over, e.g. after the process crashed.
+	idempotencyLockTimeout = time.Minute
+	// idempotencySweepInterval is how often expired records are deleted.
+	idempotencySweepInterval = 10 * time.Minute
+)
+
+// replayedHeaders are the response headers stored and replayed along with
+// the status and body.
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// Idempotency makes authenticated writes (POST, PUT, PATCH and DELETE)
+// carrying an Idempotency-Key header safe to retry. The first response is
+// stored for ttl under the key, scoped to the client (see rateLimitClient),
+// together with a hash of the method, URI and body:
+//
+//   - a retry with the same request gets the stored response, marked with
+//     Idempotent-Replayed: true, without running the handler again;
+//   - reusing the key for a different request is rejected with 422;
+//   - a retry arriving while the first request is still running gets 409
+//     with Retry-After, so the handler never runs twice concurrently.
+//
+// Server errors and responses that depend on credentials or timing (401,
+// 403, 429) are not stored, so the request can be retried with the key.
+// It must run after the authentication middlewares.
+func Idempotency(store repository.IdempotencyRepository, ttl time.Duration) gin.HandlerFunc {
+	var (
+		mu        sync.Mutex
+		lastSweep time.Time
+	)
+	sweep := func(c *gin.Context, now time.Time) {
+		mu.Lock()
+		due := now.Sub(lastSweep) >= idempotencySweepInterval
+		if due {
+			lastSweep = now
+		}
+		mu.Unlock()
+		if !due {
+			return
+		}
+		if _, err := store.DeleteExpired(c.Request.Context(), now); err != nil {
+			logger.FromContext(c.Request.Context()).Warn("failed to delete expired idempotency keys", zap.Error(err))
+		}
+	}
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		if key == "" || !isWriteMethod(c.Request.Method) || !Authenticated(c) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			problem.Abort(c, problem.New(http.StatusBadRequest, "invalid_idempotency_key", "Idempotency-Key must be at most 255 characters"))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			problem.Abort(c, bodyReadProblem(err))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now().UTC()
+		sweep(c, now)
+
+		owner, err := newIdempotencyOwner()
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, "internal_error", "failed to process Idempotency-Key"))
+			return
+		}
+		record := repository.IdempotencyRecord{
+			Key:         rateLimitClient(c) + ":" + key,
+			RequestHash: idempotencyRequestHash(c.Request.Method, c.Request.URL.RequestURI(), body),
+			Owner:       owner,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(ttl),
+		}
+
+		existing, claimed, err := store.Claim(c.Request.Context(), record)
+		if err == nil && !claimed && existing.InFlight() && now.Sub(existing.CreatedAt) > idempotencyLockTimeout {
+			// The first request was abandoned; take over its key.
+			if err = store.Release(c.Request.Context(), existing.Key, existing.Owner); err == nil {
+				existing, claimed, err = store.Claim(c.Request.Context(), record)
+			}
+		}
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Error("failed to claim idempotency key", zap.Error(err))
+			problem.Abort(c, problem.New(http.StatusInternalServerError, "internal_error", "failed to process Idempotency-Key"))
+			return
+		}
+
+		if !claimed {
+			switch {
+			case existing.RequestHash != record.RequestHash:
+				problem.Abort(c, problem.New(http.StatusUnprocessableEntity, "idempotency_key_reused", "Idempotency-Key was already used for a different request"))
+			case existing.InFlight():
+				c.Header("Retry-After", "1")
+				problem.Abort(c, problem.New(http.StatusConflict, "idempotency_key_in_use", "a request with this Idempotency-Key is still being processed"))
+			default:
+				for name, value := range existing.Headers {
+					c.Header(name, value)
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Status(existing.StatusCode)
+				_, _ = c.Writer.Write(existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		defer func() {
+			// A panicking handler never reaches Complete. Release the claim
+			// so that retries are not refused as in flight until it expires,
+			// then let the recovery middleware handle the panic.
+			if r := recover(); r != nil {
+				ctx := context.WithoutCancel(c.Request.Context())
+				if err := store.Release(ctx, record.Key, record.Owner); err != nil {
+					logger.FromContext(ctx).Warn("failed to release idempotency key", zap.Error(err))
+				}
+				panic(r)
+			}
+		}()
+		c.Next()
+
+		// The request context may already be cancelled once the handler has
+		// returned, but the outcome still has to be recorded.
+		ctx := c.Request.Context()
+		if ctx.Err() != nil {
+			ctx = context.WithoutCancel(ctx)
+		}
+
+		status := recorder.Status()
+		if !storableStatus(status) {
+			if err := store.Release(ctx, record.Key, record.Owner); err != nil {
+				logger.FromContext(ctx).Warn("failed to release idempotency key", zap.Error(err))
+			}
+			return
+		}
+		headers := make(map[string]string, len(replayedHeaders))
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+		if err := store.Complete(ctx, record.Key, record.Owner, status, headers, recorder.body.Bytes()); err != nil {
+			logger.FromContext(ctx).Warn("failed to store idempotent response", zap.Error(err))
+		}
+	}
+}
+
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func storableStatus(status int) bool {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	default:
+		return status < http.StatusInternalServerError
+	}
+}
+
+func idempotencyRequestHash(method, requestURI string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + "\n" + requestURI + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newIdempotencyOwner() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// bodyRecorder copies the response body while writing it to the client.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,212 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/config"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+// disableAuthForTest treats every request as authenticated, so that the
+// tests exercise Idempotency without credentials.
+func disableAuthForTest(t *testing.T) {
+	t.Helper()
+	original := config.AppConfig
+	config.AppConfig = &config.Config{AuthDisabled: true}
+	t.Cleanup(func() { config.AppConfig = original })
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	disableAuthForTest(t)
+
+	var calls atomic.Int32
+	router := gin.New()
+	router.Use(Idempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour))
+	router.POST("/posts", func(c *gin.Context) {
+		n := calls.Add(1)
+		c.Header("Location", "/posts/1")
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	serve := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := serve("key-1", `{"title":"a"}`)
+	if first.Code != http.StatusCreated || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("unexpected first response: %d %v", first.Code, first.Header())
+	}
+
+	replay := serve("key-1", `{"title":"a"}`)
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Fatalf("expected the stored response, got %d %s", replay.Code, replay.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" || replay.Header().Get("Location") != "/posts/1" ||
+		!strings.HasPrefix(replay.Header().Get("Content-Type"), "application/json") {
+		t.Fatalf("unexpected replay headers: %v", replay.Header())
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls.Load())
+	}
+
+	if rec := serve("key-1", `{"title":"b"}`); rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d for a different payload, got %d", http.StatusUnprocessableEntity, rec.Code)
+	}
+
+	serve("key-2", `{"title":"a"}`)
+	serve("", `{"title":"a"}`)
+	if calls.Load() != 3 {
+		t.Fatalf("expected new and missing keys to run the handler, ran %d times", calls.Load())
+	}
+
+	if rec := serve(strings.Repeat("k", 256), `{}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a long key, got %d", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestIdempotency_ConcurrentDuplicate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	disableAuthForTest(t)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	router.Use(Idempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour))
+	router.POST("/posts", func(c *gin.Context) {
+		close(started)
+		<-release
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		return req
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, newRequest())
+		done <- rec
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected status %d with Retry-After, got %d %v", http.StatusConflict, rec.Code, rec.Header())
+	}
+
+	close(release)
+	if first := <-done; first.Code != http.StatusCreated {
+		t.Fatalf("expected the first request to succeed, got %d", first.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newRequest())
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected the stored response after completion, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestIdempotency_ServerErrorReleasesKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	disableAuthForTest(t)
+
+	var calls atomic.Int32
+	router := gin.New()
+	router.Use(Idempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour))
+	router.POST("/posts", func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "boom"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	for _, want := range []int{http.StatusInternalServerError, http.StatusCreated} {
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("expected status %d, got %d", want, rec.Code)
+		}
+	}
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	disableAuthForTest(t)
+
+	var calls atomic.Int32
+	router := gin.New()
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.Use(Idempotency(repository.NewInMemoryIdempotencyRepository(), time.Hour))
+	router.POST("/posts", func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": 1})
+	})
+
+	for _, want := range []int{http.StatusInternalServerError, http.StatusCreated} {
+		req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("expected status %d, got %d", want, rec.Code)
+		}
+	}
+}
+
+func TestIdempotency_TakesOverAbandonedRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	disableAuthForTest(t)
+
+	store := repository.NewInMemoryIdempotencyRepository()
+	now := time.Now().UTC()
+	req := httptest.NewRequest(http.MethodPost, "/posts", strings.NewReader(`{}`))
+	req.Header.Set("Idempotency-Key", "key-1")
+
+	// A claim left behind by a request that never completed.
+	_, _, err := store.Claim(context.Background(), repository.IdempotencyRecord{
+		Key:         "ip:192.0.2.1:key-1",
+		RequestHash: idempotencyRequestHash(http.MethodPost, "/posts", []byte(`{}`)),
+		Owner:       "crashed",
+		CreatedAt:   now.Add(-2 * idempotencyLockTimeout),
+		ExpiresAt:   now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Claim returned error: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(Idempotency(store, time.Hour))
+	router.POST("/posts", func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{"id": 1}) })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated || rec.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("expected the abandoned key to be taken over, got %d %v", rec.Code, rec.Header())
+	}
+}
@@ -40,6 +40,6 @@ func GinZap() gin.HandlerFunc {
 			fields = append(fields, zap.String("errors", c.Errors.String()))
 		}
 
-		logger.Log.Info("request completed", fields...)
+		logger.FromContext(c.Request.Context()).Info("request completed", fields...)
 	}
 }
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitakitabauer/gin-sample-app/internal/metrics"
+)
+
+// unmatchedRoute labels requests that did not match any route, so that
+// scanning random paths cannot create unbounded label values.
+const unmatchedRoute = "unmatched"
+
+// Metrics records the count and latency of each request by route template,
+// method and status. It must be registered before the recovery middleware,
+// otherwise a panicking request is never observed.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.ObserveRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
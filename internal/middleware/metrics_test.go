@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	m := metrics.New(nil)
+	router := gin.New()
+	router.Use(Metrics(m))
+	router.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	router.GET("/posts/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/panic", func(*gin.Context) { panic("boom") })
+	router.GET("/metrics", gin.WrapH(m.Handler()))
+
+	for _, path := range []string{"/posts/1", "/posts/2", "/unknown", "/panic"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`gin_sample_app_http_requests_total{method="GET",route="/posts/:id",status="200"} 2`,
+		`gin_sample_app_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`gin_sample_app_http_requests_total{method="GET",route="/panic",status="500"} 1`,
+		`gin_sample_app_http_request_duration_seconds_count{method="GET",route="/posts/:id",status="200"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in metrics output:\n%s", want, body)
+		}
+	}
+}
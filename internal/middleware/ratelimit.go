@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
+	"github.com/kitakitabauer/gin-sample-app/logger"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"go.uber.org/zap"
+)
+
+// rateLimitSweepInterval is how often buckets that are full again are deleted.
+const rateLimitSweepInterval = 10 * time.Minute
+
+// RateLimit throttles each client with a token bucket, using the read
+// limit for GET, HEAD and OPTIONS and the write limit for everything else.
+// Clients are told apart by API key, then by user, then by IP, so it must
+// run after the authentication middlewares. A limit of 0 disables it.
+//
+// Every response carries X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (seconds until the bucket is full again); rejected
+// requests get 429 with Retry-After. If the store fails the request is let
+// through, as an unavailable limiter should not take the API down with it.
+// Buckets that have refilled are deleted periodically, so the store only
+// holds clients seen recently.
+func RateLimit(store repository.RateLimitRepository, read, write repository.RateLimit) gin.HandlerFunc {
+	var (
+		mu        sync.Mutex
+		lastSweep time.Time
+	)
+	sweep := func(c *gin.Context, now time.Time) {
+		mu.Lock()
+		due := now.Sub(lastSweep) >= rateLimitSweepInterval
+		if due {
+			lastSweep = now
+		}
+		mu.Unlock()
+		if !due {
+			return
+		}
+		if _, err := store.DeleteRefilled(c.Request.Context(), now); err != nil {
+			logger.FromContext(c.Request.Context()).Warn("failed to delete refilled rate limit buckets", zap.Error(err))
+		}
+	}
+
+	return func(c *gin.Context) {
+		class, limit := "write", write
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			class, limit = "read", read
+		}
+		if limit.Limit <= 0 {
+			c.Next()
+			return
+		}
+
+		now := time.Now()
+		sweep(c, now)
+		result, err := store.Take(c.Request.Context(), class+":"+rateLimitClient(c), limit, now)
+		if err != nil {
+			logger.FromContext(c.Request.Context()).Warn("rate limit store failed; allowing request", zap.Error(err))
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+			problem.Abort(c, problem.New(http.StatusTooManyRequests, "rate_limited", "rate limit exceeded"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitClient identifies the caller a bucket belongs to.
+func rateLimitClient(c *gin.Context) string {
+	if key, ok := CurrentAPIKey(c); ok {
+		return "api-key:" + strconv.FormatInt(key.ID, 10)
+	}
+	if user, ok := CurrentUser(c); ok {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	if isBootstrapKey(c.GetHeader(apiKeyHeader)) {
+		return "api-key:bootstrap"
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
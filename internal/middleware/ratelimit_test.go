@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(context.Context, string, repository.RateLimit, time.Time) (repository.RateLimitResult, error) {
+	return repository.RateLimitResult{}, errors.New("store unavailable")
+}
+
+func (failingRateLimitStore) DeleteRefilled(context.Context, time.Time) (int64, error) {
+	return 0, errors.New("store unavailable")
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RateLimit(repository.NewInMemoryRateLimitRepository(),
+		repository.RateLimit{Limit: 2, Period: time.Minute},
+		repository.RateLimit{Limit: 1, Period: time.Minute},
+	))
+	router.GET("/posts", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/posts", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	serve := func(method, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/posts", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "192.0.2.1")
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("unexpected response: %d %v", rec.Code, rec.Header())
+	}
+	serve(http.MethodGet, "192.0.2.1")
+	rec = serve(http.MethodGet, "192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "30" || rec.Header().Get("X-RateLimit-Remaining") != "0" || rec.Header().Get("X-RateLimit-Reset") != "60" {
+		t.Fatalf("unexpected rate limit headers: %v", rec.Header())
+	}
+
+	// Writes are counted in a bucket separate from reads.
+	if rec := serve(http.MethodPost, "192.0.2.1"); rec.Code != http.StatusCreated || rec.Header().Get("X-RateLimit-Limit") != "1" {
+		t.Fatalf("expected write to use its own limit, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := serve(http.MethodPost, "192.0.2.1"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected second write to be limited, got %d", rec.Code)
+	}
+	if rec := serve(http.MethodGet, "192.0.2.2"); rec.Code != http.StatusOK {
+		t.Fatalf("expected other clients to be unaffected, got %d", rec.Code)
+	}
+}
+
+func TestRateLimit_StoreFailureAllowsRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	limit := repository.RateLimit{Limit: 1, Period: time.Minute}
+	router.Use(RateLimit(failingRateLimitStore{}, limit, limit))
+	router.GET("/posts", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/logger"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var seen string
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) {
+		seen = logger.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			req.Header.Set(RequestIDHeader, id)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("abc-123")
+	if rec.Header().Get(RequestIDHeader) != "abc-123" || seen != "abc-123" {
+		t.Fatalf("expected the client ID to be reused, got header %q and context %q", rec.Header().Get(RequestIDHeader), seen)
+	}
+
+	for _, id := range []string{"", "has space", "line\nbreak", strings.Repeat("x", maxRequestIDLength+1)} {
+		rec := serve(id)
+		got := rec.Header().Get(RequestIDHeader)
+		if got == "" || got == id || got != seen {
+			t.Fatalf("expected a generated ID for %q, got header %q and context %q", id, got, seen)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Package problem writes error responses as RFC 7807 problem details
+// (application/problem+json).
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/logger"
+)
+
+// ContentType is the media type of problem details responses.
+const ContentType = "application/problem+json"
+
+// CodeValidationFailed is the code of problems describing invalid request
+// fields, each listed in Details.Errors.
+const CodeValidationFailed = "validation_failed"
+
+// FieldError describes one invalid field of a request. Field is the JSON
+// name of a body field or the name of a path or query parameter.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Details is a problem details object. Type is always "about:blank", so
+// Title is the standard text of Status; clients should branch on Code,
+// which is stable, rather than on Detail, which is meant for humans.
+type Details struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+	// Extensions are additional members serialised alongside the standard
+	// ones. They cannot override them.
+	Extensions map[string]any `json:"-"`
+}
+
+// New returns the problem for status with the given code and detail.
+func New(status int, code, detail string) Details {
+	return Details{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Validation returns a 400 problem listing the invalid fields.
+func Validation(detail string, errs ...FieldError) Details {
+	d := New(http.StatusBadRequest, CodeValidationFailed, detail)
+	d.Errors = errs
+	return d
+}
+
+// With returns a copy of d carrying the extension member name.
+func (d Details) With(name string, value any) Details {
+	ext := make(map[string]any, len(d.Extensions)+1)
+	for k, v := range d.Extensions {
+		ext[k] = v
+	}
+	ext[name] = value
+	d.Extensions = ext
+	return d
+}
+
+func (d Details) MarshalJSON() ([]byte, error) {
+	type details Details
+	raw, err := json.Marshal(details(d))
+	if err != nil || len(d.Extensions) == 0 {
+		return raw, err
+	}
+
+	members := make(map[string]any, len(d.Extensions))
+	for k, v := range d.Extensions {
+		members[k] = v
+	}
+	var standard map[string]any
+	if err := json.Unmarshal(raw, &standard); err != nil {
+		return nil, err
+	}
+	for k, v := range standard {
+		members[k] = v
+	}
+	return json.Marshal(members)
+}
+
+// Write responds with d. Instance defaults to the request path, and the
+// request ID, when there is one, is added so the response can be matched
+// with the server logs.
+func Write(c *gin.Context, d Details) {
+	if d.Instance == "" {
+		d.Instance = c.Request.URL.Path
+	}
+	if id := logger.RequestID(c.Request.Context()); id != "" {
+		d = d.With("request_id", id)
+	}
+	c.Header("Content-Type", ContentType)
+	c.JSON(d.Status, d)
+}
+
+// Abort responds with d and stops the remaining handlers.
+func Abort(c *gin.Context, d Details) {
+	Write(c, d)
+	c.Abort()
+}
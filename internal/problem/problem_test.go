@@ -0,0 +1,69 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kitakitabauer/gin-sample-app/logger"
+)
+
+func TestDetails_MarshalJSON(t *testing.T) {
+	d := Validation("title is required", FieldError{Field: "title", Code: "required", Message: "title is required"}).
+		With("index", 2).
+		With("status", "ignored")
+
+	raw, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal returned error: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+
+	if got["type"] != "about:blank" || got["title"] != "Bad Request" || got["code"] != CodeValidationFailed {
+		t.Fatalf("unexpected standard members: %s", raw)
+	}
+	if got["status"] != float64(http.StatusBadRequest) {
+		t.Fatalf("expected an extension not to override status, got %s", raw)
+	}
+	if got["index"] != float64(2) {
+		t.Fatalf("expected the index extension, got %s", raw)
+	}
+	if _, ok := got["instance"]; ok {
+		t.Fatalf("expected an empty instance to be omitted, got %s", raw)
+	}
+	if d.Extensions["status"] != "ignored" || len(New(http.StatusNotFound, "x", "").Extensions) != 0 {
+		t.Fatal("expected With to copy the extensions")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/posts/:id", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(logger.WithRequestID(c.Request.Context(), "req-1"))
+		Abort(c, New(http.StatusNotFound, "post_not_found", "post not found"))
+	}, func(c *gin.Context) {
+		t.Fatal("expected Abort to stop the chain")
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/posts/1?x=y", nil))
+
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("unexpected response: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var got map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if got["instance"] != "/posts/1" || got["request_id"] != "req-1" || got["detail"] != "post not found" {
+		t.Fatalf("unexpected body: %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,57 @@
+// Package scheduler runs background jobs that live alongside the HTTP server.
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/logger"
+	"go.uber.org/zap"
+)
+
+// DuePublisher publishes scheduled posts whose publish time is not after now.
+// service.PostService satisfies it.
+type DuePublisher interface {
+	PublishDue(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Publisher periodically flips scheduled posts to published.
+type Publisher struct {
+	posts    DuePublisher
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewPublisher(posts DuePublisher, interval time.Duration) *Publisher {
+	return &Publisher{posts: posts, interval: interval, now: time.Now}
+}
+
+// Run publishes due posts once immediately and then on every tick until ctx
+// is cancelled. It blocks, so callers start it in its own goroutine.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.publishDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Publisher) publishDue(ctx context.Context) {
+	published, err := p.posts.PublishDue(ctx, p.now())
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Log.Error("failed to publish scheduled posts", zap.Error(err))
+		}
+		return
+	}
+	if published > 0 {
+		logger.Log.Info("published scheduled posts", zap.Int64("count", published))
+	}
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/logger"
+)
+
+type countingPublisher struct {
+	calls atomic.Int64
+}
+
+func (p *countingPublisher) PublishDue(context.Context, time.Time) (int64, error) {
+	p.calls.Add(1)
+	return 1, nil
+}
+
+func TestPublisher_RunUntilCancelled(t *testing.T) {
+	if err := logger.Init(logger.Config{Env: "dev", Level: "error"}); err != nil {
+		t.Fatalf("failed to init logger: %v", err)
+	}
+	t.Cleanup(logger.Sync)
+
+	posts := &countingPublisher{}
+	publisher := NewPublisher(posts, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		publisher.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for posts.calls.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected repeated PublishDue calls, got %d", posts.calls.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
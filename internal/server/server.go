@@ -1,45 +1,188 @@
 package server
 
 import (
+	"crypto/rand"
 	"database/sql"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kitakitabauer/gin-sample-app/config"
 	"github.com/kitakitabauer/gin-sample-app/handler"
+	"github.com/kitakitabauer/gin-sample-app/internal/metrics"
 	"github.com/kitakitabauer/gin-sample-app/internal/middleware"
+	"github.com/kitakitabauer/gin-sample-app/internal/problem"
 	"github.com/kitakitabauer/gin-sample-app/logger"
+	"github.com/kitakitabauer/gin-sample-app/model"
 	"github.com/kitakitabauer/gin-sample-app/repository"
 	"github.com/kitakitabauer/gin-sample-app/service"
 )
 
-func New(db *sql.DB) (*gin.Engine, error) {
+// NewPostService builds the post service shared by the router and the
+// scheduled publisher, so both run with the same transactions, validation
+// rules and repository instrumentation.
+func NewPostService(db *sql.DB, m *metrics.Metrics) *service.PostService {
+	postService := service.NewPostService(
+		postRepository(db, m),
+		repository.NewSQLRevisionRepository(db, config.AppConfig.DatabaseDriver),
+		repository.NewSQLAuthorRepository(db, config.AppConfig.DatabaseDriver),
+	)
+	postService.SetTxManager(repository.NewSQLTxManager(db))
+	postRules := service.DefaultPostRules()
+	postRules.Title.MaxLength = config.AppConfig.PostTitleMaxLength
+	postRules.Content.MaxLength = config.AppConfig.PostContentMaxLength
+	postService.SetRules(postRules)
+	return postService
+}
+
+// postRepository returns the SQL post repository, recording its operations
+// when m is non-nil.
+func postRepository(db *sql.DB, m *metrics.Metrics) repository.PostRepository {
+	var posts repository.PostRepository = repository.NewSQLPostRepository(db, config.AppConfig.DatabaseDriver)
+	if m != nil {
+		posts = repository.NewInstrumentedPostRepository(posts, m.RepositoryObserver("posts"))
+	}
+	return posts
+}
+
+// New builds the application router around postService (see NewPostService).
+// When m is non-nil requests and post repository operations are recorded,
+// and /metrics is served on the router unless METRICS_ADDR moves it to a
+// separate listener.
+func New(db *sql.DB, m *metrics.Metrics, postService *service.PostService) (*gin.Engine, error) {
 	if db == nil {
 		return nil, fmt.Errorf("db is nil")
 	}
+	if postService == nil {
+		return nil, fmt.Errorf("post service is nil")
+	}
 	if logger.Log == nil {
 		return nil, fmt.Errorf("logger is not initialised")
 	}
 
+	secret, err := jwtSecret()
+	if err != nil {
+		return nil, err
+	}
+	userRepository := repository.NewSQLUserRepository(db, config.AppConfig.DatabaseDriver)
+	authService := service.NewAuthService(userRepository, service.AuthConfig{
+		Secret:     secret,
+		AccessTTL:  config.AppConfig.AccessTokenTTL,
+		RefreshTTL: config.AppConfig.RefreshTokenTTL,
+	})
+
+	rateLimits, err := rateLimitStore(db)
+	if err != nil {
+		return nil, err
+	}
+
 	r := gin.New()
-	r.Use(gin.Recovery())
+	// gin trusts every proxy by default, which would let any client pick
+	// its own IP, and with it its rate limit bucket, via X-Forwarded-For.
+	if err := r.SetTrustedProxies(config.AppConfig.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	// Metrics wraps recovery so that a panic is observed as the 500 the
+	// recovery handler writes.
+	if m != nil {
+		r.Use(middleware.Metrics(m))
+	}
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		problem.Abort(c, problem.New(http.StatusInternalServerError, "internal_error", "internal server error"))
+	}))
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Tracing())
 	r.Use(middleware.GinZap())
 
-	postRepository := repository.NewSQLPostRepository(db, config.AppConfig.DatabaseDriver)
-	postService := service.NewPostService(postRepository)
+	// Registered before authentication and rate limiting so that health
+	// probes are never throttled.
+	r.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	r.Use(middleware.BodyLimit(int64(config.AppConfig.MaxBodyBytes)))
+	r.Use(middleware.Authenticate(authService))
+
+	apiKeyService := service.NewAPIKeyService(repository.NewSQLAPIKeyRepository(db, config.AppConfig.DatabaseDriver))
+	if config.AppConfig.APIKeyEncryptionKey != "" {
+		apiKeyService.SetEncryptionKey([]byte(config.AppConfig.APIKeyEncryptionKey))
+	} else {
+		logger.Log.Warn("API_KEY_ENCRYPTION_KEY is not set; using a random key, HMAC signing will not survive a restart")
+	}
+	r.Use(middleware.AuthenticateAPIKey(apiKeyService))
+	r.Use(middleware.AuthenticateHMAC(apiKeyService, repository.NewSQLNonceRepository(db, config.AppConfig.DatabaseDriver), config.AppConfig.HMACClockSkew))
+	r.Use(middleware.RateLimit(rateLimits,
+		repository.RateLimit{Limit: config.AppConfig.RateLimitRead, Period: time.Minute},
+		repository.RateLimit{Limit: config.AppConfig.RateLimitWrite, Period: time.Minute},
+	))
+	r.Use(middleware.Idempotency(repository.NewSQLIdempotencyRepository(db, config.AppConfig.DatabaseDriver), config.AppConfig.IdempotencyTTL))
+
+	if m != nil && config.AppConfig.MetricsAddr == "" {
+		r.GET("/metrics",
+			middleware.RequireAuth(model.APIKeyScopeAdminMetrics),
+			middleware.RequireAdmin(),
+			gin.WrapH(m.Handler()),
+		)
+	}
+
+	authHandler := handler.NewAuthHandler(authService)
+	authHandler.RegisterRoutes(r)
+
 	postHandler := handler.NewPostHandler(postService)
 	postHandler.RegisterRoutes(r)
 
-	adminHandler := handler.NewAdminHandler()
+	commentRepository := repository.NewSQLCommentRepository(db, config.AppConfig.DatabaseDriver)
+	commentService := service.NewCommentService(commentRepository, postRepository(db, m))
+	commentHandler := handler.NewCommentHandler(commentService)
+	commentHandler.RegisterRoutes(r)
+
+	authorService := service.NewAuthorService(repository.NewSQLAuthorRepository(db, config.AppConfig.DatabaseDriver))
+	authorHandler := handler.NewAuthorHandler(authorService, postService)
+	authorHandler.RegisterRoutes(r)
+
+	tagHandler := handler.NewTagHandler(postService)
+	tagHandler.RegisterRoutes(r)
+
+	adminHandler := handler.NewAdminHandler(postService)
 	adminHandler.RegisterRoutes(r)
 
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	apiKeyHandler.RegisterRoutes(r)
+
 	docsHandler := handler.NewDocsHandler()
 	docsHandler.RegisterRoutes(r)
 
-	r.GET("/healthz", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	r.NoRoute(func(c *gin.Context) {
+		problem.Write(c, problem.New(http.StatusNotFound, "not_found", "no route matches the request"))
 	})
 
 	return r, nil
 }
+
+// rateLimitStore returns the rate limit store selected by RATE_LIMIT_STORE.
+func rateLimitStore(db *sql.DB) (repository.RateLimitRepository, error) {
+	switch config.AppConfig.RateLimitStore {
+	case "", "memory":
+		return repository.NewInMemoryRateLimitRepository(), nil
+	case "sql":
+		return repository.NewSQLRateLimitRepository(db, config.AppConfig.DatabaseDriver), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", config.AppConfig.RateLimitStore)
+	}
+}
+
+// jwtSecret returns the configured JWT signing secret, or a random one when
+// JWT_SECRET is unset. Tokens signed with a random secret are invalidated by
+// a restart, which is acceptable for local development only.
+func jwtSecret() ([]byte, error) {
+	if config.AppConfig.JWTSecret != "" {
+		return []byte(config.AppConfig.JWTSecret), nil
+	}
+	logger.Log.Warn("JWT_SECRET is not set; using a random secret, tokens will not survive a restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate jwt secret: %w", err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestInit_StdoutToFile(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "spans.json")
+	shutdown, err := Init(context.Background(), Config{
+		Exporter:    ExporterStdout,
+		Output:      output,
+		SampleRatio: 1,
+		Service:     "gin-sample-app",
+		Env:         "test",
+	})
+	if err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("ReadFile returned error: %v", err)
+	}
+	for _, want := range []string{`"Name":"test-span"`, `"Value":"gin-sample-app"`} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("expected %s in exported spans, got %s", want, data)
+		}
+	}
+}
+
+func TestInit_UnknownExporter(t *testing.T) {
+	if _, err := Init(context.Background(), Config{Exporter: "jaeger"}); err == nil {
+		t.Fatalf("expected an error for an unknown exporter")
+	}
+}
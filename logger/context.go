@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the request ID, which
+// FromContext attaches to every log line.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "" when there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns a child of Log carrying the request ID and the
+// trace and span IDs found in ctx, so that every line logged while serving
+// a request can be correlated. It returns a no-op logger when Log has not
+// been initialised.
+func FromContext(ctx context.Context) *zap.Logger {
+	if Log == nil {
+		return zap.NewNop()
+	}
+
+	fields := TraceFields(ctx)
+	if id := RequestID(ctx); id != "" {
+		fields = append(fields, zap.String("request_id", id))
+	}
+	if len(fields) == 0 {
+		return Log
+	}
+	return Log.With(fields...)
+}
@@ -1,9 +1,13 @@
 package logger
 
 import (
+	"context"
 	"testing"
 
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func resetLogger() {
@@ -68,3 +72,46 @@ func TestSetLevel(t *testing.T) {
 		t.Fatalf("expected error level, got %s", level)
 	}
 }
+
+func TestTraceFields(t *testing.T) {
+	if fields := TraceFields(context.Background()); fields != nil {
+		t.Fatalf("expected no fields without a span, got %v", fields)
+	}
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:  trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	})
+	fields := TraceFields(trace.ContextWithSpanContext(context.Background(), sc))
+	if len(fields) != 2 ||
+		fields[0].String != "4bf92f3577b34da6a3ce929d0e0e4736" ||
+		fields[1].String != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected trace fields: %+v", fields)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	defer resetLogger()
+
+	if FromContext(context.Background()) == nil {
+		t.Fatalf("expected a no-op logger before Init")
+	}
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	Log = zap.New(core)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	FromContext(ctx).Info("handled")
+	FromContext(context.Background()).Info("background")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if got := entries[0].ContextMap()["request_id"]; got != "req-1" {
+		t.Fatalf("expected request_id req-1, got %v", got)
+	}
+	if _, ok := entries[1].ContextMap()["request_id"]; ok {
+		t.Fatalf("expected no request_id without one in the context")
+	}
+}
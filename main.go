@@ -7,12 +7,16 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/kitakitabauer/gin-sample-app/config"
 	"github.com/kitakitabauer/gin-sample-app/internal/database"
+	"github.com/kitakitabauer/gin-sample-app/internal/metrics"
+	"github.com/kitakitabauer/gin-sample-app/internal/scheduler"
 	"github.com/kitakitabauer/gin-sample-app/internal/server"
+	"github.com/kitakitabauer/gin-sample-app/internal/tracing"
 	"github.com/kitakitabauer/gin-sample-app/logger"
 	"go.uber.org/zap"
 )
@@ -28,6 +32,27 @@ func main() {
 		log.Fatalf("failed to init logger: %v", err)
 	}
 	defer logger.Sync()
+
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		Exporter:    config.AppConfig.TracingExporter,
+		Endpoint:    config.AppConfig.TracingEndpoint,
+		Insecure:    config.AppConfig.TracingInsecure,
+		Output:      config.AppConfig.TracingOutput,
+		SampleRatio: config.AppConfig.TracingSampleRatio,
+		Service:     "gin-sample-app",
+		Env:         config.AppConfig.Env,
+	})
+	if err != nil {
+		logger.Log.Fatal("failed to init tracing", zap.Error(err))
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Log.Error("failed to flush traces", zap.Error(err))
+		}
+	}()
+
 	dbCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	db, err := database.Open(dbCtx, database.Config{
 		Driver: config.AppConfig.DatabaseDriver,
@@ -43,10 +68,36 @@ func main() {
 		logger.Log.Fatal("failed to apply migrations", zap.Error(err))
 	}
 
-	r, err := server.New(db)
+	var m *metrics.Metrics
+	if config.AppConfig.MetricsEnabled {
+		m = metrics.New(db)
+		version, dirty, err := database.MigrationVersion(db, config.AppConfig.DatabaseDriver)
+		if err != nil {
+			logger.Log.Warn("failed to read migration version", zap.Error(err))
+		} else {
+			m.SetMigrationVersion(version, dirty)
+		}
+	}
+
+	postService := server.NewPostService(db, m)
+	r, err := server.New(db, m, postService)
 	if err != nil {
 		logger.Log.Fatal("failed to create server", zap.Error(err))
 	}
+	if config.AppConfig.AuthDisabled {
+		logger.Log.Warn("authentication is disabled; anonymous callers can modify posts")
+	}
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	if interval := config.AppConfig.PublishInterval; interval > 0 {
+		publisher := scheduler.NewPublisher(postService, interval)
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			publisher.Run(jobsCtx)
+		}()
+	}
 
 	srv := &http.Server{
 		Addr:    fmt.Sprintf(":%s", config.AppConfig.Port),
@@ -63,6 +114,22 @@ func main() {
 		}
 	}()
 
+	var metricsSrv *http.Server
+	if m != nil && config.AppConfig.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", m.Handler())
+		metricsSrv = &http.Server{
+			Addr:    config.AppConfig.MetricsAddr,
+			Handler: mux,
+		}
+		go func() {
+			logger.Log.Info("starting metrics server", zap.String("addr", metricsSrv.Addr))
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Log.Fatal("metrics server error", zap.Error(err))
+			}
+		}()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -72,6 +139,14 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Log.Fatal("server forced to shutdown", zap.Error(err))
 	}
+	if metricsSrv != nil {
+		if err := metricsSrv.Shutdown(ctx); err != nil {
+			logger.Log.Error("metrics server forced to shutdown", zap.Error(err))
+		}
+	}
+
+	stopJobs()
+	jobs.Wait()
 
 	logger.Log.Info("server exited gracefully")
 }
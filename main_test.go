@@ -10,6 +10,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/kitakitabauer/gin-sample-app/config"
 	"github.com/kitakitabauer/gin-sample-app/handler"
 	"github.com/kitakitabauer/gin-sample-app/model"
 	"github.com/kitakitabauer/gin-sample-app/repository"
@@ -17,8 +18,10 @@ import (
 )
 
 func setupTestRouter() *gin.Engine {
+	config.AppConfig = &config.Config{AuthDisabled: true}
+
 	repo := repository.NewInMemoryPostRepository()
-	postService := service.NewPostService(repo)
+	postService := service.NewPostService(repo, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(repo))
 	postHandler := handler.NewPostHandler(postService)
 
 	router := gin.Default()
@@ -0,0 +1,67 @@
+package model
+
+import "time"
+
+// APIKeyはX-API-Keyヘッダーで利用するAPIキーです。キー本体は作成時にのみ返し、
+// 保存するのはSHA-256のハッシュ(KeyHash)と表示用の先頭部分(Prefix)、暗号化したHMAC署名の鍵(SigningKey)だけです。
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	SigningKey string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyのスコープです。APIキーはスコープで許可されたAPIのみを呼び出せます。
+const (
+	// APIKeyScopePostsWriteは記事・著者の作成・更新・削除とコメントの削除を許可します。
+	APIKeyScopePostsWrite = "posts:write"
+	// APIKeyScopeAdminLogLevelは/admin/log-levelを許可します。
+	APIKeyScopeAdminLogLevel = "admin:log-level"
+	// APIKeyScopeAdminPostsはゴミ箱の記事の参照・復元・完全削除を許可します。
+	APIKeyScopeAdminPosts = "admin:posts"
+	// APIKeyScopeAdminAPIKeysはAPIキーの作成・一覧・失効を許可します。
+	APIKeyScopeAdminAPIKeys = "admin:api-keys"
+	// APIKeyScopeAdminMetricsは/metricsを許可します。
+	APIKeyScopeAdminMetrics = "admin:metrics"
+)
+
+// ValidAPIKeyScopeはscopeが定義済みのスコープかどうかを返します。
+func ValidAPIKeyScope(scope string) bool {
+	switch scope {
+	case APIKeyScopePostsWrite, APIKeyScopeAdminLogLevel, APIKeyScopeAdminPosts, APIKeyScopeAdminAPIKeys, APIKeyScopeAdminMetrics:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasScopesはAPIキーがscopesをすべて持つかを返します。
+func (k APIKey) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		found := false
+		for _, granted := range k.Scopes {
+			if granted == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ActiveはAPIキーが失効しておらず、期限切れでもない場合にtrueを返します。
+func (k APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
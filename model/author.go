@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Authorは記事の著者です。名前は大文字・小文字を区別せずに一意です。
+type Author struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
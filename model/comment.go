@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// CommentはPostに対する読者のコメントです。
+// ParentIDが設定されたコメントは返信で、返信は1階層まで(トップレベルのコメントに対してのみ)作成できます。
+type Comment struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	ParentID  *int64    `json:"parent_id,omitempty"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	// Repliesはトップレベルのコメントへの返信を古い順に最大repository.ReplyPreviewLimit件保持します。返信自身では常に空です。
+	Replies []Comment `json:"replies,omitempty"`
+	// ReplyCountはトップレベルのコメントへの返信の総数です。
+	ReplyCount int `json:"reply_count,omitempty"`
+	// RepliesNextCursorはRepliesに含まれない返信がある場合に、続きを取得するためのカーソルです。
+	RepliesNextCursor string `json:"replies_next_cursor,omitempty"`
+}
@@ -4,9 +4,52 @@ import "time"
 
 // Postはブログ記事の共通データモデルです。
 type Post struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Author    string    `json:"author"`
-	CreatedAt time.Time `json:"created_at"`
+	ID      int64  `json:"id"`
+	Title   string `json:"title"`
+	Slug    string `json:"slug"`
+	Content string `json:"content"`
+	Author  string `json:"author"`
+	// AuthorIDは著者(Author)のIDです。Authorには互換性のため著者名を保持します。
+	AuthorID int64 `json:"author_id,omitempty"`
+	// OwnerIDは記事を作成したUserのIDです。APIキーで作成した記事では0になります。
+	OwnerID   int64      `json:"owner_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UpdatedBy string     `json:"updated_by"`
+	Version   int64      `json:"version"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	Tags      []string   `json:"tags"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Postの公開状態です。publishedのPostのみが未認証の利用者に公開されます。
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
+// ValidPostStatusはstatusが定義済みの公開状態かどうかを返します。
+func ValidPostStatus(status string) bool {
+	switch status {
+	case PostStatusDraft, PostStatusScheduled, PostStatusPublished, PostStatusArchived:
+		return true
+	default:
+		return false
+	}
+}
+
+// PostSearchHitは全文検索でヒットしたPostと関連度・ハイライトです。
+type PostSearchHit struct {
+	Post      Post          `json:"post"`
+	Score     float64       `json:"score"`
+	Highlight PostHighlight `json:"highlight"`
+}
+
+// PostHighlightは検索語を<mark>タグで囲んだタイトルと本文の抜粋です。<mark>以外の文字はHTMLエスケープ済みです。
+type PostHighlight struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
 }
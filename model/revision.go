@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// PostRevisionは更新前のPostの内容を記録した不変の履歴です。
+// Revisionは記録時点のPost.Versionで、Postごとに一意です。
+type PostRevision struct {
+	ID       int64  `json:"id"`
+	PostID   int64  `json:"post_id"`
+	Revision int64  `json:"revision"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	Author   string `json:"author"`
+	// AuthorIDは記録時点の著者(Author)のIDです。著者が削除された場合は0になります。
+	AuthorID  int64     `json:"author_id,omitempty"`
+	Actor     string    `json:"actor"`
+	CreatedAt time.Time `json:"created_at"`
+}
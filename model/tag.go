@@ -0,0 +1,7 @@
+package model
+
+// Tagは記事に付与するタグと、そのタグが付いた記事の件数です。
+type Tag struct {
+	Name      string `json:"name"`
+	PostCount int64  `json:"post_count"`
+}
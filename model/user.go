@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// UserはAPIにログインする利用者です。ユーザー名は大文字・小文字を区別せずに一意です。
+// PasswordHashはbcryptでハッシュ化したパスワードで、JSONには出力しません。
+type User struct {
+	ID           int64     `json:"id"`
+	Username     string    `json:"username"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Userの役割です。adminとeditorはすべての記事を変更でき、authorは自分が作成した記事のみを変更できます。
+// 管理用API(/admin)はadminのみが利用できます。
+const (
+	UserRoleAdmin  = "admin"
+	UserRoleEditor = "editor"
+	UserRoleAuthor = "author"
+)
+
+// ValidUserRoleはroleが定義済みの役割かどうかを返します。
+func ValidUserRole(role string) bool {
+	switch role {
+	case UserRoleAdmin, UserRoleEditor, UserRoleAuthor:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,82 @@
+// Package policyは認証済みの呼び出し元がリソースを操作できるかを判定します。
+// ハンドラーはサービスを呼び出す前にここで権限を確認し、拒否された場合は403を返します。
+package policy
+
+import (
+	"context"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+// Errorは権限がないことを表すエラーです。Codeはクライアントが判別に使う固定の文字列です。
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrNotPostOwner  = &Error{Code: "not_post_owner", Message: "only the owner, an editor or an admin can modify this post"}
+	ErrAdminRequired = &Error{Code: "admin_required", Message: "admin role is required"}
+	// ErrInsufficientScopeはAPIキーにAPIの呼び出しに必要なスコープが無いことを表します。
+	ErrInsufficientScope = &Error{Code: "insufficient_scope", Message: "api key does not have the required scope"}
+)
+
+// Principalは操作を行う呼び出し元です。UserIDはAPIキーで認証された場合など、Userが無いときは0です。
+type Principal struct {
+	UserID int64
+	Role   string
+}
+
+// HasRoleはPrincipalがrolesのいずれかを持つかを返します。
+func (p Principal) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if p.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireAdminはPrincipalがadminでない場合にErrAdminRequiredを返します。
+func RequireAdmin(p Principal) error {
+	if !p.HasRole(model.UserRoleAdmin) {
+		return ErrAdminRequired
+	}
+	return nil
+}
+
+// PostFinderはPostPolicyが所有者を確認するためにPostを読み込むインターフェースです。
+type PostFinder interface {
+	Get(ctx context.Context, id int64) (model.Post, error)
+}
+
+// PostPolicyはPostの変更権限を判定します。adminとeditorはすべてのPostを、
+// authorは自分が作成したPostのみを変更できます。
+type PostPolicy struct {
+	posts PostFinder
+}
+
+func NewPostPolicy(posts PostFinder) *PostPolicy {
+	return &PostPolicy{posts: posts}
+}
+
+// CanModifyはPrincipalがidのPostを変更できる場合にnilを返します。
+// Postが存在しない場合は読み込み時のエラー(repository.ErrPostNotFoundなど)をそのまま返します。
+func (p *PostPolicy) CanModify(ctx context.Context, principal Principal, id int64) error {
+	if principal.HasRole(model.UserRoleAdmin, model.UserRoleEditor) {
+		return nil
+	}
+
+	post, err := p.posts.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if principal.UserID == 0 || post.OwnerID != principal.UserID {
+		return ErrNotPostOwner
+	}
+	return nil
+}
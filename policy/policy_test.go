@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+type postFinderFunc func(ctx context.Context, id int64) (model.Post, error)
+
+func (f postFinderFunc) Get(ctx context.Context, id int64) (model.Post, error) {
+	return f(ctx, id)
+}
+
+func TestPostPolicy_CanModify(t *testing.T) {
+	policy := NewPostPolicy(postFinderFunc(func(_ context.Context, id int64) (model.Post, error) {
+		switch id {
+		case 1:
+			return model.Post{ID: 1, OwnerID: 10}, nil
+		case 2:
+			return model.Post{ID: 2}, nil
+		default:
+			return model.Post{}, repository.ErrPostNotFound
+		}
+	}))
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		principal Principal
+		postID    int64
+		want      error
+	}{
+		{"admin", Principal{UserID: 20, Role: model.UserRoleAdmin}, 1, nil},
+		{"editor", Principal{UserID: 20, Role: model.UserRoleEditor}, 1, nil},
+		{"owner", Principal{UserID: 10, Role: model.UserRoleAuthor}, 1, nil},
+		{"other author", Principal{UserID: 20, Role: model.UserRoleAuthor}, 1, ErrNotPostOwner},
+		{"post without owner", Principal{UserID: 20, Role: model.UserRoleAuthor}, 2, ErrNotPostOwner},
+		{"missing post", Principal{UserID: 10, Role: model.UserRoleAuthor}, 3, repository.ErrPostNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := policy.CanModify(ctx, tt.principal, tt.postID); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	if err := RequireAdmin(Principal{Role: model.UserRoleAdmin}); err != nil {
+		t.Fatalf("expected admin to pass, got %v", err)
+	}
+	if err := RequireAdmin(Principal{Role: model.UserRoleEditor}); !errors.Is(err, ErrAdminRequired) {
+		t.Fatalf("expected ErrAdminRequired, got %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKeyRepositoryはAPIKeyの永続化を抽象化するインターフェースです。
+// キー本体は保存せず、KeyHashで検索します。
+type APIKeyRepository interface {
+	Create(ctx context.Context, key model.APIKey) (model.APIKey, error)
+	FindAll(ctx context.Context) ([]model.APIKey, error)
+	FindByID(ctx context.Context, id int64) (model.APIKey, error)
+	FindByHash(ctx context.Context, hash string) (model.APIKey, error)
+	// Revokeはキーを失効させます。失効済みのキーはそのまま返します。
+	Revoke(ctx context.Context, id int64, at time.Time) (model.APIKey, error)
+	// Touchは最終利用日時を記録します。
+	Touch(ctx context.Context, id int64, at time.Time) error
+}
+
+const apiKeyColumns = "id, name, prefix, key_hash, signing_key, scopes, expires_at, last_used_at, revoked_at, created_at"
+
+// スコープは空白区切りの1つのカラムに保存します。
+func joinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+func splitScopes(raw string) []string {
+	scopes := strings.Fields(raw)
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return scopes
+}
+
+func scanAPIKey(row rowScanner) (model.APIKey, error) {
+	var key model.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, &key.SigningKey, &scopes, &expiresAt, &lastUsedAt, &revokedAt, &key.CreatedAt); err != nil {
+		return model.APIKey{}, err
+	}
+	key.Scopes = splitScopes(scopes)
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return key, nil
+}
+
+// SQLAPIKeyRepositoryはRDBを利用したAPIKeyRepositoryの実装です。
+type SQLAPIKeyRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+func NewSQLAPIKeyRepository(db *sql.DB, driver string) *SQLAPIKeyRepository {
+	return &SQLAPIKeyRepository{
+		db:      db,
+		dialect: detectDialect(driver),
+	}
+}
+
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLAPIKeyRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+func (r *SQLAPIKeyRepository) Create(ctx context.Context, key model.APIKey) (model.APIKey, error) {
+	args := []any{key.Name, key.Prefix, key.KeyHash, key.SigningKey, joinScopes(key.Scopes), key.ExpiresAt, key.CreatedAt}
+	switch r.dialect {
+	case "postgres":
+		query := `INSERT INTO api_keys (name, prefix, key_hash, signing_key, scopes, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+		if err := r.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&key.ID); err != nil {
+			return model.APIKey{}, err
+		}
+		return key, nil
+	default:
+		res, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO api_keys (name, prefix, key_hash, signing_key, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`, args...)
+		if err != nil {
+			return model.APIKey{}, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return model.APIKey{}, err
+		}
+		key.ID = id
+		return key, nil
+	}
+}
+
+func (r *SQLAPIKeyRepository) FindAll(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]model.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *SQLAPIKeyRepository) FindByID(ctx context.Context, id int64) (model.APIKey, error) {
+	query := fmt.Sprintf(`SELECT %s FROM api_keys WHERE id = %s`, apiKeyColumns, r.placeholder(1))
+	return r.findOne(ctx, query, id)
+}
+
+func (r *SQLAPIKeyRepository) FindByHash(ctx context.Context, hash string) (model.APIKey, error) {
+	query := fmt.Sprintf(`SELECT %s FROM api_keys WHERE key_hash = %s`, apiKeyColumns, r.placeholder(1))
+	return r.findOne(ctx, query, hash)
+}
+
+func (r *SQLAPIKeyRepository) findOne(ctx context.Context, query string, args ...any) (model.APIKey, error) {
+	key, err := scanAPIKey(r.conn(ctx).QueryRowContext(ctx, query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.APIKey{}, ErrAPIKeyNotFound
+		}
+		return model.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (r *SQLAPIKeyRepository) Revoke(ctx context.Context, id int64, at time.Time) (model.APIKey, error) {
+	query := fmt.Sprintf("UPDATE api_keys SET revoked_at = COALESCE(revoked_at, %s) WHERE id = %s", r.placeholder(1), r.placeholder(2))
+	res, err := r.conn(ctx).ExecContext(ctx, query, at, id)
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	if rowsAffected == 0 {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	return r.FindByID(ctx, id)
+}
+
+func (r *SQLAPIKeyRepository) Touch(ctx context.Context, id int64, at time.Time) error {
+	query := fmt.Sprintf("UPDATE api_keys SET last_used_at = %s WHERE id = %s", r.placeholder(1), r.placeholder(2))
+	_, err := r.conn(ctx).ExecContext(ctx, query, at, id)
+	return err
+}
+
+func (r *SQLAPIKeyRepository) placeholder(idx int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", idx)
+	}
+	return "?"
+}
+
+// InMemoryAPIKeyRepositoryはAPIKeyRepositoryのメモリ上の実装です。
+type InMemoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[int64]model.APIKey
+	nextID int64
+}
+
+func NewInMemoryAPIKeyRepository() *InMemoryAPIKeyRepository {
+	return &InMemoryAPIKeyRepository{
+		keys: make(map[int64]model.APIKey),
+	}
+}
+
+// cloneAPIKeyは呼び出し元と保存済みの値がスライスを共有しないようにコピーします。
+func cloneAPIKey(key model.APIKey) model.APIKey {
+	key.Scopes = append([]string{}, key.Scopes...)
+	return key
+}
+
+func (r *InMemoryAPIKeyRepository) Create(_ context.Context, key model.APIKey) (model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	key.ID = r.nextID
+	r.keys[key.ID] = cloneAPIKey(key)
+	return cloneAPIKey(key), nil
+}
+
+func (r *InMemoryAPIKeyRepository) FindAll(_ context.Context) ([]model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]model.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		keys = append(keys, cloneAPIKey(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (r *InMemoryAPIKeyRepository) FindByID(_ context.Context, id int64) (model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	return cloneAPIKey(key), nil
+}
+
+func (r *InMemoryAPIKeyRepository) FindByHash(_ context.Context, hash string) (model.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == hash {
+			return cloneAPIKey(key), nil
+		}
+	}
+	return model.APIKey{}, ErrAPIKeyNotFound
+}
+
+func (r *InMemoryAPIKeyRepository) Revoke(_ context.Context, id int64, at time.Time) (model.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return model.APIKey{}, ErrAPIKeyNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+		r.keys[id] = key
+	}
+	return cloneAPIKey(key), nil
+}
+
+func (r *InMemoryAPIKeyRepository) Touch(_ context.Context, id int64, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	key.LastUsedAt = &at
+	r.keys[id] = key
+	return nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+func TestSQLAPIKeyRepository_Lifecycle(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	keys := NewSQLAPIKeyRepository(posts.db, "sqlite")
+
+	ctx := context.Background()
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	expires := now.Add(24 * time.Hour)
+	created, err := keys.Create(ctx, model.APIKey{
+		Name:      "ci",
+		Prefix:    "gsk_abcd",
+		KeyHash:   "hash",
+		Scopes:    []string{model.APIKeyScopePostsWrite, model.APIKeyScopeAdminLogLevel},
+		ExpiresAt: &expires,
+		CreatedAt: now,
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	found, err := keys.FindByHash(ctx, "hash")
+	if err != nil {
+		t.Fatalf("FindByHash returned error: %v", err)
+	}
+	if found.ID != created.ID || !reflect.DeepEqual(found.Scopes, created.Scopes) || found.ExpiresAt == nil || !found.ExpiresAt.Equal(expires) {
+		t.Fatalf("unexpected key: %+v", found)
+	}
+	if _, err := keys.FindByHash(ctx, "other"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	if err := keys.Touch(ctx, created.ID, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Touch returned error: %v", err)
+	}
+	revoked, err := keys.Revoke(ctx, created.ID, now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Revoke returned error: %v", err)
+	}
+	if revoked.LastUsedAt == nil || !revoked.LastUsedAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected last_used_at to be recorded, got %v", revoked.LastUsedAt)
+	}
+	if revoked.RevokedAt == nil || !revoked.RevokedAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected revoked_at to be recorded, got %v", revoked.RevokedAt)
+	}
+	// 失効済みのキーを再度失効させても失効日時は変わりません。
+	if again, err := keys.Revoke(ctx, created.ID, now.Add(2*time.Hour)); err != nil || !again.RevokedAt.Equal(*revoked.RevokedAt) {
+		t.Fatalf("expected revoke to keep the first timestamp, got %+v (err=%v)", again, err)
+	}
+	if _, err := keys.Revoke(ctx, created.ID+1, now); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Fatalf("expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	all, err := keys.FindAll(ctx)
+	if err != nil || len(all) != 1 {
+		t.Fatalf("expected 1 key, got %d (err=%v)", len(all), err)
+	}
+}
@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+var (
+	ErrAuthorNotFound  = errors.New("author not found")
+	ErrAuthorNameTaken = errors.New("author name is already in use")
+	ErrAuthorHasPosts  = errors.New("author still has posts")
+)
+
+// AuthorRepositoryはAuthorの永続化を抽象化するインターフェースです。
+// 著者名はASCIIの英字に限って大文字・小文字を区別せずに比較します(equalAuthorNames)。
+type AuthorRepository interface {
+	Create(ctx context.Context, author model.Author) (model.Author, error)
+	FindAll(ctx context.Context) ([]model.Author, error)
+	FindByID(ctx context.Context, id int64) (model.Author, error)
+	FindByName(ctx context.Context, name string) (model.Author, error)
+	Rename(ctx context.Context, id int64, name string, updatedAt time.Time) (model.Author, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+const authorColumns = "id, name, created_at, updated_at"
+
+// SQLAuthorRepositoryはRDBを利用したAuthorRepositoryの実装です。
+type SQLAuthorRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+func NewSQLAuthorRepository(db *sql.DB, driver string) *SQLAuthorRepository {
+	return &SQLAuthorRepository{
+		db:      db,
+		dialect: detectDialect(driver),
+	}
+}
+
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLAuthorRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+func (r *SQLAuthorRepository) Create(ctx context.Context, author model.Author) (model.Author, error) {
+	args := []any{author.Name, author.CreatedAt, author.UpdatedAt}
+	switch r.dialect {
+	case "postgres":
+		query := `INSERT INTO authors (name, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id`
+		if err := r.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&author.ID); err != nil {
+			return model.Author{}, authorNameError(err)
+		}
+		return author, nil
+	default:
+		res, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO authors (name, created_at, updated_at) VALUES (?, ?, ?)`, args...)
+		if err != nil {
+			return model.Author{}, authorNameError(err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return model.Author{}, err
+		}
+		author.ID = id
+		return author, nil
+	}
+}
+
+func (r *SQLAuthorRepository) FindAll(ctx context.Context) ([]model.Author, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT `+authorColumns+` FROM authors ORDER BY name, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	authors := make([]model.Author, 0)
+	for rows.Next() {
+		var author model.Author
+		if err := rows.Scan(&author.ID, &author.Name, &author.CreatedAt, &author.UpdatedAt); err != nil {
+			return nil, err
+		}
+		authors = append(authors, author)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return authors, nil
+}
+
+func (r *SQLAuthorRepository) FindByID(ctx context.Context, id int64) (model.Author, error) {
+	query := fmt.Sprintf(`SELECT %s FROM authors WHERE id = %s`, authorColumns, r.placeholder(1))
+	return r.findOne(ctx, query, id)
+}
+
+// FindByNameはASCIIの英字の大文字・小文字を区別せずにnameと一致するAuthorを返します。
+func (r *SQLAuthorRepository) FindByName(ctx context.Context, name string) (model.Author, error) {
+	// sqliteではnameカラムのCOLLATE NOCASEによりASCIIの英字だけが大文字・小文字を区別せずに比較されます。
+	// Postgresでも同じ規則になるよう、照合順序"C"のLOWERでASCIIの英字だけを小文字にして比較します。
+	query := `SELECT ` + authorColumns + ` FROM authors WHERE name = ?`
+	if r.dialect == "postgres" {
+		query = `SELECT ` + authorColumns + ` FROM authors WHERE LOWER(name COLLATE "C") = LOWER($1::text COLLATE "C")`
+	}
+	return r.findOne(ctx, query, name)
+}
+
+func (r *SQLAuthorRepository) findOne(ctx context.Context, query string, args ...any) (model.Author, error) {
+	var author model.Author
+	if err := r.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&author.ID, &author.Name, &author.CreatedAt, &author.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Author{}, ErrAuthorNotFound
+		}
+		return model.Author{}, err
+	}
+	return author, nil
+}
+
+// RenameはAuthorの名前を変更します。記事に保持している著者名も同じトランザクションで書き換え、
+// 書き換えた記事はversionとupdated_atを進めてETagやupdated_sinceに変更が表れるようにします。
+func (r *SQLAuthorRepository) Rename(ctx context.Context, id int64, name string, updatedAt time.Time) (model.Author, error) {
+	err := runInTx(ctx, r.db, r.dialect, func(tx sqlExecutor) error {
+		query := fmt.Sprintf("UPDATE authors SET name = %s, updated_at = %s WHERE id = %s", r.placeholder(1), r.placeholder(2), r.placeholder(3))
+		res, err := tx.ExecContext(ctx, query, name, updatedAt, id)
+		if err != nil {
+			return authorNameError(err)
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrAuthorNotFound
+		}
+
+		query = fmt.Sprintf("UPDATE posts SET author = %s, updated_at = %s, version = version + 1 WHERE author_id = %s AND author <> %s",
+			r.placeholder(1), r.placeholder(2), r.placeholder(3), r.placeholder(4))
+		_, err = tx.ExecContext(ctx, query, name, updatedAt, id, name)
+		return err
+	})
+	if err != nil {
+		return model.Author{}, err
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+// DeleteはAuthorを削除します。ゴミ箱にあるものも含めて記事が残っている場合はErrAuthorHasPostsを返します。
+func (r *SQLAuthorRepository) Delete(ctx context.Context, id int64) error {
+	return runInTx(ctx, r.db, r.dialect, func(tx sqlExecutor) error {
+		var hasPosts bool
+		query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM posts WHERE author_id = %s)", r.placeholder(1))
+		if err := tx.QueryRowContext(ctx, query, id).Scan(&hasPosts); err != nil {
+			return err
+		}
+		if hasPosts {
+			return ErrAuthorHasPosts
+		}
+
+		query = fmt.Sprintf("DELETE FROM authors WHERE id = %s", r.placeholder(1))
+		res, err := tx.ExecContext(ctx, query, id)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			return ErrAuthorNotFound
+		}
+		return nil
+	})
+}
+
+func (r *SQLAuthorRepository) placeholder(idx int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", idx)
+	}
+	return "?"
+}
+
+// authorNameErrorは一意制約違反をErrAuthorNameTakenに変換します。
+func authorNameError(err error) error {
+	if isUniqueViolation(err) {
+		return ErrAuthorNameTaken
+	}
+	return err
+}
+
+// InMemoryAuthorRepositoryはAuthorRepositoryのメモリ上の実装です。
+// SQLの実装と同じく、Renameはpostsの記事の著者名も書き換え、記事が残っている著者のDeleteはErrAuthorHasPostsを返します。
+type InMemoryAuthorRepository struct {
+	mu      sync.RWMutex
+	authors map[int64]model.Author
+	posts   *InMemoryPostRepository
+	nextID  int64
+}
+
+func NewInMemoryAuthorRepository(posts *InMemoryPostRepository) *InMemoryAuthorRepository {
+	return &InMemoryAuthorRepository{
+		authors: make(map[int64]model.Author),
+		posts:   posts,
+	}
+}
+
+// SnapshotはInMemoryTxManagerで利用するため、現在の著者を保存し、その状態に戻す関数を返します。
+func (r *InMemoryAuthorRepository) Snapshot() func() {
+	r.mu.RLock()
+	authors := maps.Clone(r.authors)
+	nextID := r.nextID
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.authors = authors
+		r.nextID = nextID
+	}
+}
+
+func (r *InMemoryAuthorRepository) Create(_ context.Context, author model.Author) (model.Author, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.findByName(author.Name); ok {
+		return model.Author{}, ErrAuthorNameTaken
+	}
+
+	r.nextID++
+	author.ID = r.nextID
+	r.authors[author.ID] = author
+	return author, nil
+}
+
+func (r *InMemoryAuthorRepository) FindAll(_ context.Context) ([]model.Author, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	authors := make([]model.Author, 0, len(r.authors))
+	for _, author := range r.authors {
+		authors = append(authors, author)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].Name != authors[j].Name {
+			return authors[i].Name < authors[j].Name
+		}
+		return authors[i].ID < authors[j].ID
+	})
+	return authors, nil
+}
+
+func (r *InMemoryAuthorRepository) FindByID(_ context.Context, id int64) (model.Author, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	author, ok := r.authors[id]
+	if !ok {
+		return model.Author{}, ErrAuthorNotFound
+	}
+	return author, nil
+}
+
+func (r *InMemoryAuthorRepository) FindByName(_ context.Context, name string) (model.Author, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	author, ok := r.findByName(name)
+	if !ok {
+		return model.Author{}, ErrAuthorNotFound
+	}
+	return author, nil
+}
+
+func (r *InMemoryAuthorRepository) findByName(name string) (model.Author, bool) {
+	for _, author := range r.authors {
+		if equalAuthorNames(author.Name, name) {
+			return author, true
+		}
+	}
+	return model.Author{}, false
+}
+
+func (r *InMemoryAuthorRepository) Rename(_ context.Context, id int64, name string, updatedAt time.Time) (model.Author, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	author, ok := r.authors[id]
+	if !ok {
+		return model.Author{}, ErrAuthorNotFound
+	}
+	if other, ok := r.findByName(name); ok && other.ID != id {
+		return model.Author{}, ErrAuthorNameTaken
+	}
+
+	author.Name = name
+	author.UpdatedAt = updatedAt
+	r.authors[id] = author
+	r.posts.renameAuthor(id, name, updatedAt)
+	return author, nil
+}
+
+func (r *InMemoryAuthorRepository) Delete(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.authors[id]; !ok {
+		return ErrAuthorNotFound
+	}
+	if r.posts.hasAuthorPosts(id) {
+		return ErrAuthorHasPosts
+	}
+	delete(r.authors, id)
+	return nil
+}
+
+// equalAuthorNamesはASCIIの英字に限って大文字・小文字を区別せずに著者名を比較します。
+// sqliteのCOLLATE NOCASEとPostgresのLOWER(name COLLATE "C")と同じ規則で、"É"と"é"は別の名前として扱います。
+func equalAuthorNames(a, b string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := 0; i < len(a); i++ {
+		if lowerASCII(a[i]) != lowerASCII(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerASCII(c byte) byte {
+	if 'A' <= c && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
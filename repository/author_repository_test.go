@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+func testAuthorRepository(t *testing.T, authors AuthorRepository, posts PostRepository) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	alice, err := authors.Create(ctx, model.Author{Name: "Alice", CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := authors.Create(ctx, model.Author{Name: "ALICE", CreatedAt: now, UpdatedAt: now}); !errors.Is(err, ErrAuthorNameTaken) {
+		t.Fatalf("expected ErrAuthorNameTaken, got %v", err)
+	}
+
+	// 大文字・小文字を区別しないのはASCIIの英字だけで、どの実装でも"É"と"é"は別の著者です。
+	emile, err := authors.Create(ctx, model.Author{Name: "Émile", CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := authors.Create(ctx, model.Author{Name: "émile", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("expected a name differing in non-ASCII case to be a new author, got %v", err)
+	}
+	if found, err := authors.FindByName(ctx, "ÉMILE"); err != nil || found.ID != emile.ID {
+		t.Fatalf("expected ASCII letters to match case-insensitively, got %+v (err=%v)", found, err)
+	}
+
+	found, err := authors.FindByName(ctx, "alice")
+	if err != nil {
+		t.Fatalf("FindByName returned error: %v", err)
+	}
+	if found.ID != alice.ID || found.Name != "Alice" {
+		t.Fatalf("unexpected author: %+v", found)
+	}
+
+	post, err := posts.Create(ctx, model.Post{Title: "title", Content: "content", Author: alice.Name, AuthorID: alice.ID})
+	if err != nil {
+		t.Fatalf("Create post returned error: %v", err)
+	}
+	if post, err = posts.FindByID(ctx, post.ID); err != nil || post.AuthorID != alice.ID {
+		t.Fatalf("expected post linked to author %d, got %+v (err=%v)", alice.ID, post, err)
+	}
+
+	before := post
+	renamed, err := authors.Rename(ctx, alice.ID, "Alice Smith", now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Rename returned error: %v", err)
+	}
+	if renamed.Name != "Alice Smith" || !renamed.UpdatedAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected renamed author: %+v", renamed)
+	}
+	if post, err = posts.FindByID(ctx, post.ID); err != nil || post.Author != "Alice Smith" {
+		t.Fatalf("expected post author to follow rename, got %+v (err=%v)", post, err)
+	}
+	if post.Version != before.Version+1 || !post.UpdatedAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected the rename to bump the post version and updated_at, got %+v", post)
+	}
+
+	page, err := posts.FindPage(ctx, PostQuery{Filter: PostFilter{AuthorID: alice.ID}})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != post.ID {
+		t.Fatalf("unexpected author posts: %+v", page.Posts)
+	}
+
+	if err := authors.Delete(ctx, alice.ID); !errors.Is(err, ErrAuthorHasPosts) {
+		t.Fatalf("expected ErrAuthorHasPosts, got %v", err)
+	}
+	if err := posts.Delete(ctx, post.ID); err != nil {
+		t.Fatalf("Delete post returned error: %v", err)
+	}
+	if err := authors.Delete(ctx, alice.ID); !errors.Is(err, ErrAuthorHasPosts) {
+		t.Fatalf("expected trashed posts to block deletion, got %v", err)
+	}
+	if err := posts.Purge(ctx, post.ID); err != nil {
+		t.Fatalf("Purge returned error: %v", err)
+	}
+	if err := authors.Delete(ctx, alice.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := authors.FindByID(ctx, alice.ID); !errors.Is(err, ErrAuthorNotFound) {
+		t.Fatalf("expected ErrAuthorNotFound, got %v", err)
+	}
+}
+
+func TestInMemoryAuthorRepository_Lifecycle(t *testing.T) {
+	posts := NewInMemoryPostRepository()
+	testAuthorRepository(t, NewInMemoryAuthorRepository(posts), posts)
+}
+
+func TestSQLAuthorRepository_Lifecycle(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	testAuthorRepository(t, NewSQLAuthorRepository(posts.db, "sqlite"), posts)
+}
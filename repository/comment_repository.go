@@ -0,0 +1,399 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+var ErrCommentNotFound = errors.New("comment not found")
+
+// CommentRepositoryはCommentの永続化を抽象化するインターフェースです。
+// コメントを削除すると返信も削除され、Postを物理削除するとコメントも連動して削除されます。
+type CommentRepository interface {
+	Create(ctx context.Context, comment model.Comment) (model.Comment, error)
+	FindByID(ctx context.Context, id int64) (model.Comment, error)
+	FindPage(ctx context.Context, query CommentQuery) (CommentPage, error)
+	Delete(ctx context.Context, id int64) error
+}
+
+// ReplyPreviewLimitはトップレベルのコメントのページに含める、コメントごとの返信の件数です。
+// 残りの返信はCommentQuery.ParentIDを指定して取得します。
+const ReplyPreviewLimit = 3
+
+// CommentQueryはPostのトップレベルのコメントをID順にページ単位で取得する条件です。
+// ParentIDを指定した場合は、そのコメントへの返信をID順に取得します。
+type CommentQuery struct {
+	PostID   int64
+	ParentID int64
+	Limit    int
+	Cursor   string
+}
+
+// CommentPageはコメントのページです。トップレベルのコメントにはReplyPreviewLimit件までの返信と返信の総数が含まれます。
+type CommentPage struct {
+	Comments   []model.Comment
+	NextCursor string
+}
+
+type commentCursor struct {
+	ID int64 `json:"id"`
+}
+
+// commentPageBoundsはクエリのLimitを正規化し、Cursorが指定されていれば直前のコメントIDを返します。
+func commentPageBounds(query CommentQuery) (int, int64, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if query.Cursor == "" {
+		return limit, 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(query.Cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	var cur commentCursor
+	if err := json.Unmarshal(data, &cur); err != nil || cur.ID <= 0 {
+		return 0, 0, ErrInvalidCursor
+	}
+	return limit, cur.ID, nil
+}
+
+// buildCommentPageはlimit+1件まで取得した結果からページと次のカーソルを組み立てます。
+func buildCommentPage(comments []model.Comment, limit int) CommentPage {
+	if len(comments) <= limit {
+		return CommentPage{Comments: comments}
+	}
+	comments = comments[:limit]
+	return CommentPage{Comments: comments, NextCursor: encodeCommentCursor(comments[len(comments)-1].ID)}
+}
+
+// encodeCommentCursorはidのコメントの次から取得するためのカーソルを返します。
+func encodeCommentCursor(id int64) string {
+	data, err := json.Marshal(commentCursor{ID: id})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// setRepliesCursorは返信の一部だけを含むコメントに、続きの返信を取得するためのカーソルを設定します。
+func setRepliesCursor(comment *model.Comment) {
+	if comment.ReplyCount > len(comment.Replies) && len(comment.Replies) > 0 {
+		comment.RepliesNextCursor = encodeCommentCursor(comment.Replies[len(comment.Replies)-1].ID)
+	}
+}
+
+const commentColumns = "id, post_id, parent_id, author, body, created_at"
+
+// SQLCommentRepositoryはRDBを利用したCommentRepositoryの実装です。
+type SQLCommentRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+func NewSQLCommentRepository(db *sql.DB, driver string) *SQLCommentRepository {
+	return &SQLCommentRepository{
+		db:      db,
+		dialect: detectDialect(driver),
+	}
+}
+
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLCommentRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+func (r *SQLCommentRepository) Create(ctx context.Context, comment model.Comment) (model.Comment, error) {
+	args := []any{comment.PostID, comment.ParentID, comment.Author, comment.Body, comment.CreatedAt}
+	switch r.dialect {
+	case "postgres":
+		query := `INSERT INTO comments (post_id, parent_id, author, body, created_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+		if err := r.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&comment.ID); err != nil {
+			return model.Comment{}, err
+		}
+		return comment, nil
+	default:
+		res, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO comments (post_id, parent_id, author, body, created_at) VALUES (?, ?, ?, ?, ?)`, args...)
+		if err != nil {
+			return model.Comment{}, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return model.Comment{}, err
+		}
+		comment.ID = id
+		return comment, nil
+	}
+}
+
+func (r *SQLCommentRepository) FindByID(ctx context.Context, id int64) (model.Comment, error) {
+	query := fmt.Sprintf(`SELECT %s FROM comments WHERE id = %s`, commentColumns, r.placeholder(1))
+	comment, err := scanComment(r.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Comment{}, ErrCommentNotFound
+		}
+		return model.Comment{}, err
+	}
+	return comment, nil
+}
+
+func (r *SQLCommentRepository) FindPage(ctx context.Context, query CommentQuery) (CommentPage, error) {
+	limit, afterID, err := commentPageBounds(query)
+	if err != nil {
+		return CommentPage{}, err
+	}
+
+	if query.ParentID != 0 {
+		q := fmt.Sprintf(`SELECT %s FROM comments WHERE post_id = %s AND parent_id = %s AND id > %s ORDER BY id LIMIT %s`,
+			commentColumns, r.placeholder(1), r.placeholder(2), r.placeholder(3), r.placeholder(4))
+		replies, err := r.query(ctx, q, query.PostID, query.ParentID, afterID, limit+1)
+		if err != nil {
+			return CommentPage{}, err
+		}
+		return buildCommentPage(replies, limit), nil
+	}
+
+	q := fmt.Sprintf(`SELECT %s FROM comments WHERE post_id = %s AND parent_id IS NULL AND id > %s ORDER BY id LIMIT %s`,
+		commentColumns, r.placeholder(1), r.placeholder(2), r.placeholder(3))
+	comments, err := r.query(ctx, q, query.PostID, afterID, limit+1)
+	if err != nil {
+		return CommentPage{}, err
+	}
+
+	page := buildCommentPage(comments, limit)
+	if err := r.attachReplies(ctx, page.Comments); err != nil {
+		return CommentPage{}, err
+	}
+	return page, nil
+}
+
+// attachRepliesはトップレベルのコメントそれぞれに、返信を古い順でReplyPreviewLimit件までと返信の総数を読み込みます。
+// ウィンドウ関数でコメントごとに件数を絞るため、返信の多いコメントがあっても1回の問い合わせで済みます。
+func (r *SQLCommentRepository) attachReplies(ctx context.Context, comments []model.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]int, len(comments))
+	placeholders := make([]string, len(comments))
+	args := make([]any, len(comments))
+	for i, comment := range comments {
+		index[comment.ID] = i
+		placeholders[i] = r.placeholder(i + 1)
+		args[i] = comment.ID
+	}
+
+	q := fmt.Sprintf(`SELECT %s, reply_count FROM (
+		SELECT %s,
+			ROW_NUMBER() OVER (PARTITION BY parent_id ORDER BY id) AS reply_number,
+			COUNT(*) OVER (PARTITION BY parent_id) AS reply_count
+		FROM comments WHERE parent_id IN (%s)
+	) replies WHERE reply_number <= %d ORDER BY id`, commentColumns, commentColumns, strings.Join(placeholders, ", "), ReplyPreviewLimit)
+	rows, err := r.conn(ctx).QueryContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			reply    model.Comment
+			parentID int64
+			count    int
+		)
+		if err := rows.Scan(&reply.ID, &reply.PostID, &parentID, &reply.Author, &reply.Body, &reply.CreatedAt, &count); err != nil {
+			return err
+		}
+		reply.ParentID = &parentID
+		i := index[parentID]
+		comments[i].Replies = append(comments[i].Replies, reply)
+		comments[i].ReplyCount = count
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range comments {
+		setRepliesCursor(&comments[i])
+	}
+	return nil
+}
+
+func (r *SQLCommentRepository) query(ctx context.Context, query string, args ...any) ([]model.Comment, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]model.Comment, 0)
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return comments, nil
+}
+
+// Deleteはコメントを削除します。返信は外部キーのON DELETE CASCADEにより連動して削除されます。
+func (r *SQLCommentRepository) Delete(ctx context.Context, id int64) error {
+	query := fmt.Sprintf("DELETE FROM comments WHERE id = %s", r.placeholder(1))
+	res, err := r.conn(ctx).ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCommentNotFound
+	}
+	return nil
+}
+
+func (r *SQLCommentRepository) placeholder(idx int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", idx)
+	}
+	return "?"
+}
+
+func scanComment(row rowScanner) (model.Comment, error) {
+	var comment model.Comment
+	var parentID sql.NullInt64
+	if err := row.Scan(&comment.ID, &comment.PostID, &parentID, &comment.Author, &comment.Body, &comment.CreatedAt); err != nil {
+		return model.Comment{}, err
+	}
+	if parentID.Valid {
+		comment.ParentID = &parentID.Int64
+	}
+	return comment, nil
+}
+
+// InMemoryCommentRepositoryはCommentRepositoryのメモリ上の実装です。
+type InMemoryCommentRepository struct {
+	mu       sync.RWMutex
+	comments map[int64]model.Comment
+	nextID   int64
+}
+
+func NewInMemoryCommentRepository() *InMemoryCommentRepository {
+	return &InMemoryCommentRepository{
+		comments: make(map[int64]model.Comment),
+	}
+}
+
+func (r *InMemoryCommentRepository) Create(_ context.Context, comment model.Comment) (model.Comment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	comment.ID = r.nextID
+	comment.Replies = nil
+	r.comments[comment.ID] = comment
+	return comment, nil
+}
+
+func (r *InMemoryCommentRepository) FindByID(_ context.Context, id int64) (model.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return model.Comment{}, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+func (r *InMemoryCommentRepository) FindPage(_ context.Context, query CommentQuery) (CommentPage, error) {
+	limit, afterID, err := commentPageBounds(query)
+	if err != nil {
+		return CommentPage{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	all := make([]model.Comment, 0)
+	for _, comment := range r.comments {
+		if comment.PostID == query.PostID {
+			all = append(all, comment)
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].ID < all[j].ID
+	})
+
+	if query.ParentID != 0 {
+		replies := make([]model.Comment, 0, limit+1)
+		for _, comment := range all {
+			if comment.ParentID != nil && *comment.ParentID == query.ParentID && comment.ID > afterID && len(replies) <= limit {
+				replies = append(replies, comment)
+			}
+		}
+		return buildCommentPage(replies, limit), nil
+	}
+
+	topLevel := make([]model.Comment, 0, limit+1)
+	for _, comment := range all {
+		if comment.ParentID == nil && comment.ID > afterID && len(topLevel) <= limit {
+			topLevel = append(topLevel, comment)
+		}
+	}
+
+	page := buildCommentPage(topLevel, limit)
+	index := make(map[int64]int, len(page.Comments))
+	for i, comment := range page.Comments {
+		index[comment.ID] = i
+	}
+	for _, comment := range all {
+		if comment.ParentID == nil {
+			continue
+		}
+		if i, ok := index[*comment.ParentID]; ok {
+			if len(page.Comments[i].Replies) < ReplyPreviewLimit {
+				page.Comments[i].Replies = append(page.Comments[i].Replies, comment)
+			}
+			page.Comments[i].ReplyCount++
+		}
+	}
+	for i := range page.Comments {
+		setRepliesCursor(&page.Comments[i])
+	}
+	return page, nil
+}
+
+func (r *InMemoryCommentRepository) Delete(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.comments[id]; !ok {
+		return ErrCommentNotFound
+	}
+	delete(r.comments, id)
+	for replyID, comment := range r.comments {
+		if comment.ParentID != nil && *comment.ParentID == id {
+			delete(r.comments, replyID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+func TestSQLCommentRepository_ThreadsAndPagination(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	comments := NewSQLCommentRepository(posts.db, "sqlite")
+
+	ctx := context.Background()
+	post, err := posts.Create(ctx, model.Post{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	now := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	var topLevel []model.Comment
+	for i := 0; i < 3; i++ {
+		comment, err := comments.Create(ctx, model.Comment{PostID: post.ID, Author: "reader", Body: "comment", CreatedAt: now})
+		if err != nil {
+			t.Fatalf("Create comment returned error: %v", err)
+		}
+		topLevel = append(topLevel, comment)
+	}
+	reply, err := comments.Create(ctx, model.Comment{PostID: post.ID, ParentID: &topLevel[0].ID, Author: "author", Body: "reply", CreatedAt: now})
+	if err != nil {
+		t.Fatalf("Create reply returned error: %v", err)
+	}
+
+	found, err := comments.FindByID(ctx, reply.ID)
+	if err != nil {
+		t.Fatalf("FindByID returned error: %v", err)
+	}
+	if found.ParentID == nil || *found.ParentID != topLevel[0].ID {
+		t.Fatalf("unexpected reply: %+v", found)
+	}
+
+	page, err := comments.FindPage(ctx, CommentQuery{PostID: post.ID, Limit: 2})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Comments) != 2 || page.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	if len(page.Comments[0].Replies) != 1 || page.Comments[0].Replies[0].ID != reply.ID {
+		t.Fatalf("expected reply to be nested under its parent, got %+v", page.Comments[0])
+	}
+
+	page, err = comments.FindPage(ctx, CommentQuery{PostID: post.ID, Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Comments) != 1 || page.Comments[0].ID != topLevel[2].ID || page.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+	if _, err := comments.FindPage(ctx, CommentQuery{PostID: post.ID, Cursor: "bogus"}); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+
+	if err := comments.Delete(ctx, topLevel[0].ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := comments.FindByID(ctx, reply.ID); err != ErrCommentNotFound {
+		t.Fatalf("expected reply to be deleted with its parent, got %v", err)
+	}
+	if err := comments.Delete(ctx, topLevel[0].ID); err != ErrCommentNotFound {
+		t.Fatalf("expected ErrCommentNotFound, got %v", err)
+	}
+
+	if err := posts.Delete(ctx, post.ID); err != nil {
+		t.Fatalf("Delete post returned error: %v", err)
+	}
+	if err := posts.Purge(ctx, post.ID); err != nil {
+		t.Fatalf("Purge returned error: %v", err)
+	}
+	if _, err := comments.FindByID(ctx, topLevel[1].ID); err != ErrCommentNotFound {
+		t.Fatalf("expected comments to be purged with the post, got %v", err)
+	}
+}
+
+func testCommentReplies(t *testing.T, comments CommentRepository, postID int64) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	parent, err := comments.Create(ctx, model.Comment{PostID: postID, Author: "reader", Body: "comment", CreatedAt: now})
+	if err != nil {
+		t.Fatalf("Create comment returned error: %v", err)
+	}
+	var replies []model.Comment
+	for i := 0; i < ReplyPreviewLimit+2; i++ {
+		reply, err := comments.Create(ctx, model.Comment{PostID: postID, ParentID: &parent.ID, Author: "author", Body: "reply", CreatedAt: now})
+		if err != nil {
+			t.Fatalf("Create reply returned error: %v", err)
+		}
+		replies = append(replies, reply)
+	}
+
+	// トップレベルのコメントには返信の一部と総数、続きのカーソルだけが含まれます。
+	page, err := comments.FindPage(ctx, CommentQuery{PostID: postID})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Comments) != 1 {
+		t.Fatalf("expected only the top-level comment, got %+v", page.Comments)
+	}
+	got := page.Comments[0]
+	if len(got.Replies) != ReplyPreviewLimit || got.Replies[0].ID != replies[0].ID || got.ReplyCount != len(replies) || got.RepliesNextCursor == "" {
+		t.Fatalf("expected %d of %d replies with a cursor, got %+v", ReplyPreviewLimit, len(replies), got)
+	}
+
+	page, err = comments.FindPage(ctx, CommentQuery{PostID: postID, ParentID: parent.ID, Limit: 1, Cursor: got.RepliesNextCursor})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Comments) != 1 || page.Comments[0].ID != replies[ReplyPreviewLimit].ID || page.NextCursor == "" {
+		t.Fatalf("unexpected replies page: %+v", page)
+	}
+	page, err = comments.FindPage(ctx, CommentQuery{PostID: postID, ParentID: parent.ID, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Comments) != 1 || page.Comments[0].ID != replies[len(replies)-1].ID || page.NextCursor != "" {
+		t.Fatalf("unexpected last replies page: %+v", page)
+	}
+}
+
+func TestInMemoryCommentRepository_Replies(t *testing.T) {
+	testCommentReplies(t, NewInMemoryCommentRepository(), 1)
+}
+
+func TestSQLCommentRepository_Replies(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	post, err := posts.Create(context.Background(), model.Post{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	testCommentReplies(t, NewSQLCommentRepository(posts.db, "sqlite"), post.ID)
+}
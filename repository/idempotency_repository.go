@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecordはIdempotency-Keyに対応するリクエストと、その最初の応答です。
+// StatusCodeが0の間は最初のリクエストを処理中です。Ownerは処理中のリクエストを識別する値で、
+// 処理を引き継がれたリクエストが後から応答を上書きしないようにします。
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	Owner       string
+	StatusCode  int
+	Headers     map[string]string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// InFlightは最初のリクエストを処理中かどうかを返します。
+func (r IdempotencyRecord) InFlight() bool {
+	return r.StatusCode == 0
+}
+
+// IdempotencyRepositoryはIdempotencyRecordの永続化を抽象化するインターフェースです。
+type IdempotencyRepository interface {
+	// Claimはrecordを処理中として保存します。有効期限内の同じキーが既にある場合は保存せず、
+	// 既存のレコードとfalseを返します。有効期限を過ぎたレコードは無いものとして扱います。
+	Claim(ctx context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error)
+	// Completeはownerが処理中のレコードに応答を保存します。
+	Complete(ctx context.Context, key, owner string, statusCode int, headers map[string]string, body []byte) error
+	// Releaseはownerが処理中のレコードを削除し、同じキーで再度リクエストできるようにします。
+	Release(ctx context.Context, key, owner string) error
+	// DeleteExpiredは有効期限を過ぎたレコードを削除し、削除した件数を返します。
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// SQLIdempotencyRepositoryはRDBを利用したIdempotencyRepositoryの実装です。
+// キーの主キー制約により、同時に届いた同じキーのリクエストのうち1つだけが処理中として保存されます。
+type SQLIdempotencyRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+func NewSQLIdempotencyRepository(db *sql.DB, driver string) *SQLIdempotencyRepository {
+	return &SQLIdempotencyRepository{
+		db:      db,
+		dialect: detectDialect(driver),
+	}
+}
+
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLIdempotencyRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+func (r *SQLIdempotencyRepository) Claim(ctx context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	deleteQuery := fmt.Sprintf("DELETE FROM idempotency_keys WHERE idempotency_key = %s AND expires_at <= %s", r.placeholder(1), r.placeholder(2))
+	insertQuery := fmt.Sprintf(`INSERT INTO idempotency_keys (idempotency_key, request_hash, owner, created_at, expires_at)
+VALUES (%s, %s, %s, %s, %s) ON CONFLICT (idempotency_key) DO NOTHING`,
+		r.placeholder(1), r.placeholder(2), r.placeholder(3), r.placeholder(4), r.placeholder(5))
+	selectQuery := fmt.Sprintf(`SELECT idempotency_key, request_hash, owner, status_code, response_headers, response_body, created_at, expires_at
+FROM idempotency_keys WHERE idempotency_key = %s`, r.placeholder(1))
+
+	// 既存のレコードを読む前に削除・失効された場合に備えて、一度だけやり直します。
+	for attempt := 0; attempt < 2; attempt++ {
+		if _, err := r.conn(ctx).ExecContext(ctx, deleteQuery, record.Key, record.CreatedAt); err != nil {
+			return IdempotencyRecord{}, false, err
+		}
+		res, err := r.conn(ctx).ExecContext(ctx, insertQuery, record.Key, record.RequestHash, record.Owner, record.CreatedAt, record.ExpiresAt)
+		if err != nil {
+			return IdempotencyRecord{}, false, err
+		}
+		inserted, err := res.RowsAffected()
+		if err != nil {
+			return IdempotencyRecord{}, false, err
+		}
+		if inserted == 1 {
+			return record, true, nil
+		}
+
+		var (
+			existing IdempotencyRecord
+			headers  string
+		)
+		err = r.conn(ctx).QueryRowContext(ctx, selectQuery, record.Key).Scan(&existing.Key, &existing.RequestHash, &existing.Owner,
+			&existing.StatusCode, &headers, &existing.Body, &existing.CreatedAt, &existing.ExpiresAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return IdempotencyRecord{}, false, err
+		}
+		if err := json.Unmarshal([]byte(headers), &existing.Headers); err != nil {
+			return IdempotencyRecord{}, false, err
+		}
+		return existing, false, nil
+	}
+	return IdempotencyRecord{}, false, fmt.Errorf("claim idempotency key %q: record changed concurrently", record.Key)
+}
+
+func (r *SQLIdempotencyRepository) Complete(ctx context.Context, key, owner string, statusCode int, headers map[string]string, body []byte) error {
+	encoded, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("UPDATE idempotency_keys SET status_code = %s, response_headers = %s, response_body = %s WHERE idempotency_key = %s AND owner = %s",
+		r.placeholder(1), r.placeholder(2), r.placeholder(3), r.placeholder(4), r.placeholder(5))
+	_, err = r.conn(ctx).ExecContext(ctx, query, statusCode, string(encoded), body, key, owner)
+	return err
+}
+
+func (r *SQLIdempotencyRepository) Release(ctx context.Context, key, owner string) error {
+	query := fmt.Sprintf("DELETE FROM idempotency_keys WHERE idempotency_key = %s AND owner = %s", r.placeholder(1), r.placeholder(2))
+	_, err := r.conn(ctx).ExecContext(ctx, query, key, owner)
+	return err
+}
+
+func (r *SQLIdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM idempotency_keys WHERE expires_at <= %s", r.placeholder(1))
+	res, err := r.conn(ctx).ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *SQLIdempotencyRepository) placeholder(idx int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", idx)
+	}
+	return "?"
+}
+
+// InMemoryIdempotencyRepositoryはIdempotencyRepositoryのメモリ上の実装です。
+type InMemoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+func NewInMemoryIdempotencyRepository() *InMemoryIdempotencyRepository {
+	return &InMemoryIdempotencyRepository{
+		records: make(map[string]IdempotencyRecord),
+	}
+}
+
+func (r *InMemoryIdempotencyRepository) Claim(_ context.Context, record IdempotencyRecord) (IdempotencyRecord, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.records[record.Key]; ok && record.CreatedAt.Before(existing.ExpiresAt) {
+		return existing, false, nil
+	}
+	r.records[record.Key] = record
+	return record, true, nil
+}
+
+func (r *InMemoryIdempotencyRepository) Complete(_ context.Context, key, owner string, statusCode int, headers map[string]string, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.records[key]
+	if !ok || record.Owner != owner {
+		return nil
+	}
+	record.StatusCode = statusCode
+	record.Headers = headers
+	record.Body = append([]byte(nil), body...)
+	r.records[key] = record
+	return nil
+}
+
+func (r *InMemoryIdempotencyRepository) Release(_ context.Context, key, owner string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if record, ok := r.records[key]; ok && record.Owner == owner {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *InMemoryIdempotencyRepository) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, record := range r.records {
+		if !now.Before(record.ExpiresAt) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
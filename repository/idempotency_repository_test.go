@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testIdempotencyRepository(t *testing.T, repo IdempotencyRepository) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	record := IdempotencyRecord{Key: "client:key", RequestHash: "hash", Owner: "a", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	if _, claimed, err := repo.Claim(ctx, record); err != nil || !claimed {
+		t.Fatalf("expected first claim to succeed, got claimed=%v err=%v", claimed, err)
+	}
+	second := record
+	second.Owner = "b"
+	existing, claimed, err := repo.Claim(ctx, second)
+	if err != nil || claimed {
+		t.Fatalf("expected second claim to fail, got claimed=%v err=%v", claimed, err)
+	}
+	if !existing.InFlight() || existing.Owner != "a" || existing.RequestHash != "hash" {
+		t.Fatalf("unexpected existing record: %+v", existing)
+	}
+
+	// 別のownerは応答を保存できません。
+	if err := repo.Complete(ctx, "client:key", "b", 500, nil, nil); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if err := repo.Complete(ctx, "client:key", "a", 201, map[string]string{"Content-Type": "application/json"}, []byte(`{"id":1}`)); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	existing, _, err = repo.Claim(ctx, second)
+	if err != nil {
+		t.Fatalf("Claim returned error: %v", err)
+	}
+	if existing.StatusCode != 201 || string(existing.Body) != `{"id":1}` || existing.Headers["Content-Type"] != "application/json" {
+		t.Fatalf("unexpected completed record: %+v", existing)
+	}
+
+	// 有効期限を過ぎたキーは再び利用できます。
+	expired := second
+	expired.CreatedAt = now.Add(time.Hour)
+	expired.ExpiresAt = now.Add(2 * time.Hour)
+	if _, claimed, err := repo.Claim(ctx, expired); err != nil || !claimed {
+		t.Fatalf("expected expired key to be claimable, got claimed=%v err=%v", claimed, err)
+	}
+
+	if err := repo.Release(ctx, "client:key", "a"); err != nil {
+		t.Fatalf("Release returned error: %v", err)
+	}
+	if _, claimed, _ := repo.Claim(ctx, expired); claimed {
+		t.Fatal("expected release by another owner to keep the record")
+	}
+	if err := repo.Release(ctx, "client:key", "b"); err != nil {
+		t.Fatalf("Release returned error: %v", err)
+	}
+	if _, claimed, err := repo.Claim(ctx, expired); err != nil || !claimed {
+		t.Fatalf("expected released key to be claimable, got claimed=%v err=%v", claimed, err)
+	}
+
+	if deleted, err := repo.DeleteExpired(ctx, now.Add(3*time.Hour)); err != nil || deleted != 1 {
+		t.Fatalf("expected 1 expired record to be deleted, got %d (err=%v)", deleted, err)
+	}
+}
+
+func TestInMemoryIdempotencyRepository(t *testing.T) {
+	testIdempotencyRepository(t, NewInMemoryIdempotencyRepository())
+}
+
+func TestSQLIdempotencyRepository(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	testIdempotencyRepository(t, NewSQLIdempotencyRepository(posts.db, "sqlite"))
+}
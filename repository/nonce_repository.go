@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// NonceRepositoryはHMAC署名のnonceの使用履歴を抽象化するインターフェースです。
+// 複数台で動かす場合も同じnonceを一度しか受け付けないよう、SQLの実装ではデータベースで共有します。
+type NonceRepository interface {
+	// Useはnonceをexpires_atまで使用済みとして記録し、未使用だった場合にtrueを返します。
+	// nowの時点で有効期限を過ぎたnonceは未使用として扱います。
+	Use(ctx context.Context, nonce string, now, expiresAt time.Time) (bool, error)
+	// DeleteExpiredは有効期限を過ぎたnonceを削除し、削除した件数を返します。
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// SQLNonceRepositoryはRDBを利用したNonceRepositoryの実装です。
+// nonceの主キー制約により、同時に届いた同じnonceのリクエストのうち1つだけが受け付けられます。
+type SQLNonceRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+func NewSQLNonceRepository(db *sql.DB, driver string) *SQLNonceRepository {
+	return &SQLNonceRepository{
+		db:      db,
+		dialect: detectDialect(driver),
+	}
+}
+
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLNonceRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+func (r *SQLNonceRepository) Use(ctx context.Context, nonce string, now, expiresAt time.Time) (bool, error) {
+	deleteQuery := fmt.Sprintf("DELETE FROM hmac_nonces WHERE nonce = %s AND expires_at <= %s", r.placeholder(1), r.placeholder(2))
+	if _, err := r.conn(ctx).ExecContext(ctx, deleteQuery, nonce, now); err != nil {
+		return false, err
+	}
+
+	insertQuery := fmt.Sprintf("INSERT INTO hmac_nonces (nonce, expires_at) VALUES (%s, %s) ON CONFLICT (nonce) DO NOTHING", r.placeholder(1), r.placeholder(2))
+	res, err := r.conn(ctx).ExecContext(ctx, insertQuery, nonce, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted == 1, nil
+}
+
+func (r *SQLNonceRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM hmac_nonces WHERE expires_at <= %s", r.placeholder(1))
+	res, err := r.conn(ctx).ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *SQLNonceRepository) placeholder(idx int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", idx)
+	}
+	return "?"
+}
+
+// InMemoryNonceRepositoryはNonceRepositoryのメモリ上の実装です。1台で動かす場合とテストで利用します。
+type InMemoryNonceRepository struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+}
+
+func NewInMemoryNonceRepository() *InMemoryNonceRepository {
+	return &InMemoryNonceRepository{
+		nonces: make(map[string]time.Time),
+	}
+}
+
+func (r *InMemoryNonceRepository) Use(_ context.Context, nonce string, now, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if expires, ok := r.nonces[nonce]; ok && now.Before(expires) {
+		return false, nil
+	}
+	r.nonces[nonce] = expiresAt
+	return true, nil
+}
+
+func (r *InMemoryNonceRepository) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for nonce, expires := range r.nonces {
+		if !now.Before(expires) {
+			delete(r.nonces, nonce)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func testNonceRepository(t *testing.T, repo NonceRepository) {
+	t.Helper()
+
+	ctx := context.Background()
+	now := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	if used, err := repo.Use(ctx, "1:a", now, now.Add(time.Minute)); err != nil || !used {
+		t.Fatalf("expected first use to succeed, got %v (err=%v)", used, err)
+	}
+	if used, err := repo.Use(ctx, "1:a", now.Add(30*time.Second), now.Add(90*time.Second)); err != nil || used {
+		t.Fatalf("expected reuse within ttl to fail, got %v (err=%v)", used, err)
+	}
+	if used, err := repo.Use(ctx, "2:a", now, now.Add(time.Minute)); err != nil || !used {
+		t.Fatalf("expected the same nonce of another key to succeed, got %v (err=%v)", used, err)
+	}
+
+	// 有効期限を過ぎたnonceは再び利用できます。
+	later := now.Add(time.Minute)
+	if used, err := repo.Use(ctx, "1:a", later, later.Add(time.Minute)); err != nil || !used {
+		t.Fatalf("expected use after ttl to succeed, got %v (err=%v)", used, err)
+	}
+
+	if deleted, err := repo.DeleteExpired(ctx, later); err != nil || deleted != 1 {
+		t.Fatalf("expected 1 expired nonce to be deleted, got %d (err=%v)", deleted, err)
+	}
+}
+
+func TestInMemoryNonceRepository(t *testing.T) {
+	testNonceRepository(t, NewInMemoryNonceRepository())
+}
+
+func TestSQLNonceRepository(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	testNonceRepository(t, NewSQLNonceRepository(posts.db, "sqlite"))
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+// OperationObserverはリポジトリの操作名と所要時間を受け取る関数です。
+type OperationObserver func(operation string, duration time.Duration)
+
+// InstrumentedPostRepositoryはPostRepositoryの各操作の所要時間をOperationObserverへ通知するデコレーターです。
+type InstrumentedPostRepository struct {
+	next    PostRepository
+	observe OperationObserver
+}
+
+func NewInstrumentedPostRepository(next PostRepository, observe OperationObserver) *InstrumentedPostRepository {
+	return &InstrumentedPostRepository{
+		next:    next,
+		observe: observe,
+	}
+}
+
+// measureはoperationの開始時刻を記録し、呼び出すと所要時間を通知する関数を返します。
+func (r *InstrumentedPostRepository) measure(operation string) func() {
+	start := time.Now()
+	return func() {
+		r.observe(operation, time.Since(start))
+	}
+}
+
+func (r *InstrumentedPostRepository) Create(ctx context.Context, post model.Post) (model.Post, error) {
+	defer r.measure("create")()
+	return r.next.Create(ctx, post)
+}
+
+func (r *InstrumentedPostRepository) FindAll(ctx context.Context) ([]model.Post, error) {
+	defer r.measure("find_all")()
+	return r.next.FindAll(ctx)
+}
+
+func (r *InstrumentedPostRepository) FindPage(ctx context.Context, query PostQuery) (PostPage, error) {
+	defer r.measure("find_page")()
+	return r.next.FindPage(ctx, query)
+}
+
+func (r *InstrumentedPostRepository) Search(ctx context.Context, query PostSearchQuery) ([]model.PostSearchHit, error) {
+	defer r.measure("search")()
+	return r.next.Search(ctx, query)
+}
+
+func (r *InstrumentedPostRepository) FindByID(ctx context.Context, id int64) (model.Post, error) {
+	defer r.measure("find_by_id")()
+	return r.next.FindByID(ctx, id)
+}
+
+func (r *InstrumentedPostRepository) FindBySlug(ctx context.Context, slug string) (model.Post, error) {
+	defer r.measure("find_by_slug")()
+	return r.next.FindBySlug(ctx, slug)
+}
+
+func (r *InstrumentedPostRepository) SlugTaken(ctx context.Context, slug string, exceptID int64) (bool, error) {
+	defer r.measure("slug_taken")()
+	return r.next.SlugTaken(ctx, slug, exceptID)
+}
+
+func (r *InstrumentedPostRepository) Update(ctx context.Context, id int64, update PostUpdate) (model.Post, error) {
+	defer r.measure("update")()
+	return r.next.Update(ctx, id, update)
+}
+
+func (r *InstrumentedPostRepository) Delete(ctx context.Context, id int64) error {
+	defer r.measure("delete")()
+	return r.next.Delete(ctx, id)
+}
+
+func (r *InstrumentedPostRepository) FindTrash(ctx context.Context, query PostQuery) (PostPage, error) {
+	defer r.measure("find_trash")()
+	return r.next.FindTrash(ctx, query)
+}
+
+func (r *InstrumentedPostRepository) Restore(ctx context.Context, id int64) (model.Post, error) {
+	defer r.measure("restore")()
+	return r.next.Restore(ctx, id)
+}
+
+func (r *InstrumentedPostRepository) Purge(ctx context.Context, id int64) error {
+	defer r.measure("purge")()
+	return r.next.Purge(ctx, id)
+}
+
+func (r *InstrumentedPostRepository) PublishDue(ctx context.Context, now time.Time, actor string) (int64, error) {
+	defer r.measure("publish_due")()
+	return r.next.PublishDue(ctx, now, actor)
+}
+
+func (r *InstrumentedPostRepository) FindTags(ctx context.Context, status string) ([]model.Tag, error) {
+	defer r.measure("find_tags")()
+	return r.next.FindTags(ctx, status)
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+func TestInstrumentedPostRepository(t *testing.T) {
+	var operations []string
+	repo := NewInstrumentedPostRepository(NewInMemoryPostRepository(), func(operation string, duration time.Duration) {
+		if duration < 0 {
+			t.Fatalf("unexpected duration %v for %s", duration, operation)
+		}
+		operations = append(operations, operation)
+	})
+
+	ctx := context.Background()
+	created, err := repo.Create(ctx, model.Post{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, created.ID); err != nil {
+		t.Fatalf("FindByID returned error: %v", err)
+	}
+	// 失敗した操作も計測されます。
+	if err := repo.Delete(ctx, 999); err != ErrPostNotFound {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+
+	want := []string{"create", "find_by_id", "delete"}
+	if len(operations) != len(want) {
+		t.Fatalf("expected operations %v, got %v", want, operations)
+	}
+	for i := range want {
+		if operations[i] != want[i] {
+			t.Fatalf("expected operations %v, got %v", want, operations)
+		}
+	}
+}
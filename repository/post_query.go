@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+)
+
+// DefaultPageLimitはPostQuery.Limitが未指定の場合に利用する件数です。
+const DefaultPageLimit = 20
+
+// 並び替えに利用できるフィールドです。
+const (
+	SortFieldID        = "id"
+	SortFieldCreatedAt = "created_at"
+	SortFieldUpdatedAt = "updated_at"
+)
+
+// sortFieldDeletedAtはゴミ箱の一覧でのみ使う並び替えフィールドです。ParsePostSortでは受け付けません。
+const sortFieldDeletedAt = "deleted_at"
+
+// trashSortはゴミ箱の並び順(削除日時の新しい順)です。
+var trashSort = PostSort{Field: sortFieldDeletedAt, Desc: true}
+
+// PostQueryはPost一覧をページ単位で取得する際の条件です。
+// Cursorは同じFilter/Sortで取得した前ページのPostPage.NextCursorをそのまま渡します。
+type PostQuery struct {
+	Filter PostFilter
+	Sort   PostSort
+	Limit  int
+	Cursor string
+}
+
+// PostFilterはPost一覧の絞り込み条件です。ゼロ値の項目は条件に含めません。
+type PostFilter struct {
+	Status        string
+	Author        string
+	AuthorID      int64
+	Tag           string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// UpdatedSinceは指定時刻以降(指定時刻を含む)に作成・更新されたPostに絞り込みます。
+	UpdatedSince *time.Time
+}
+
+// PostSortはPost一覧の並び順です。ゼロ値はID昇順を表します。
+type PostSort struct {
+	Field string
+	Desc  bool
+}
+
+// PostPageはページングされたPost一覧です。NextCursorが空の場合は最終ページです。
+type PostPage struct {
+	Posts      []model.Post
+	NextCursor string
+}
+
+// ParsePostSortは"created_at"や"-created_at"形式の文字列をPostSortに変換します。
+// 先頭の"-"は降順を表し、空文字はID昇順として扱います。
+func ParsePostSort(raw string) (PostSort, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return PostSort{}, nil
+	}
+
+	var s PostSort
+	if strings.HasPrefix(raw, "-") {
+		s.Desc = true
+		raw = raw[1:]
+	}
+
+	switch raw {
+	case SortFieldID, SortFieldCreatedAt, SortFieldUpdatedAt:
+		s.Field = raw
+	default:
+		return PostSort{}, ErrInvalidSort
+	}
+	return s, nil
+}
+
+func (s PostSort) String() string {
+	field := s.column()
+	if s.Desc {
+		return "-" + field
+	}
+	return field
+}
+
+func (s PostSort) column() string {
+	if s.Field == "" {
+		return SortFieldID
+	}
+	return s.Field
+}
+
+// lessはソート順でaがbより前に来る場合にtrueを返します。同値の場合はIDで順序を決めます。
+func (s PostSort) less(a, b model.Post) bool {
+	if s.column() != SortFieldID {
+		at, bt := s.timestamp(a), s.timestamp(b)
+		if !at.Equal(bt) {
+			if s.Desc {
+				return at.After(bt)
+			}
+			return at.Before(bt)
+		}
+	}
+	if s.Desc {
+		return a.ID > b.ID
+	}
+	return a.ID < b.ID
+}
+
+// timestampは時刻による並び替えで比較に使う値を返します。
+func (s PostSort) timestamp(post model.Post) time.Time {
+	switch s.column() {
+	case SortFieldUpdatedAt:
+		return post.UpdatedAt
+	case sortFieldDeletedAt:
+		if post.DeletedAt != nil {
+			return *post.DeletedAt
+		}
+		return time.Time{}
+	}
+	return post.CreatedAt
+}
+
+func (f PostFilter) matches(post model.Post) bool {
+	if f.Status != "" && post.Status != f.Status {
+		return false
+	}
+	if f.Author != "" && post.Author != f.Author {
+		return false
+	}
+	if f.AuthorID != 0 && post.AuthorID != f.AuthorID {
+		return false
+	}
+	if f.Tag != "" && !hasTag(post, f.Tag) {
+		return false
+	}
+	if f.CreatedAfter != nil && !post.CreatedAt.After(*f.CreatedAfter) {
+		return false
+	}
+	if f.CreatedBefore != nil && !post.CreatedAt.Before(*f.CreatedBefore) {
+		return false
+	}
+	if f.UpdatedSince != nil && post.UpdatedAt.Before(*f.UpdatedSince) {
+		return false
+	}
+	return true
+}
+
+// postCursorはページ末尾の位置を表します。Sortが異なるクエリでは再利用できません。
+// Atは時刻で並び替えている場合の末尾Postの時刻です。
+type postCursor struct {
+	Sort string    `json:"s"`
+	ID   int64     `json:"id"`
+	At   time.Time `json:"at,omitempty"`
+}
+
+func (c postCursor) post() model.Post {
+	at := c.At
+	return model.Post{ID: c.ID, CreatedAt: c.At, UpdatedAt: c.At, DeletedAt: &at}
+}
+
+func encodeCursor(cur postCursor) string {
+	data, err := json.Marshal(cur)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(raw string, sort PostSort) (*postCursor, error) {
+	var cur postCursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cur); err != nil || cur.ID <= 0 || cur.Sort != sort.String() {
+		return nil, ErrInvalidCursor
+	}
+	return &cur, nil
+}
+
+// pageBoundsはクエリのLimitを正規化し、Cursorが指定されていればデコードして返します。
+func pageBounds(query PostQuery) (int, *postCursor, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+	if query.Cursor == "" {
+		return limit, nil, nil
+	}
+	cur, err := decodeCursor(query.Cursor, query.Sort)
+	if err != nil {
+		return 0, nil, err
+	}
+	return limit, cur, nil
+}
+
+// buildPageはlimit+1件まで取得した結果からページと次のカーソルを組み立てます。
+func buildPage(posts []model.Post, sort PostSort, limit int) PostPage {
+	if len(posts) <= limit {
+		return PostPage{Posts: posts}
+	}
+	posts = posts[:limit]
+	last := posts[len(posts)-1]
+	cur := postCursor{Sort: sort.String(), ID: last.ID}
+	if sort.column() != SortFieldID {
+		cur.At = sort.timestamp(last)
+	}
+	return PostPage{
+		Posts:      posts,
+		NextCursor: encodeCursor(cur),
+	}
+}
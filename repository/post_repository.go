@@ -5,28 +5,105 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"maps"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/kitakitabauer/gin-sample-app/model"
 )
 
-var ErrPostNotFound = errors.New("post not found")
+var (
+	ErrPostNotFound    = errors.New("post not found")
+	ErrVersionConflict = errors.New("post has been modified")
+	ErrPostNotInTrash  = errors.New("post is not in trash")
+	ErrSlugTaken       = errors.New("slug is already in use")
+
+	// errPostNotUpdatedはUpdateのUPDATE文が対象の行に一致しなかったことを表す内部用のエラーです。
+	errPostNotUpdated = errors.New("post was not updated")
+)
 
 // PostRepositoryはPostの永続化を抽象化するインターフェースです。
 type PostRepository interface {
 	Create(ctx context.Context, post model.Post) (model.Post, error)
 	FindAll(ctx context.Context) ([]model.Post, error)
+	FindPage(ctx context.Context, query PostQuery) (PostPage, error)
+	Search(ctx context.Context, query PostSearchQuery) ([]model.PostSearchHit, error)
 	FindByID(ctx context.Context, id int64) (model.Post, error)
+	FindBySlug(ctx context.Context, slug string) (model.Post, error)
+	SlugTaken(ctx context.Context, slug string, exceptID int64) (bool, error)
 	Update(ctx context.Context, id int64, update PostUpdate) (model.Post, error)
 	Delete(ctx context.Context, id int64) error
+	FindTrash(ctx context.Context, query PostQuery) (PostPage, error)
+	Restore(ctx context.Context, id int64) (model.Post, error)
+	Purge(ctx context.Context, id int64) error
+	PublishDue(ctx context.Context, now time.Time, actor string) (int64, error)
+	FindTags(ctx context.Context, status string) ([]model.Tag, error)
 }
 
+// PostUpdateは部分更新の内容です。ExpectedVersionを指定すると、
+// 現在のバージョンが一致する場合にのみ更新し、一致しなければErrVersionConflictを返します。
+// Statusを指定した場合はPublishAtも合わせて書き込みます(nilの場合はNULLになります)。
+// Tagsを指定した場合は付与済みのタグをすべて置き換えます。
+// Slugを変更した場合、それまでのslugは履歴として残りFindBySlugで引き続き参照できます。
+// Authorを変更する場合はAuthorIDも合わせて指定します。
 type PostUpdate struct {
-	Title   *string
-	Content *string
-	Author  *string
+	Title           *string
+	Slug            *string
+	Content         *string
+	Author          *string
+	AuthorID        *int64
+	Status          *string
+	PublishAt       *time.Time
+	Tags            *[]string
+	UpdatedAt       time.Time
+	UpdatedBy       string
+	ExpectedVersion *int64
+}
+
+var postColumnNames = []string{"id", "title", "content", "author", "author_id", "owner_id", "created_at", "updated_at", "updated_by", "version", "slug", "status", "publish_at", "deleted_at"}
+
+// postColumnsはSELECT句に使うカラム一覧を返します。aliasを指定するとテーブル別名で修飾します。
+func postColumns(alias string) string {
+	if alias == "" {
+		return strings.Join(postColumnNames, ", ")
+	}
+	qualified := make([]string, len(postColumnNames))
+	for i, name := range postColumnNames {
+		qualified[i] = alias + "." + name
+	}
+	return strings.Join(qualified, ", ")
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPostはpostColumnsの順で1行を読み取ります。extraには後続の追加カラムの格納先を渡します。
+func scanPost(row rowScanner, extra ...any) (model.Post, error) {
+	var post model.Post
+	var updatedAt, publishAt, deletedAt sql.NullTime
+	var slug sql.NullString
+	var authorID, ownerID sql.NullInt64
+	dest := append([]any{&post.ID, &post.Title, &post.Content, &post.Author, &authorID, &ownerID, &post.CreatedAt, &updatedAt, &post.UpdatedBy, &post.Version, &slug, &post.Status, &publishAt, &deletedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return model.Post{}, err
+	}
+	post.Slug = slug.String
+	post.AuthorID = authorID.Int64
+	post.OwnerID = ownerID.Int64
+	post.UpdatedAt = post.CreatedAt
+	if updatedAt.Valid {
+		post.UpdatedAt = updatedAt.Time
+	}
+	if publishAt.Valid {
+		post.PublishAt = &publishAt.Time
+	}
+	if deletedAt.Valid {
+		post.DeletedAt = &deletedAt.Time
+	}
+	return post, nil
 }
 
 // SQLPostRepositoryはRDBを利用したPostRepositoryの実装です。
@@ -42,30 +119,59 @@ func NewSQLPostRepository(db *sql.DB, driver string) *SQLPostRepository {
 	}
 }
 
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLPostRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+// withDefaultsはStatus未指定のPostを下書きとして扱い、Tagsを空のスライスに揃えます。
+func withDefaults(post model.Post) model.Post {
+	if post.Status == "" {
+		post.Status = model.PostStatusDraft
+	}
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+	return post
+}
+
+// Createはpostとそのタグを1つのトランザクションで保存します。
 func (r *SQLPostRepository) Create(ctx context.Context, post model.Post) (model.Post, error) {
-	switch r.dialect {
-	case "postgres":
-		query := `INSERT INTO posts (title, content, author, created_at) VALUES ($1, $2, $3, $4) RETURNING id`
-		if err := r.db.QueryRowContext(ctx, query, post.Title, post.Content, post.Author, post.CreatedAt).Scan(&post.ID); err != nil {
-			return model.Post{}, err
+	post = withDefaults(post)
+
+	// slugが空の場合はNULLとして保存し、一意制約の対象から外します。
+	slug := sql.NullString{String: post.Slug, Valid: post.Slug != ""}
+	authorID := sql.NullInt64{Int64: post.AuthorID, Valid: post.AuthorID != 0}
+	ownerID := sql.NullInt64{Int64: post.OwnerID, Valid: post.OwnerID != 0}
+	args := []any{post.Title, slug, post.Content, post.Author, authorID, ownerID, post.CreatedAt, post.UpdatedAt, post.UpdatedBy, post.Status, post.PublishAt}
+	err := runInTx(ctx, r.db, r.dialect, func(tx sqlExecutor) error {
+		switch r.dialect {
+		case "postgres":
+			query := `INSERT INTO posts (title, slug, content, author, author_id, owner_id, created_at, updated_at, updated_by, status, publish_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+			if err := tx.QueryRowContext(ctx, query, args...).Scan(&post.ID); err != nil {
+				return slugError(err)
+			}
+		default:
+			res, err := tx.ExecContext(ctx, `INSERT INTO posts (title, slug, content, author, author_id, owner_id, created_at, updated_at, updated_by, status, publish_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+			if err != nil {
+				return slugError(err)
+			}
+			if post.ID, err = res.LastInsertId(); err != nil {
+				return err
+			}
 		}
-		return post, nil
-	default:
-		res, err := r.db.ExecContext(ctx, `INSERT INTO posts (title, content, author, created_at) VALUES (?, ?, ?, ?)`, post.Title, post.Content, post.Author, post.CreatedAt)
-		if err != nil {
-			return model.Post{}, err
-		}
-		id, err := res.LastInsertId()
-		if err != nil {
-			return model.Post{}, err
-		}
-		post.ID = id
-		return post, nil
+		return r.replaceTags(ctx, tx, post.ID, post.Tags)
+	})
+	if err != nil {
+		return model.Post{}, err
 	}
+
+	post.Version = 1
+	return post, nil
 }
 
 func (r *SQLPostRepository) FindAll(ctx context.Context) ([]model.Post, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, title, content, author, created_at FROM posts ORDER BY id`)
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT `+postColumns("")+` FROM posts WHERE deleted_at IS NULL ORDER BY id`)
 	if err != nil {
 		return nil, err
 	}
@@ -73,8 +179,8 @@ func (r *SQLPostRepository) FindAll(ctx context.Context) ([]model.Post, error) {
 
 	var posts []model.Post
 	for rows.Next() {
-		var post model.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.Author, &post.CreatedAt); err != nil {
+		post, err := scanPost(rows)
+		if err != nil {
 			return nil, err
 		}
 		posts = append(posts, post)
@@ -84,19 +190,121 @@ func (r *SQLPostRepository) FindAll(ctx context.Context) ([]model.Post, error) {
 		return nil, err
 	}
 
-	return posts, nil
+	return posts, r.attachTags(ctx, posts)
+}
+
+func (r *SQLPostRepository) FindPage(ctx context.Context, query PostQuery) (PostPage, error) {
+	limit, after, err := pageBounds(query)
+	if err != nil {
+		return PostPage{}, err
+	}
+
+	where, args := r.listConditions(query, after)
+	column := query.Sort.column()
+	direction := "ASC"
+	if query.Sort.Desc {
+		direction = "DESC"
+	}
+
+	q := `SELECT ` + postColumns("") + ` FROM posts WHERE ` + strings.Join(where, " AND ")
+	if column == SortFieldID {
+		q += fmt.Sprintf(" ORDER BY id %s", direction)
+	} else {
+		q += fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+	}
+	args = append(args, limit+1)
+	q += " LIMIT " + r.placeholder(len(args))
+
+	rows, err := r.conn(ctx).QueryContext(ctx, q, args...)
+	if err != nil {
+		return PostPage{}, err
+	}
+	defer rows.Close()
+
+	posts := make([]model.Post, 0, limit+1)
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return PostPage{}, err
+		}
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return PostPage{}, err
+	}
+	if err := r.attachTags(ctx, posts); err != nil {
+		return PostPage{}, err
+	}
+
+	return buildPage(posts, query.Sort, limit), nil
+}
+
+// listConditionsはフィルタとカーソルからWHERE句の条件とバインド引数を組み立てます。
+func (r *SQLPostRepository) listConditions(query PostQuery, after *postCursor) ([]string, []any) {
+	where := []string{"deleted_at IS NULL"}
+	var args []any
+	bind := func(v any) string {
+		args = append(args, v)
+		return r.placeholder(len(args))
+	}
+
+	filter := query.Filter
+	if filter.Status != "" {
+		where = append(where, "status = "+bind(filter.Status))
+	}
+	if filter.Author != "" {
+		where = append(where, "author = "+bind(filter.Author))
+	}
+	if filter.AuthorID != 0 {
+		where = append(where, "author_id = "+bind(filter.AuthorID))
+	}
+	if filter.Tag != "" {
+		where = append(where, `EXISTS (SELECT 1 FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+			WHERE pt.post_id = posts.id AND t.name = `+bind(filter.Tag)+`)`)
+	}
+	if filter.CreatedAfter != nil {
+		where = append(where, "created_at > "+bind(filter.CreatedAfter.UTC()))
+	}
+	if filter.CreatedBefore != nil {
+		where = append(where, "created_at < "+bind(filter.CreatedBefore.UTC()))
+	}
+	if filter.UpdatedSince != nil {
+		where = append(where, "updated_at >= "+bind(filter.UpdatedSince.UTC()))
+	}
+
+	if after != nil {
+		op := ">"
+		if query.Sort.Desc {
+			op = "<"
+		}
+		switch query.Sort.column() {
+		case SortFieldID:
+			where = append(where, fmt.Sprintf("id %s %s", op, bind(after.ID)))
+		default:
+			column := query.Sort.column()
+			where = append(where, fmt.Sprintf("(%s %s %s OR (%s = %s AND id %s %s))",
+				column, op, bind(after.At), column, bind(after.At), op, bind(after.ID)))
+		}
+	}
+
+	return where, args
 }
 
 func (r *SQLPostRepository) FindByID(ctx context.Context, id int64) (model.Post, error) {
-	query := fmt.Sprintf(`SELECT id, title, content, author, created_at FROM posts WHERE id = %s`, r.placeholder(1))
-	var post model.Post
-	if err := r.db.QueryRowContext(ctx, query, id).Scan(&post.ID, &post.Title, &post.Content, &post.Author, &post.CreatedAt); err != nil {
+	query := fmt.Sprintf(`SELECT %s FROM posts WHERE id = %s AND deleted_at IS NULL`, postColumns(""), r.placeholder(1))
+	post, err := scanPost(r.conn(ctx).QueryRowContext(ctx, query, id))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.Post{}, ErrPostNotFound
 		}
 		return model.Post{}, err
 	}
-	return post, nil
+	posts := []model.Post{post}
+	if err := r.attachTags(ctx, posts); err != nil {
+		return model.Post{}, err
+	}
+	return posts[0], nil
 }
 
 func (r *SQLPostRepository) Update(ctx context.Context, id int64, update PostUpdate) (model.Post, error) {
@@ -108,6 +316,11 @@ func (r *SQLPostRepository) Update(ctx context.Context, id int64, update PostUpd
 		sets = append(sets, fmt.Sprintf("title = %s", r.placeholder(idx)))
 		args = append(args, *update.Title)
 	}
+	if update.Slug != nil {
+		idx := len(args) + 1
+		sets = append(sets, fmt.Sprintf("slug = %s", r.placeholder(idx)))
+		args = append(args, *update.Slug)
+	}
 	if update.Content != nil {
 		idx := len(args) + 1
 		sets = append(sets, fmt.Sprintf("content = %s", r.placeholder(idx)))
@@ -118,15 +331,143 @@ func (r *SQLPostRepository) Update(ctx context.Context, id int64, update PostUpd
 		sets = append(sets, fmt.Sprintf("author = %s", r.placeholder(idx)))
 		args = append(args, *update.Author)
 	}
+	if update.AuthorID != nil {
+		idx := len(args) + 1
+		sets = append(sets, fmt.Sprintf("author_id = %s", r.placeholder(idx)))
+		args = append(args, *update.AuthorID)
+	}
+	if update.Status != nil {
+		args = append(args, *update.Status, update.PublishAt)
+		sets = append(sets, fmt.Sprintf("status = %s", r.placeholder(len(args)-1)), fmt.Sprintf("publish_at = %s", r.placeholder(len(args))))
+	}
 
-	if len(sets) == 0 {
-		return r.FindByID(ctx, id)
+	if len(sets) == 0 && update.Tags == nil {
+		post, err := r.FindByID(ctx, id)
+		if err != nil {
+			return model.Post{}, err
+		}
+		if update.ExpectedVersion != nil && post.Version != *update.ExpectedVersion {
+			return model.Post{}, ErrVersionConflict
+		}
+		return post, nil
 	}
 
+	args = append(args, update.UpdatedAt, update.UpdatedBy)
+	sets = append(sets, fmt.Sprintf("updated_at = %s", r.placeholder(len(args)-1)), fmt.Sprintf("updated_by = %s", r.placeholder(len(args))))
+
+	// バージョン確認と更新を1つのUPDATE文で行い、同時更新による上書きを防ぎます。
+	sets = append(sets, "version = version + 1")
 	args = append(args, id)
-	query := fmt.Sprintf("UPDATE posts SET %s WHERE id = %s", strings.Join(sets, ", "), r.placeholder(len(args)))
+	where := fmt.Sprintf("id = %s AND deleted_at IS NULL", r.placeholder(len(args)))
+	if update.ExpectedVersion != nil {
+		args = append(args, *update.ExpectedVersion)
+		where += fmt.Sprintf(" AND version = %s", r.placeholder(len(args)))
+	}
+	query := fmt.Sprintf("UPDATE posts SET %s WHERE %s", strings.Join(sets, ", "), where)
+
+	// タグの置き換えも同じトランザクションで行い、記事とタグの不整合を防ぎます。
+	err := runInTx(ctx, r.db, r.dialect, func(tx sqlExecutor) error {
+		if update.Slug != nil {
+			if err := r.keepSlugHistory(ctx, tx, id, *update.Slug, update.UpdatedAt); err != nil {
+				return err
+			}
+		}
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return slugError(err)
+		}
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rowsAffected == 0 {
+			// 更新しなかった場合は記録済みのslug履歴も残さないよう、エラーを返してロールバックします。
+			return errPostNotUpdated
+		}
+		if update.Tags != nil {
+			return r.replaceTags(ctx, tx, id, *update.Tags)
+		}
+		return nil
+	})
+	if errors.Is(err, errPostNotUpdated) {
+		if update.ExpectedVersion == nil {
+			return model.Post{}, ErrPostNotFound
+		}
+		if _, err := r.FindByID(ctx, id); err != nil {
+			return model.Post{}, err
+		}
+		return model.Post{}, ErrVersionConflict
+	}
+	if err != nil {
+		return model.Post{}, err
+	}
+
+	return r.FindByID(ctx, id)
+}
+
+// Deleteはdeleted_atを設定してPostをゴミ箱へ移動します。行はPurgeするまで残ります。
+func (r *SQLPostRepository) Delete(ctx context.Context, id int64) error {
+	query := fmt.Sprintf("UPDATE posts SET deleted_at = %s WHERE id = %s AND deleted_at IS NULL", r.placeholder(1), r.placeholder(2))
+	res, err := r.conn(ctx).ExecContext(ctx, query, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// FindTrashはゴミ箱にあるPostを削除日時の新しい順にページ単位で返します。
+// queryはLimitとCursorのみを使い、FilterとSortは無視します。
+func (r *SQLPostRepository) FindTrash(ctx context.Context, query PostQuery) (PostPage, error) {
+	query = PostQuery{Sort: trashSort, Limit: query.Limit, Cursor: query.Cursor}
+	limit, after, err := pageBounds(query)
+	if err != nil {
+		return PostPage{}, err
+	}
+
+	q := `SELECT ` + postColumns("") + ` FROM posts WHERE deleted_at IS NOT NULL`
+	var args []any
+	if after != nil {
+		args = append(args, after.At, after.At, after.ID)
+		q += fmt.Sprintf(" AND (deleted_at < %s OR (deleted_at = %s AND id < %s))", r.placeholder(1), r.placeholder(2), r.placeholder(3))
+	}
+	args = append(args, limit+1)
+	q += " ORDER BY deleted_at DESC, id DESC LIMIT " + r.placeholder(len(args))
+
+	rows, err := r.conn(ctx).QueryContext(ctx, q, args...)
+	if err != nil {
+		return PostPage{}, err
+	}
+	defer rows.Close()
+
+	posts := make([]model.Post, 0, limit+1)
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return PostPage{}, err
+		}
+		posts = append(posts, post)
+	}
+
+	if err := rows.Err(); err != nil {
+		return PostPage{}, err
+	}
+	if err := r.attachTags(ctx, posts); err != nil {
+		return PostPage{}, err
+	}
+
+	return buildPage(posts, query.Sort, limit), nil
+}
 
-	res, err := r.db.ExecContext(ctx, query, args...)
+func (r *SQLPostRepository) Restore(ctx context.Context, id int64) (model.Post, error) {
+	query := fmt.Sprintf("UPDATE posts SET deleted_at = NULL WHERE id = %s AND deleted_at IS NOT NULL", r.placeholder(1))
+	res, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return model.Post{}, err
 	}
@@ -135,15 +476,15 @@ func (r *SQLPostRepository) Update(ctx context.Context, id int64, update PostUpd
 		return model.Post{}, err
 	}
 	if rowsAffected == 0 {
-		return model.Post{}, ErrPostNotFound
+		return model.Post{}, r.trashMissError(ctx, id)
 	}
-
 	return r.FindByID(ctx, id)
 }
 
-func (r *SQLPostRepository) Delete(ctx context.Context, id int64) error {
-	query := fmt.Sprintf("DELETE FROM posts WHERE id = %s", r.placeholder(1))
-	res, err := r.db.ExecContext(ctx, query, id)
+// Purgeはゴミ箱にあるPostを物理削除します。
+func (r *SQLPostRepository) Purge(ctx context.Context, id int64) error {
+	query := fmt.Sprintf("DELETE FROM posts WHERE id = %s AND deleted_at IS NOT NULL", r.placeholder(1))
+	res, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -152,11 +493,32 @@ func (r *SQLPostRepository) Delete(ctx context.Context, id int64) error {
 		return err
 	}
 	if rowsAffected == 0 {
-		return ErrPostNotFound
+		return r.trashMissError(ctx, id)
 	}
 	return nil
 }
 
+// PublishDueは公開日時を過ぎた予約投稿をまとめて公開し、公開した件数を返します。
+func (r *SQLPostRepository) PublishDue(ctx context.Context, now time.Time, actor string) (int64, error) {
+	query := fmt.Sprintf(`UPDATE posts SET status = %s, updated_at = %s, updated_by = %s, version = version + 1
+		WHERE status = %s AND publish_at <= %s AND deleted_at IS NULL`,
+		r.placeholder(1), r.placeholder(2), r.placeholder(3), r.placeholder(4), r.placeholder(5))
+	res, err := r.conn(ctx).ExecContext(ctx, query, model.PostStatusPublished, now, actor, model.PostStatusScheduled, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// trashMissErrorはゴミ箱に対象が無かった理由を判定します。
+// 削除されていないPostであればErrPostNotInTrash、存在しなければErrPostNotFoundを返します。
+func (r *SQLPostRepository) trashMissError(ctx context.Context, id int64) error {
+	if _, err := r.FindByID(ctx, id); err != nil {
+		return err
+	}
+	return ErrPostNotInTrash
+}
+
 func (r *SQLPostRepository) placeholder(idx int) string {
 	if r.dialect == "postgres" {
 		return fmt.Sprintf("$%d", idx)
@@ -175,15 +537,35 @@ func detectDialect(driver string) string {
 
 // InMemoryPostRepositoryはPostRepositoryのメモリ上の実装です。
 type InMemoryPostRepository struct {
-	mu     sync.RWMutex
-	posts  map[int64]model.Post
-	nextID int64
+	mu    sync.RWMutex
+	posts map[int64]model.Post
+	// slugHistoryは変更前のslugから対象PostのIDへの対応です。
+	slugHistory map[string]int64
+	nextID      int64
 }
 
 func NewInMemoryPostRepository() *InMemoryPostRepository {
 	return &InMemoryPostRepository{
-		posts:  make(map[int64]model.Post),
-		nextID: 0,
+		posts:       make(map[int64]model.Post),
+		slugHistory: make(map[string]int64),
+		nextID:      0,
+	}
+}
+
+// SnapshotはInMemoryTxManagerで利用するため、現在のPostとslugの履歴を保存し、その状態に戻す関数を返します。
+func (r *InMemoryPostRepository) Snapshot() func() {
+	r.mu.RLock()
+	posts := maps.Clone(r.posts)
+	slugHistory := maps.Clone(r.slugHistory)
+	nextID := r.nextID
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.posts = posts
+		r.slugHistory = slugHistory
+		r.nextID = nextID
 	}
 }
 
@@ -191,8 +573,15 @@ func (r *InMemoryPostRepository) Create(_ context.Context, post model.Post) (mod
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if post.Slug != "" && r.slugInUse(post.Slug, 0) {
+		return model.Post{}, ErrSlugTaken
+	}
+
 	r.nextID++
+	post = withDefaults(post)
+	post.Tags = append([]string(nil), post.Tags...)
 	post.ID = r.nextID
+	post.Version = 1
 	r.posts[post.ID] = post
 
 	return post, nil
@@ -204,6 +593,9 @@ func (r *InMemoryPostRepository) FindAll(_ context.Context) ([]model.Post, error
 
 	result := make([]model.Post, 0, len(r.posts))
 	for _, post := range r.posts {
+		if post.DeletedAt != nil {
+			continue
+		}
 		result = append(result, post)
 	}
 
@@ -214,12 +606,47 @@ func (r *InMemoryPostRepository) FindAll(_ context.Context) ([]model.Post, error
 	return result, nil
 }
 
+func (r *InMemoryPostRepository) FindPage(ctx context.Context, query PostQuery) (PostPage, error) {
+	limit, after, err := pageBounds(query)
+	if err != nil {
+		return PostPage{}, err
+	}
+
+	all, err := r.FindAll(ctx)
+	if err != nil {
+		return PostPage{}, err
+	}
+
+	matched := make([]model.Post, 0, len(all))
+	for _, post := range all {
+		if query.Filter.matches(post) {
+			matched = append(matched, post)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		return query.Sort.less(matched[i], matched[j])
+	})
+
+	posts := make([]model.Post, 0, limit+1)
+	for _, post := range matched {
+		if after != nil && !query.Sort.less(after.post(), post) {
+			continue
+		}
+		posts = append(posts, post)
+		if len(posts) > limit {
+			break
+		}
+	}
+
+	return buildPage(posts, query.Sort, limit), nil
+}
+
 func (r *InMemoryPostRepository) FindByID(_ context.Context, id int64) (model.Post, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	post, ok := r.posts[id]
-	if !ok {
+	if !ok || post.DeletedAt != nil {
 		return model.Post{}, ErrPostNotFound
 	}
 
@@ -231,19 +658,49 @@ func (r *InMemoryPostRepository) Update(_ context.Context, id int64, update Post
 	defer r.mu.Unlock()
 
 	post, ok := r.posts[id]
-	if !ok {
+	if !ok || post.DeletedAt != nil {
 		return model.Post{}, ErrPostNotFound
 	}
+	if update.ExpectedVersion != nil && post.Version != *update.ExpectedVersion {
+		return model.Post{}, ErrVersionConflict
+	}
+
+	if update.Slug != nil && r.slugInUse(*update.Slug, id) {
+		return model.Post{}, ErrSlugTaken
+	}
 
+	hasChange := update.Title != nil || update.Slug != nil || update.Content != nil || update.Author != nil || update.AuthorID != nil || update.Status != nil || update.Tags != nil
 	if update.Title != nil {
 		post.Title = *update.Title
 	}
+	if update.Slug != nil && *update.Slug != post.Slug {
+		delete(r.slugHistory, *update.Slug)
+		if post.Slug != "" {
+			r.slugHistory[post.Slug] = id
+		}
+		post.Slug = *update.Slug
+	}
 	if update.Content != nil {
 		post.Content = *update.Content
 	}
 	if update.Author != nil {
 		post.Author = *update.Author
 	}
+	if update.AuthorID != nil {
+		post.AuthorID = *update.AuthorID
+	}
+	if update.Status != nil {
+		post.Status = *update.Status
+		post.PublishAt = update.PublishAt
+	}
+	if update.Tags != nil {
+		post.Tags = append([]string{}, *update.Tags...)
+	}
+	if hasChange {
+		post.UpdatedAt = update.UpdatedAt
+		post.UpdatedBy = update.UpdatedBy
+		post.Version++
+	}
 
 	r.posts[id] = post
 	return post, nil
@@ -253,10 +710,133 @@ func (r *InMemoryPostRepository) Delete(_ context.Context, id int64) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.posts[id]; !ok {
+	post, ok := r.posts[id]
+	if !ok || post.DeletedAt != nil {
 		return ErrPostNotFound
 	}
 
+	deletedAt := time.Now().UTC()
+	post.DeletedAt = &deletedAt
+	r.posts[id] = post
+	return nil
+}
+
+func (r *InMemoryPostRepository) FindTrash(_ context.Context, query PostQuery) (PostPage, error) {
+	query = PostQuery{Sort: trashSort, Limit: query.Limit, Cursor: query.Cursor}
+	limit, after, err := pageBounds(query)
+	if err != nil {
+		return PostPage{}, err
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	trashed := make([]model.Post, 0)
+	for _, post := range r.posts {
+		if post.DeletedAt != nil {
+			trashed = append(trashed, post)
+		}
+	}
+	sort.Slice(trashed, func(i, j int) bool {
+		return query.Sort.less(trashed[i], trashed[j])
+	})
+
+	posts := make([]model.Post, 0, limit+1)
+	for _, post := range trashed {
+		if after != nil && !query.Sort.less(after.post(), post) {
+			continue
+		}
+		posts = append(posts, post)
+		if len(posts) > limit {
+			break
+		}
+	}
+
+	return buildPage(posts, query.Sort, limit), nil
+}
+
+func (r *InMemoryPostRepository) Restore(_ context.Context, id int64) (model.Post, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return model.Post{}, ErrPostNotFound
+	}
+	if post.DeletedAt == nil {
+		return model.Post{}, ErrPostNotInTrash
+	}
+
+	post.DeletedAt = nil
+	r.posts[id] = post
+	return post, nil
+}
+
+func (r *InMemoryPostRepository) Purge(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return ErrPostNotFound
+	}
+	if post.DeletedAt == nil {
+		return ErrPostNotInTrash
+	}
+
 	delete(r.posts, id)
+	for slug, postID := range r.slugHistory {
+		if postID == id {
+			delete(r.slugHistory, slug)
+		}
+	}
 	return nil
 }
+
+// renameAuthorはInMemoryAuthorRepository.Renameから呼ばれ、authorIDの記事が保持する著者名を書き換えます。
+// 書き換えた記事はSQLの実装と同様にVersionとUpdatedAtを進めます。
+func (r *InMemoryPostRepository) renameAuthor(authorID int64, name string, updatedAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, post := range r.posts {
+		if post.AuthorID == authorID && post.Author != name {
+			post.Author = name
+			post.UpdatedAt = updatedAt
+			post.Version++
+			r.posts[id] = post
+		}
+	}
+}
+
+// hasAuthorPostsはゴミ箱にあるものも含めてauthorIDの記事があるかを返します。
+func (r *InMemoryPostRepository) hasAuthorPosts(authorID int64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, post := range r.posts {
+		if post.AuthorID == authorID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryPostRepository) PublishDue(_ context.Context, now time.Time, actor string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var published int64
+	for id, post := range r.posts {
+		if post.DeletedAt != nil || post.Status != model.PostStatusScheduled || post.PublishAt == nil || post.PublishAt.After(now) {
+			continue
+		}
+		post.Status = model.PostStatusPublished
+		post.UpdatedAt = now
+		post.UpdatedBy = actor
+		post.Version++
+		r.posts[id] = post
+		published++
+	}
+	return published, nil
+}
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	dbpkg "github.com/kitakitabauer/gin-sample-app/internal/database"
 	"github.com/kitakitabauer/gin-sample-app/model"
@@ -82,6 +84,179 @@ func TestSQLPostRepository_FindByID_NotFound(t *testing.T) {
 	}
 }
 
+func TestSQLPostRepository_FindPage(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		if _, err := repo.Create(ctx, model.Post{Title: "title", Content: "content", Author: "author"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	var ids []int64
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("pagination did not terminate")
+		}
+		page, err := repo.FindPage(ctx, PostQuery{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("FindPage returned error: %v", err)
+		}
+		for _, post := range page.Posts {
+			ids = append(ids, post.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(ids) != 5 {
+		t.Fatalf("expected 5 posts across pages, got %v", ids)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("expected ascending IDs, got %v", ids)
+		}
+	}
+
+	if _, err := repo.FindPage(ctx, PostQuery{Cursor: "%%%"}); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestSQLPostRepository_FindPage_FilterAndSort(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, author := range []string{"alice", "bob", "alice", "alice", "bob"} {
+		post := model.Post{Title: "title", Content: "content", Author: author, CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+		if _, err := repo.Create(ctx, post); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	after := base
+	query := PostQuery{
+		Filter: PostFilter{Author: "alice", CreatedAfter: &after},
+		Sort:   PostSort{Field: SortFieldCreatedAt, Desc: true},
+		Limit:  1,
+	}
+
+	var got []time.Time
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("pagination did not terminate")
+		}
+		page, err := repo.FindPage(ctx, query)
+		if err != nil {
+			t.Fatalf("FindPage returned error: %v", err)
+		}
+		for _, post := range page.Posts {
+			if post.Author != "alice" {
+				t.Fatalf("unexpected author %q", post.Author)
+			}
+			got = append(got, post.CreatedAt)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	want := []time.Time{base.Add(3 * time.Hour), base.Add(2 * time.Hour)}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d posts, got %v", len(want), got)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Fatalf("expected %v at %d, got %v", want[i], i, got[i])
+		}
+	}
+
+	query.Sort = PostSort{}
+	if _, err := repo.FindPage(ctx, query); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor when sort changes, got %v", err)
+	}
+}
+
+func TestSQLPostRepository_Search(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	golang, err := repo.Create(ctx, model.Post{Title: "Learning Go", Content: "Go makes concurrency approachable.", Author: "alice"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := repo.Create(ctx, model.Post{Title: "Rust notes", Content: "Ownership and borrowing.", Author: "bob"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	hits, err := repo.Search(ctx, PostSearchQuery{Text: "concurrency"})
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Post.ID != golang.ID {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if hits[0].Score <= 0 {
+		t.Fatalf("expected positive score, got %f", hits[0].Score)
+	}
+	if !strings.Contains(hits[0].Highlight.Content, "<mark>concurrency</mark>") {
+		t.Fatalf("expected highlighted snippet, got %q", hits[0].Highlight.Content)
+	}
+
+	newContent := "Now about generics."
+	if _, err := repo.Update(ctx, golang.ID, PostUpdate{Content: &newContent}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if hits, err := repo.Search(ctx, PostSearchQuery{Text: "concurrency"}); err != nil || len(hits) != 0 {
+		t.Fatalf("expected index to follow update, got %+v (err %v)", hits, err)
+	}
+
+	if err := repo.Delete(ctx, golang.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if hits, err := repo.Search(ctx, PostSearchQuery{Text: "generics"}); err != nil || len(hits) != 0 {
+		t.Fatalf("expected index to follow delete, got %+v (err %v)", hits, err)
+	}
+
+	if hits, err := repo.Search(ctx, PostSearchQuery{Text: `"unbalanced OR`}); err != nil || len(hits) != 0 {
+		t.Fatalf("expected operators to be treated as terms, got %+v (err %v)", hits, err)
+	}
+}
+
+func TestPostRepository_SearchEscapesHTML(t *testing.T) {
+	sqlRepo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for name, repo := range map[string]PostRepository{"sql": sqlRepo, "in-memory": NewInMemoryPostRepository()} {
+		post := model.Post{Title: "<b>xss</b> title", Content: `<script>alert(1)</script> xss <img src=x onerror="y">`, Author: "alice"}
+		if _, err := repo.Create(ctx, post); err != nil {
+			t.Fatalf("%s: Create returned error: %v", name, err)
+		}
+
+		hits, err := repo.Search(ctx, PostSearchQuery{Text: "xss"})
+		if err != nil || len(hits) != 1 {
+			t.Fatalf("%s: unexpected hits: %+v (err %v)", name, hits, err)
+		}
+		highlight := hits[0].Highlight
+		if highlight.Title != "&lt;b&gt;<mark>xss</mark>&lt;/b&gt; title" {
+			t.Fatalf("%s: expected an escaped title, got %q", name, highlight.Title)
+		}
+		if strings.Contains(highlight.Content, "<script") || strings.Contains(highlight.Content, "<img") || !strings.Contains(highlight.Content, "<mark>xss</mark>") {
+			t.Fatalf("%s: expected an escaped snippet, got %q", name, highlight.Content)
+		}
+	}
+}
+
 func TestSQLPostRepository_Update(t *testing.T) {
 	repo, cleanup := newTestSQLRepository(t)
 	defer cleanup()
@@ -94,7 +269,8 @@ func TestSQLPostRepository_Update(t *testing.T) {
 
 	newTitle := "new title"
 	newContent := "new content"
-	update := PostUpdate{Title: &newTitle, Content: &newContent}
+	updatedAt := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	update := PostUpdate{Title: &newTitle, Content: &newContent, UpdatedAt: updatedAt, UpdatedBy: "editor"}
 
 	updated, err := repo.Update(ctx, created.ID, update)
 	if err != nil {
@@ -104,12 +280,62 @@ func TestSQLPostRepository_Update(t *testing.T) {
 	if updated.Title != newTitle || updated.Content != newContent {
 		t.Fatalf("unexpected updated post: %+v", updated)
 	}
+	if !updated.UpdatedAt.Equal(updatedAt) || updated.UpdatedBy != "editor" {
+		t.Fatalf("expected modification to be tracked, got %+v", updated)
+	}
+
+	page, err := repo.FindPage(ctx, PostQuery{Filter: PostFilter{UpdatedSince: &updatedAt}, Sort: PostSort{Field: SortFieldUpdatedAt, Desc: true}})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != created.ID {
+		t.Fatalf("expected updated post in updated_since page, got %+v", page.Posts)
+	}
 
 	if _, err := repo.Update(ctx, created.ID+100, update); err != ErrPostNotFound {
 		t.Fatalf("expected ErrPostNotFound, got %v", err)
 	}
 }
 
+func TestSQLPostRepository_Update_ExpectedVersion(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	created, err := repo.Create(ctx, model.Post{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if created.Version != 1 {
+		t.Fatalf("expected initial version 1, got %d", created.Version)
+	}
+
+	title := "first"
+	updated, err := repo.Update(ctx, created.ID, PostUpdate{Title: &title, ExpectedVersion: &created.Version})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if updated.Version != 2 {
+		t.Fatalf("expected version 2, got %d", updated.Version)
+	}
+
+	stale := "stale"
+	if _, err := repo.Update(ctx, created.ID, PostUpdate{Title: &stale, ExpectedVersion: &created.Version}); err != ErrVersionConflict {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if _, err := repo.Update(ctx, created.ID+100, PostUpdate{Title: &stale, ExpectedVersion: &created.Version}); err != ErrPostNotFound {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+
+	found, err := repo.FindByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("FindByID returned error: %v", err)
+	}
+	if found.Title != title || found.Version != 2 {
+		t.Fatalf("unexpected post after conflict: %+v", found)
+	}
+}
+
 func TestSQLPostRepository_Delete(t *testing.T) {
 	repo, cleanup := newTestSQLRepository(t)
 	defer cleanup()
@@ -128,3 +354,477 @@ func TestSQLPostRepository_Delete(t *testing.T) {
 		t.Fatalf("expected ErrPostNotFound after delete, got %v", err)
 	}
 }
+
+func TestSQLPostRepository_SoftDelete(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	created, err := repo.Create(ctx, model.Post{Title: "trashable", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if _, err := repo.Restore(ctx, created.ID); err != ErrPostNotInTrash {
+		t.Fatalf("expected ErrPostNotInTrash, got %v", err)
+	}
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	if _, err := repo.FindByID(ctx, created.ID); err != ErrPostNotFound {
+		t.Fatalf("expected deleted post to be hidden, got %v", err)
+	}
+	if all, err := repo.FindAll(ctx); err != nil || len(all) != 0 {
+		t.Fatalf("expected FindAll to hide deleted post, got %+v (err %v)", all, err)
+	}
+	if hits, err := repo.Search(ctx, PostSearchQuery{Text: "trashable"}); err != nil || len(hits) != 0 {
+		t.Fatalf("expected Search to hide deleted post, got %+v (err %v)", hits, err)
+	}
+
+	trash, err := repo.FindTrash(ctx, PostQuery{})
+	if err != nil {
+		t.Fatalf("FindTrash returned error: %v", err)
+	}
+	if len(trash.Posts) != 1 || trash.Posts[0].ID != created.ID || trash.Posts[0].DeletedAt == nil || trash.NextCursor != "" {
+		t.Fatalf("unexpected trash: %+v", trash)
+	}
+
+	restored, err := repo.Restore(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+	if restored.DeletedAt != nil {
+		t.Fatalf("expected DeletedAt to be cleared, got %v", restored.DeletedAt)
+	}
+
+	if err := repo.Purge(ctx, created.ID); err != ErrPostNotInTrash {
+		t.Fatalf("expected ErrPostNotInTrash, got %v", err)
+	}
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := repo.Purge(ctx, created.ID); err != nil {
+		t.Fatalf("Purge returned error: %v", err)
+	}
+	if err := repo.Purge(ctx, created.ID); err != ErrPostNotFound {
+		t.Fatalf("expected ErrPostNotFound after purge, got %v", err)
+	}
+}
+
+func TestPostRepository_FindTrashPages(t *testing.T) {
+	sqlRepo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for name, repo := range map[string]PostRepository{"sql": sqlRepo, "in-memory": NewInMemoryPostRepository()} {
+		for i := 0; i < 5; i++ {
+			post, err := repo.Create(ctx, model.Post{Title: "title", Content: "content", Author: "author"})
+			if err != nil {
+				t.Fatalf("%s: Create returned error: %v", name, err)
+			}
+			if err := repo.Delete(ctx, post.ID); err != nil {
+				t.Fatalf("%s: Delete returned error: %v", name, err)
+			}
+		}
+
+		var trashed []model.Post
+		cursor := ""
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatalf("%s: pagination did not terminate", name)
+			}
+			page, err := repo.FindTrash(ctx, PostQuery{Limit: 2, Cursor: cursor})
+			if err != nil {
+				t.Fatalf("%s: FindTrash returned error: %v", name, err)
+			}
+			trashed = append(trashed, page.Posts...)
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+
+		if len(trashed) != 5 {
+			t.Fatalf("%s: expected 5 trashed posts across pages, got %+v", name, trashed)
+		}
+		for i := 1; i < len(trashed); i++ {
+			prev, cur := trashed[i-1], trashed[i]
+			if cur.DeletedAt.After(*prev.DeletedAt) || (cur.DeletedAt.Equal(*prev.DeletedAt) && cur.ID >= prev.ID) {
+				t.Fatalf("%s: expected newest deletions first, got %+v", name, trashed)
+			}
+		}
+
+		if _, err := repo.FindTrash(ctx, PostQuery{Cursor: "%%%"}); err != ErrInvalidCursor {
+			t.Fatalf("%s: expected ErrInvalidCursor, got %v", name, err)
+		}
+		page, err := repo.FindTrash(ctx, PostQuery{Limit: 2})
+		if err != nil {
+			t.Fatalf("%s: FindTrash returned error: %v", name, err)
+		}
+		if _, err := repo.FindPage(ctx, PostQuery{Cursor: page.NextCursor}); err != ErrInvalidCursor {
+			t.Fatalf("%s: expected a trash cursor to be rejected by FindPage, got %v", name, err)
+		}
+	}
+}
+
+func TestSQLPostRepository_StatusAndPublishDue(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	due := now.Add(-time.Minute)
+	later := now.Add(time.Hour)
+
+	draft, err := repo.Create(ctx, model.Post{Title: "draft", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if draft.Status != model.PostStatusDraft || draft.PublishAt != nil {
+		t.Fatalf("expected draft without publish_at, got %+v", draft)
+	}
+	dueScheduled, err := repo.Create(ctx, model.Post{Title: "due", Content: "c", Author: "a", Status: model.PostStatusScheduled, PublishAt: &due})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := repo.Create(ctx, model.Post{Title: "later", Content: "c", Author: "a", Status: model.PostStatusScheduled, PublishAt: &later}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	published, err := repo.PublishDue(ctx, now, "scheduler")
+	if err != nil {
+		t.Fatalf("PublishDue returned error: %v", err)
+	}
+	if published != 1 {
+		t.Fatalf("expected 1 post to be published, got %d", published)
+	}
+
+	found, err := repo.FindByID(ctx, dueScheduled.ID)
+	if err != nil {
+		t.Fatalf("FindByID returned error: %v", err)
+	}
+	if found.Status != model.PostStatusPublished || found.UpdatedBy != "scheduler" || found.Version != 2 {
+		t.Fatalf("unexpected published post: %+v", found)
+	}
+	if found.PublishAt == nil || !found.PublishAt.Equal(due) {
+		t.Fatalf("expected publish_at %v to be kept, got %v", due, found.PublishAt)
+	}
+
+	page, err := repo.FindPage(ctx, PostQuery{Filter: PostFilter{Status: model.PostStatusPublished}})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != dueScheduled.ID {
+		t.Fatalf("expected only the published post, got %+v", page.Posts)
+	}
+	if hits, err := repo.Search(ctx, PostSearchQuery{Text: "draft", Status: model.PostStatusPublished}); err != nil || len(hits) != 0 {
+		t.Fatalf("expected Search to skip drafts, got %+v (err %v)", hits, err)
+	}
+
+	status := model.PostStatusDraft
+	updated, err := repo.Update(ctx, dueScheduled.ID, PostUpdate{Status: &status, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if updated.Status != model.PostStatusDraft || updated.PublishAt != nil {
+		t.Fatalf("expected status update to clear publish_at, got %+v", updated)
+	}
+}
+
+func TestSQLPostRepository_Tags(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	goPost, err := repo.Create(ctx, model.Post{Title: "go", Content: "c", Author: "a", Status: model.PostStatusPublished, Tags: []string{"gin", "go"}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	draft, err := repo.Create(ctx, model.Post{Title: "draft", Content: "c", Author: "a", Tags: []string{"go"}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	untagged, err := repo.Create(ctx, model.Post{Title: "untagged", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	found, err := repo.FindByID(ctx, goPost.ID)
+	if err != nil {
+		t.Fatalf("FindByID returned error: %v", err)
+	}
+	if strings.Join(found.Tags, ",") != "gin,go" {
+		t.Fatalf("unexpected tags: %v", found.Tags)
+	}
+	if found, err := repo.FindByID(ctx, untagged.ID); err != nil || found.Tags == nil || len(found.Tags) != 0 {
+		t.Fatalf("expected empty tags, got %v (err %v)", found.Tags, err)
+	}
+
+	page, err := repo.FindPage(ctx, PostQuery{Filter: PostFilter{Tag: "go"}})
+	if err != nil {
+		t.Fatalf("FindPage returned error: %v", err)
+	}
+	if len(page.Posts) != 2 || page.Posts[0].ID != goPost.ID || page.Posts[1].ID != draft.ID {
+		t.Fatalf("unexpected tagged posts: %+v", page.Posts)
+	}
+
+	tags, err := repo.FindTags(ctx, "")
+	if err != nil {
+		t.Fatalf("FindTags returned error: %v", err)
+	}
+	if len(tags) != 2 || tags[0] != (model.Tag{Name: "go", PostCount: 2}) || tags[1] != (model.Tag{Name: "gin", PostCount: 1}) {
+		t.Fatalf("unexpected tag counts: %+v", tags)
+	}
+	if tags, err := repo.FindTags(ctx, model.PostStatusPublished); err != nil || len(tags) != 2 || tags[0].PostCount != 1 {
+		t.Fatalf("unexpected published tag counts: %+v (err %v)", tags, err)
+	}
+
+	replaced := []string{"sql"}
+	updated, err := repo.Update(ctx, goPost.ID, PostUpdate{Tags: &replaced, UpdatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if strings.Join(updated.Tags, ",") != "sql" || updated.Version != 2 {
+		t.Fatalf("expected tags to be replaced with a version bump, got %+v", updated)
+	}
+
+	if err := repo.Delete(ctx, draft.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	tags, err = repo.FindTags(ctx, "")
+	if err != nil {
+		t.Fatalf("FindTags returned error: %v", err)
+	}
+	if len(tags) != 1 || tags[0].Name != "sql" {
+		t.Fatalf("expected trashed posts to be excluded from counts, got %+v", tags)
+	}
+}
+
+func TestSQLPostRepository_Slugs(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	created, err := repo.Create(ctx, model.Post{Title: "t", Slug: "first", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := repo.Create(ctx, model.Post{Title: "t", Slug: "first", Content: "c", Author: "a"}); err != ErrSlugTaken {
+		t.Fatalf("expected ErrSlugTaken, got %v", err)
+	}
+	other, err := repo.Create(ctx, model.Post{Title: "t", Slug: "other", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	renamed := "renamed"
+	if _, err := repo.Update(ctx, created.ID, PostUpdate{Slug: &renamed, UpdatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	found, err := repo.FindBySlug(ctx, "first")
+	if err != nil {
+		t.Fatalf("FindBySlug returned error: %v", err)
+	}
+	if found.ID != created.ID || found.Slug != "renamed" {
+		t.Fatalf("expected old slug to resolve to the current post, got %+v", found)
+	}
+	if taken, err := repo.SlugTaken(ctx, "first", other.ID); err != nil || !taken {
+		t.Fatalf("expected retired slug to be taken for other posts, got %v (err %v)", taken, err)
+	}
+	if taken, err := repo.SlugTaken(ctx, "first", created.ID); err != nil || taken {
+		t.Fatalf("expected retired slug to be free for its own post, got %v (err %v)", taken, err)
+	}
+
+	stale := int64(1)
+	back := "first"
+	if _, err := repo.Update(ctx, created.ID, PostUpdate{Slug: &back, UpdatedAt: time.Now().UTC(), ExpectedVersion: &stale}); err != ErrVersionConflict {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if found, err := repo.FindBySlug(ctx, "renamed"); err != nil || found.Slug != "renamed" {
+		t.Fatalf("expected failed update to leave slugs untouched, got %+v (err %v)", found, err)
+	}
+
+	taken := "other"
+	if _, err := repo.Update(ctx, created.ID, PostUpdate{Slug: &taken, UpdatedAt: time.Now().UTC()}); err != ErrSlugTaken {
+		t.Fatalf("expected ErrSlugTaken, got %v", err)
+	}
+
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, err := repo.FindBySlug(ctx, "renamed"); err != ErrPostNotFound {
+		t.Fatalf("expected trashed post to be hidden, got %v", err)
+	}
+	if err := repo.Purge(ctx, created.ID); err != nil {
+		t.Fatalf("Purge returned error: %v", err)
+	}
+	if taken, err := repo.SlugTaken(ctx, "first", other.ID); err != nil || taken {
+		t.Fatalf("expected purge to release slug history, got %v (err %v)", taken, err)
+	}
+}
+
+func TestSQLPostRepository_FailedSlugUpdateKeepsNoHistory(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	created, err := repo.Create(ctx, model.Post{Title: "t", Slug: "first", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	stale := created.Version + 1
+	second := "second"
+	if _, err := repo.Update(ctx, created.ID, PostUpdate{Slug: &second, UpdatedAt: time.Now().UTC(), ExpectedVersion: &stale}); err != ErrVersionConflict {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+	if _, err := repo.Update(ctx, created.ID+1, PostUpdate{Slug: &second, UpdatedAt: time.Now().UTC()}); err != ErrPostNotFound {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+	var count int
+	if err := repo.db.QueryRow("SELECT COUNT(*) FROM post_slugs").Scan(&count); err != nil {
+		t.Fatalf("failed to count slug history: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected failed updates to record no slug history, got %d rows", count)
+	}
+
+	updated, err := repo.Update(ctx, created.ID, PostUpdate{Slug: &second, UpdatedAt: time.Now().UTC(), ExpectedVersion: &created.Version})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if updated.Slug != "second" {
+		t.Fatalf("expected the rename to apply, got %+v", updated)
+	}
+	first := "first"
+	if _, err := repo.Update(ctx, created.ID, PostUpdate{Slug: &first, UpdatedAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("expected renaming back to the original slug to succeed, got %v", err)
+	}
+}
+
+func TestSQLTxManager_WithinTx(t *testing.T) {
+	repo, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	existing, err := repo.Create(ctx, model.Post{Title: "existing", Content: "content", Author: "author", Tags: []string{"go"}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	revisions := NewSQLRevisionRepository(repo.db, "sqlite")
+	txm := NewSQLTxManager(repo.db)
+	errAbort := errors.New("abort")
+	var created model.Post
+	err = txm.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if created, err = repo.Create(ctx, model.Post{Title: "in tx", Content: "content", Author: "author", Tags: []string{"tx"}}); err != nil {
+			t.Fatalf("Create in tx returned error: %v", err)
+		}
+		title := "renamed"
+		if _, err := repo.Update(ctx, existing.ID, PostUpdate{Title: &title, Tags: &[]string{}}); err != nil {
+			t.Fatalf("Update in tx returned error: %v", err)
+		}
+		if _, err := revisions.Create(ctx, model.PostRevision{PostID: existing.ID, Revision: 1, Title: "existing", Content: "content", Author: "author"}); err != nil {
+			t.Fatalf("revision Create in tx returned error: %v", err)
+		}
+		if err := repo.Delete(ctx, 999); err != ErrPostNotFound {
+			t.Fatalf("expected ErrPostNotFound, got %v", err)
+		}
+		// ネストしたWithinTxは同じトランザクションに参加します。
+		return txm.WithinTx(ctx, func(ctx context.Context) error {
+			// トランザクション内の読み込みには未コミットの変更が反映されます。
+			found, err := repo.FindByID(ctx, created.ID)
+			if err != nil || len(found.Tags) != 1 || found.Tags[0] != "tx" {
+				t.Fatalf("expected the uncommitted post in tx, got %+v, %v", found, err)
+			}
+			return errAbort
+		})
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected the error from fn, got %v", err)
+	}
+
+	if _, err := repo.FindByID(ctx, created.ID); err != ErrPostNotFound {
+		t.Fatalf("expected rolled back post to be missing, got %v", err)
+	}
+	found, err := repo.FindByID(ctx, existing.ID)
+	if err != nil {
+		t.Fatalf("FindByID returned error: %v", err)
+	}
+	if found.Title != "existing" || found.Version != 1 || len(found.Tags) != 1 {
+		t.Fatalf("expected the update to be rolled back, got %+v", found)
+	}
+	if revs, err := revisions.FindByPost(ctx, existing.ID); err != nil || len(revs) != 0 {
+		t.Fatalf("expected the revision to be rolled back, got %+v (err %v)", revs, err)
+	}
+
+	err = txm.WithinTx(ctx, func(ctx context.Context) error {
+		_, err := repo.Create(ctx, model.Post{Title: "committed", Content: "content", Author: "author"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithinTx returned error: %v", err)
+	}
+	if posts, err := repo.FindAll(ctx); err != nil || len(posts) != 2 {
+		t.Fatalf("expected the committed post, got %d posts (err %v)", len(posts), err)
+	}
+}
+
+func TestInMemoryTxManager_WithinTx(t *testing.T) {
+	repo := NewInMemoryPostRepository()
+	revisions := NewInMemoryRevisionRepository()
+	txm := NewInMemoryTxManager(repo, revisions)
+
+	ctx := context.Background()
+	existing, err := repo.Create(ctx, model.Post{Title: "existing", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err = txm.WithinTx(ctx, func(ctx context.Context) error {
+		if _, err := repo.Create(ctx, model.Post{Title: "in tx", Content: "content", Author: "author"}); err != nil {
+			t.Fatalf("Create in tx returned error: %v", err)
+		}
+		slug := "renamed"
+		if _, err := repo.Update(ctx, existing.ID, PostUpdate{Slug: &slug}); err != nil {
+			t.Fatalf("Update in tx returned error: %v", err)
+		}
+		if _, err := revisions.Create(ctx, model.PostRevision{PostID: existing.ID, Revision: 1}); err != nil {
+			t.Fatalf("revision Create in tx returned error: %v", err)
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("expected the error from fn, got %v", err)
+	}
+
+	posts, _ := repo.FindAll(ctx)
+	if len(posts) != 1 || posts[0].Slug != existing.Slug || posts[0].Version != existing.Version {
+		t.Fatalf("expected the changes to be rolled back, got %+v", posts)
+	}
+	if _, err := repo.FindBySlug(ctx, "renamed"); err != ErrPostNotFound {
+		t.Fatalf("expected the slug change to be rolled back, got %v", err)
+	}
+	if revs, _ := revisions.FindByPost(ctx, existing.ID); len(revs) != 0 {
+		t.Fatalf("expected the revision to be rolled back, got %+v", revs)
+	}
+	created, err := repo.Create(ctx, model.Post{Title: "after", Content: "content", Author: "author"})
+	if err != nil || created.ID != existing.ID+1 {
+		t.Fatalf("expected IDs to be reused after rollback, got %+v (err %v)", created, err)
+	}
+
+	func() {
+		defer func() { _ = recover() }()
+		_ = txm.WithinTx(ctx, func(ctx context.Context) error {
+			_ = repo.Delete(ctx, existing.ID)
+			panic("boom")
+		})
+	}()
+	if _, err := repo.FindByID(ctx, existing.ID); err != nil {
+		t.Fatalf("expected a panic to roll back the delete, got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+const (
+	highlightStart  = "<mark>"
+	highlightEnd    = "</mark>"
+	snippetEllipsis = "…"
+	// matchStartとmatchEndはデータベースが一致箇所に付ける目印です。本文をHTMLエスケープした後で
+	// <mark>に置き換えるため、本文に含まれるHTMLがそのまま返ることはありません。
+	// どちらも制御文字で、入力の検証で拒否されるため保存された本文には含まれません。
+	matchStart = "\x02"
+	matchEnd   = "\x03"
+	// snippetTokensは本文の抜粋に含める最大トークン数です。
+	snippetTokens = 16
+)
+
+// PostSearchQueryは全文検索の条件です。Textは空白区切りの語をすべて含むPostに一致します。
+// Statusを指定するとその公開状態のPostに限定します。
+type PostSearchQuery struct {
+	Text   string
+	Status string
+	Limit  int
+}
+
+// searchTermsは検索文字列を小文字化した語に分割します。
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// ftsMatchExprは各語をダブルクォートで囲み、FTS5の構文として解釈されないようにします。
+func ftsMatchExpr(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+func (r *SQLPostRepository) Search(ctx context.Context, query PostSearchQuery) ([]model.PostSearchHit, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return []model.PostSearchHit{}, nil
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+
+	var q string
+	var args []any
+	switch r.dialect {
+	case "postgres":
+		q = `SELECT ` + postColumns("p") + `,
+			ts_rank(p.search_vector, q) AS score,
+			ts_headline('simple', p.title, q, $4),
+			ts_headline('simple', p.content, q, $5)
+		FROM posts p, to_tsquery('simple', $1) q
+		WHERE p.search_vector @@ q AND p.deleted_at IS NULL AND ($2::text = '' OR p.status = $2::text)
+		ORDER BY score DESC, p.id
+		LIMIT $3`
+		selectors := fmt.Sprintf("StartSel=%s, StopSel=%s", matchStart, matchEnd)
+		args = []any{tsQueryExpr(terms), query.Status, limit, selectors + ", HighlightAll=true", selectors + ", MaxFragments=1, MaxWords=16, MinWords=5"}
+	default:
+		q = fmt.Sprintf(`SELECT %s,
+			-bm25(posts_fts, 2.0, 1.0) AS score,
+			highlight(posts_fts, 0, ?, ?),
+			snippet(posts_fts, 1, ?, ?, ?, %d)
+		FROM posts_fts
+		JOIN posts p ON p.id = posts_fts.rowid
+		WHERE posts_fts MATCH ? AND p.deleted_at IS NULL AND (? = '' OR p.status = ?)
+		ORDER BY score DESC, p.id
+		LIMIT ?`, postColumns("p"), snippetTokens)
+		args = []any{matchStart, matchEnd, matchStart, matchEnd, snippetEllipsis, ftsMatchExpr(terms), query.Status, query.Status, limit}
+	}
+
+	rows, err := r.conn(ctx).QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := make([]model.PostSearchHit, 0, limit)
+	for rows.Next() {
+		var hit model.PostSearchHit
+		post, err := scanPost(rows, &hit.Score, &hit.Highlight.Title, &hit.Highlight.Content)
+		if err != nil {
+			return nil, err
+		}
+		hit.Post = post
+		hit.Highlight.Title = markMatches(hit.Highlight.Title)
+		hit.Highlight.Content = markMatches(hit.Highlight.Content)
+		hits = append(hits, hit)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	posts := make([]model.Post, len(hits))
+	for i, hit := range hits {
+		posts[i] = hit.Post
+	}
+	if err := r.attachTags(ctx, posts); err != nil {
+		return nil, err
+	}
+	for i := range hits {
+		hits[i].Post = posts[i]
+	}
+
+	return hits, nil
+}
+
+// markMatchesはデータベースが返した抜粋をHTMLエスケープし、一致箇所の目印を<mark>に置き換えます。
+func markMatches(text string) string {
+	text = html.EscapeString(text)
+	return strings.NewReplacer(matchStart, highlightStart, matchEnd, highlightEnd).Replace(text)
+}
+
+// tsQueryExprは各語をAND条件で結合したto_tsquery用の式を返します。
+func tsQueryExpr(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = "'" + strings.ReplaceAll(term, "'", "''") + "'"
+	}
+	return strings.Join(quoted, " & ")
+}
+
+func (r *InMemoryPostRepository) Search(ctx context.Context, query PostSearchQuery) ([]model.PostSearchHit, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return []model.PostSearchHit{}, nil
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultPageLimit
+	}
+
+	all, err := r.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	hits := make([]model.PostSearchHit, 0, limit)
+	for _, post := range all {
+		if query.Status != "" && post.Status != query.Status {
+			continue
+		}
+		titleCounts := countTerms(post.Title, wanted)
+		contentCounts := countTerms(post.Content, wanted)
+
+		var score float64
+		matchedAll := true
+		for term := range wanted {
+			n := 2*titleCounts[term] + contentCounts[term]
+			if n == 0 {
+				matchedAll = false
+				break
+			}
+			score += float64(n)
+		}
+		if !matchedAll {
+			continue
+		}
+
+		hits = append(hits, model.PostSearchHit{
+			Post:  post,
+			Score: score,
+			Highlight: model.PostHighlight{
+				Title:   highlightText(post.Title, wanted, 0),
+				Content: highlightText(post.Content, wanted, snippetTokens),
+			},
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits, nil
+}
+
+// textTokenはテキスト中の語とそのバイト位置です。
+type textToken struct {
+	start, end int
+	term       string
+}
+
+func tokenize(text string) []textToken {
+	var tokens []textToken
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		switch {
+		case isWord && start < 0:
+			start = i
+		case !isWord && start >= 0:
+			tokens = append(tokens, textToken{start: start, end: i, term: strings.ToLower(text[start:i])})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, textToken{start: start, end: len(text), term: strings.ToLower(text[start:])})
+	}
+	return tokens
+}
+
+func countTerms(text string, wanted map[string]bool) map[string]int {
+	counts := make(map[string]int)
+	for _, tok := range tokenize(text) {
+		if wanted[tok.term] {
+			counts[tok.term]++
+		}
+	}
+	return counts
+}
+
+// highlightTextはテキストをHTMLエスケープし、一致した語を<mark>で囲みます。
+// maxTokensが正の場合は最初の一致周辺の抜粋を返します。
+func highlightText(text string, wanted map[string]bool, maxTokens int) string {
+	tokens := tokenize(text)
+	from, to := 0, len(tokens)
+	if maxTokens > 0 && len(tokens) > maxTokens {
+		first := 0
+		for i, tok := range tokens {
+			if wanted[tok.term] {
+				first = i
+				break
+			}
+		}
+		from = first - maxTokens/4
+		if from < 0 {
+			from = 0
+		}
+		to = from + maxTokens
+		if to > len(tokens) {
+			to = len(tokens)
+			from = to - maxTokens
+		}
+	}
+	if from >= to {
+		return html.EscapeString(text)
+	}
+
+	var b strings.Builder
+	begin, finish := 0, len(text)
+	if from > 0 {
+		b.WriteString(snippetEllipsis)
+		begin = tokens[from].start
+	}
+	if to < len(tokens) {
+		finish = tokens[to-1].end
+	}
+
+	pos := begin
+	for _, tok := range tokens[from:to] {
+		if !wanted[tok.term] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:tok.start]))
+		b.WriteString(highlightStart)
+		b.WriteString(html.EscapeString(text[tok.start:tok.end]))
+		b.WriteString(highlightEnd)
+		pos = tok.end
+	}
+	b.WriteString(html.EscapeString(text[pos:finish]))
+	if to < len(tokens) {
+		b.WriteString(snippetEllipsis)
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+// FindBySlugは現在のslug、または変更前のslugに一致するPostを返します。
+// 呼び出し元は返されたPostのSlugと比較することで、古いslugでの参照かどうかを判定できます。
+func (r *SQLPostRepository) FindBySlug(ctx context.Context, slug string) (model.Post, error) {
+	query := fmt.Sprintf(`SELECT %s FROM posts WHERE deleted_at IS NULL AND (slug = %s
+		OR id = (SELECT post_id FROM post_slugs WHERE slug = %s))
+		ORDER BY CASE WHEN slug = %s THEN 0 ELSE 1 END
+		LIMIT 1`, postColumns(""), r.placeholder(1), r.placeholder(2), r.placeholder(3))
+	post, err := scanPost(r.conn(ctx).QueryRowContext(ctx, query, slug, slug, slug))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Post{}, ErrPostNotFound
+		}
+		return model.Post{}, err
+	}
+	posts := []model.Post{post}
+	if err := r.attachTags(ctx, posts); err != nil {
+		return model.Post{}, err
+	}
+	return posts[0], nil
+}
+
+// SlugTakenはslugがexceptID以外のPostの現在または過去のslugとして使われているかを返します。
+// ゴミ箱のPostのslugも使用中として扱います。
+func (r *SQLPostRepository) SlugTaken(ctx context.Context, slug string, exceptID int64) (bool, error) {
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM posts WHERE slug = %s AND id <> %s)
+		OR EXISTS (SELECT 1 FROM post_slugs WHERE slug = %s AND post_id <> %s)`,
+		r.placeholder(1), r.placeholder(2), r.placeholder(3), r.placeholder(4))
+	var taken bool
+	if err := r.conn(ctx).QueryRowContext(ctx, query, slug, exceptID, slug, exceptID).Scan(&taken); err != nil {
+		return false, err
+	}
+	return taken, nil
+}
+
+// keepSlugHistoryはslugを変更する前に現在のslugを履歴へ移します。
+// 以前使っていたslugへ戻す場合は、そのslugを履歴から取り除きます。
+func (r *SQLPostRepository) keepSlugHistory(ctx context.Context, tx sqlExecutor, id int64, slug string, changedAt time.Time) error {
+	var current sql.NullString
+	query := fmt.Sprintf("SELECT slug FROM posts WHERE id = %s AND deleted_at IS NULL", r.placeholder(1))
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&current); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// 対象が無い場合は後続のUPDATEでErrPostNotFoundを返します。
+			return nil
+		}
+		return err
+	}
+	if current.String == slug {
+		return nil
+	}
+
+	del := fmt.Sprintf("DELETE FROM post_slugs WHERE slug = %s AND post_id = %s", r.placeholder(1), r.placeholder(2))
+	if _, err := tx.ExecContext(ctx, del, slug, id); err != nil {
+		return err
+	}
+	if !current.Valid || current.String == "" {
+		return nil
+	}
+	insert := fmt.Sprintf("INSERT INTO post_slugs (slug, post_id, created_at) VALUES (%s, %s, %s)", r.placeholder(1), r.placeholder(2), r.placeholder(3))
+	_, err := tx.ExecContext(ctx, insert, current.String, id, changedAt)
+	return slugError(err)
+}
+
+// slugErrorは一意制約違反をErrSlugTakenに変換します。
+func slugError(err error) error {
+	if isUniqueViolation(err) {
+		return ErrSlugTaken
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "23505"
+	}
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+func (r *InMemoryPostRepository) FindBySlug(_ context.Context, slug string) (model.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, post := range r.posts {
+		if post.Slug == slug && post.DeletedAt == nil {
+			return post, nil
+		}
+	}
+	if id, ok := r.slugHistory[slug]; ok {
+		if post, ok := r.posts[id]; ok && post.DeletedAt == nil {
+			return post, nil
+		}
+	}
+	return model.Post{}, ErrPostNotFound
+}
+
+func (r *InMemoryPostRepository) SlugTaken(_ context.Context, slug string, exceptID int64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.slugInUse(slug, exceptID), nil
+}
+
+// slugInUseはr.muを保持した状態で呼び出します。
+func (r *InMemoryPostRepository) slugInUse(slug string, exceptID int64) bool {
+	for id, post := range r.posts {
+		if id != exceptID && post.Slug == slug {
+			return true
+		}
+	}
+	if id, ok := r.slugHistory[slug]; ok && id != exceptID {
+		return true
+	}
+	return false
+}
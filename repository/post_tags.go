@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+// replaceTagsはPostに付与されたタグをtagsで置き換えます。未登録のタグはtagsテーブルに追加します。
+func (r *SQLPostRepository) replaceTags(ctx context.Context, exec sqlExecutor, postID int64, tags []string) error {
+	if _, err := exec.ExecContext(ctx, "DELETE FROM post_tags WHERE post_id = "+r.placeholder(1), postID); err != nil {
+		return err
+	}
+
+	insertTag := fmt.Sprintf("INSERT INTO tags (name) VALUES (%s) ON CONFLICT (name) DO NOTHING", r.placeholder(1))
+	linkTag := fmt.Sprintf("INSERT INTO post_tags (post_id, tag_id) SELECT %s, id FROM tags WHERE name = %s", r.placeholder(1), r.placeholder(2))
+	for _, tag := range tags {
+		if _, err := exec.ExecContext(ctx, insertTag, tag); err != nil {
+			return err
+		}
+		if _, err := exec.ExecContext(ctx, linkTag, postID, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachTagsはpostsそれぞれのTagsを名前順で読み込みます。
+func (r *SQLPostRepository) attachTags(ctx context.Context, posts []model.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]int, len(posts))
+	placeholders := make([]string, len(posts))
+	args := make([]any, len(posts))
+	for i := range posts {
+		posts[i].Tags = []string{}
+		index[posts[i].ID] = i
+		placeholders[i] = r.placeholder(i + 1)
+		args[i] = posts[i].ID
+	}
+
+	query := `SELECT pt.post_id, t.name FROM post_tags pt JOIN tags t ON t.id = pt.tag_id
+		WHERE pt.post_id IN (` + strings.Join(placeholders, ", ") + `) ORDER BY t.name`
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return err
+		}
+		if i, ok := index[postID]; ok {
+			posts[i].Tags = append(posts[i].Tags, name)
+		}
+	}
+	return rows.Err()
+}
+
+// FindTagsはゴミ箱以外のPostに付与されているタグを件数の多い順に返します。
+// statusを指定するとその公開状態のPostのみを数えます。
+func (r *SQLPostRepository) FindTags(ctx context.Context, status string) ([]model.Tag, error) {
+	where := "p.deleted_at IS NULL"
+	var args []any
+	if status != "" {
+		where += " AND p.status = " + r.placeholder(1)
+		args = append(args, status)
+	}
+	query := `SELECT t.name, COUNT(*) FROM tags t
+		JOIN post_tags pt ON pt.tag_id = t.id
+		JOIN posts p ON p.id = pt.post_id
+		WHERE ` + where + `
+		GROUP BY t.name
+		ORDER BY COUNT(*) DESC, t.name`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]model.Tag, 0)
+	for rows.Next() {
+		var tag model.Tag
+		if err := rows.Scan(&tag.Name, &tag.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func hasTag(post model.Post, tag string) bool {
+	for _, t := range post.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *InMemoryPostRepository) FindTags(_ context.Context, status string) ([]model.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[string]int64)
+	for _, post := range r.posts {
+		if post.DeletedAt != nil || (status != "" && post.Status != status) {
+			continue
+		}
+		for _, tag := range post.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]model.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, model.Tag{Name: name, PostCount: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
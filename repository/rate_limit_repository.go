@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimitContentionは同じバケットへの更新が競合し続け、トークンを取り出せなかったことを表します。
+var ErrRateLimitContention = errors.New("rate limit bucket is too contended")
+
+// maxRateLimitAttemptsはSQLRateLimitRepositoryが競合時にやり直す回数です。
+const maxRateLimitAttempts = 5
+
+// RateLimitはトークンバケットの設定です。バケットはLimit個のトークンを保持でき、
+// Periodの間にLimit個のペースで補充されます。
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ratePerSecondは1秒あたりに補充されるトークン数です。
+func (l RateLimit) ratePerSecond() float64 {
+	return float64(l.Limit) / l.Period.Seconds()
+}
+
+// RateLimitResultはトークンを取り出した結果です。
+// RetryAfterは拒否された場合に次のトークンが補充されるまでの時間、ResetAfterはバケットが満杯に戻るまでの時間です。
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+// RateLimitRepositoryはトークンバケットの状態を保存するインターフェースです。
+type RateLimitRepository interface {
+	// Takeはbucketからトークンを1つ取り出します。トークンが無い場合はAllowedがfalseの結果を返します。
+	Take(ctx context.Context, bucket string, limit RateLimit, now time.Time) (RateLimitResult, error)
+	// DeleteRefilledはnowの時点で満杯に戻ったバケットを削除し、削除した件数を返します。
+	// 満杯のバケットは新しいバケットと同じ状態のため、削除しても制限の結果は変わりません。
+	DeleteRefilled(ctx context.Context, now time.Time) (int64, error)
+}
+
+// takeTokenはrefilledAtからnowまでの分を補充したうえでトークンを1つ取り出し、
+// 新しいトークン数と補充日時を返します。時刻が巻き戻った場合は補充しません。
+func takeToken(tokens float64, refilledAt time.Time, limit RateLimit, now time.Time) (float64, time.Time, RateLimitResult) {
+	capacity := float64(limit.Limit)
+	rate := limit.ratePerSecond()
+	if now.After(refilledAt) {
+		tokens = math.Min(capacity, tokens+now.Sub(refilledAt).Seconds()*rate)
+		refilledAt = now
+	}
+
+	result := RateLimitResult{Limit: limit.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = secondsToDuration((capacity - tokens) / rate)
+	return tokens, refilledAt, result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
+
+// SQLRateLimitRepositoryはRDBを利用したRateLimitRepositoryの実装です。
+// 同じDBを使う複数のプロセスでバケットを共有します。行ロックの代わりにversionによる楽観的な更新を行うため、
+// sqliteとPostgresの両方で同じ手順で動作します。
+type SQLRateLimitRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+func NewSQLRateLimitRepository(db *sql.DB, driver string) *SQLRateLimitRepository {
+	return &SQLRateLimitRepository{
+		db:      db,
+		dialect: detectDialect(driver),
+	}
+}
+
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLRateLimitRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+func (r *SQLRateLimitRepository) Take(ctx context.Context, bucket string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	selectQuery := fmt.Sprintf("SELECT tokens, refilled_at, version FROM rate_limits WHERE bucket = %s", r.placeholder(1))
+	insertQuery := fmt.Sprintf("INSERT INTO rate_limits (bucket, tokens, refilled_at, full_at, version) VALUES (%s, %s, %s, %s, 1) ON CONFLICT (bucket) DO NOTHING",
+		r.placeholder(1), r.placeholder(2), r.placeholder(3), r.placeholder(4))
+	updateQuery := fmt.Sprintf("UPDATE rate_limits SET tokens = %s, refilled_at = %s, full_at = %s, version = version + 1 WHERE bucket = %s AND version = %s",
+		r.placeholder(1), r.placeholder(2), r.placeholder(3), r.placeholder(4), r.placeholder(5))
+
+	for attempt := 0; attempt < maxRateLimitAttempts; attempt++ {
+		var (
+			tokens     float64
+			refilledAt time.Time
+			version    int64
+		)
+		err := r.conn(ctx).QueryRowContext(ctx, selectQuery, bucket).Scan(&tokens, &refilledAt, &version)
+		if errors.Is(err, sql.ErrNoRows) {
+			// 新しいバケットは満杯から始めます。同時に作成された場合は作成済みの行でやり直します。
+			tokens, refilledAt, result := takeToken(float64(limit.Limit), now, limit, now)
+			res, err := r.conn(ctx).ExecContext(ctx, insertQuery, bucket, tokens, refilledAt.UTC(), refilledAt.Add(result.ResetAfter).UTC())
+			if err != nil {
+				return RateLimitResult{}, err
+			}
+			if inserted, err := res.RowsAffected(); err != nil {
+				return RateLimitResult{}, err
+			} else if inserted == 1 {
+				return result, nil
+			}
+			continue
+		}
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+
+		tokens, refilledAt, result := takeToken(tokens, refilledAt, limit, now)
+		res, err := r.conn(ctx).ExecContext(ctx, updateQuery, tokens, refilledAt.UTC(), refilledAt.Add(result.ResetAfter).UTC(), bucket, version)
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		updated, err := res.RowsAffected()
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		if updated == 1 {
+			return result, nil
+		}
+	}
+	return RateLimitResult{}, ErrRateLimitContention
+}
+
+// DeleteRefilledはfull_atがnow以前のバケットを削除します。
+// 削除と同時に別のプロセスが同じバケットを更新した場合、その更新はversionの不一致でやり直しになり、満杯のバケットとして作り直されます。
+func (r *SQLRateLimitRepository) DeleteRefilled(ctx context.Context, now time.Time) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM rate_limits WHERE full_at <= %s", r.placeholder(1))
+	res, err := r.conn(ctx).ExecContext(ctx, query, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (r *SQLRateLimitRepository) placeholder(idx int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", idx)
+	}
+	return "?"
+}
+
+type tokenBucket struct {
+	tokens     float64
+	refilledAt time.Time
+	period     time.Duration
+}
+
+// InMemoryRateLimitRepositoryはRateLimitRepositoryのメモリ上の実装です。バケットはプロセスごとに独立しています。
+// 満杯に戻ったバケットは保持する必要が無いため、定期的に削除します。
+type InMemoryRateLimitRepository struct {
+	mu        sync.Mutex
+	buckets   map[string]tokenBucket
+	lastSweep time.Time
+}
+
+func NewInMemoryRateLimitRepository() *InMemoryRateLimitRepository {
+	return &InMemoryRateLimitRepository{
+		buckets: make(map[string]tokenBucket),
+	}
+}
+
+// inMemoryRateLimitSweepIntervalは満杯に戻ったバケットを削除する間隔です。
+const inMemoryRateLimitSweepInterval = time.Minute
+
+func (r *InMemoryRateLimitRepository) Take(_ context.Context, bucket string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now.Sub(r.lastSweep) >= inMemoryRateLimitSweepInterval {
+		r.deleteRefilled(now)
+		r.lastSweep = now
+	}
+
+	b, ok := r.buckets[bucket]
+	if !ok {
+		b = tokenBucket{tokens: float64(limit.Limit), refilledAt: now}
+	}
+	tokens, refilledAt, result := takeToken(b.tokens, b.refilledAt, limit, now)
+	r.buckets[bucket] = tokenBucket{tokens: tokens, refilledAt: refilledAt, period: limit.Period}
+	return result, nil
+}
+
+func (r *InMemoryRateLimitRepository) DeleteRefilled(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deleteRefilled(now), nil
+}
+
+// deleteRefilledは満杯に戻ったバケットを削除します。r.muを保持した状態で呼び出します。
+func (r *InMemoryRateLimitRepository) deleteRefilled(now time.Time) int64 {
+	var deleted int64
+	for key, b := range r.buckets {
+		if now.Sub(b.refilledAt) >= b.period {
+			delete(r.buckets, key)
+			deleted++
+		}
+	}
+	return deleted
+}
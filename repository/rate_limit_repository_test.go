@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testRateLimitRepository(t *testing.T, repo RateLimitRepository) {
+	t.Helper()
+
+	ctx := context.Background()
+	limit := RateLimit{Limit: 2, Period: time.Minute}
+	now := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, want := range []int{1, 0} {
+		result, err := repo.Take(ctx, "client", limit, now)
+		if err != nil {
+			t.Fatalf("Take returned error: %v", err)
+		}
+		if !result.Allowed || result.Remaining != want || result.Limit != 2 {
+			t.Fatalf("request %d: unexpected result %+v", i, result)
+		}
+	}
+
+	result, err := repo.Take(ctx, "client", limit, now)
+	if err != nil {
+		t.Fatalf("Take returned error: %v", err)
+	}
+	if result.Allowed || result.RetryAfter != 30*time.Second || result.ResetAfter != time.Minute {
+		t.Fatalf("expected request to be limited for 30s, got %+v", result)
+	}
+
+	if other, err := repo.Take(ctx, "other", limit, now); err != nil || !other.Allowed {
+		t.Fatalf("expected buckets to be independent, got %+v (err=%v)", other, err)
+	}
+
+	// 30秒で1つ補充されます。
+	result, err = repo.Take(ctx, "client", limit, now.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("Take returned error: %v", err)
+	}
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected refilled token to be taken, got %+v", result)
+	}
+
+	// "other"は30秒後、"client"は90秒後に満杯に戻ります。
+	if deleted, err := repo.DeleteRefilled(ctx, now.Add(29*time.Second)); err != nil || deleted != 0 {
+		t.Fatalf("expected no bucket to be deleted yet, got %d (err=%v)", deleted, err)
+	}
+	if deleted, err := repo.DeleteRefilled(ctx, now.Add(90*time.Second)); err != nil || deleted != 2 {
+		t.Fatalf("expected both buckets to be deleted, got %d (err=%v)", deleted, err)
+	}
+	result, err = repo.Take(ctx, "client", limit, now.Add(90*time.Second))
+	if err != nil {
+		t.Fatalf("Take returned error: %v", err)
+	}
+	if !result.Allowed || result.Remaining != 1 {
+		t.Fatalf("expected a deleted bucket to start full, got %+v", result)
+	}
+}
+
+func TestInMemoryRateLimitRepository_Take(t *testing.T) {
+	testRateLimitRepository(t, NewInMemoryRateLimitRepository())
+}
+
+func TestSQLRateLimitRepository_Take(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	testRateLimitRepository(t, NewSQLRateLimitRepository(posts.db, "sqlite"))
+}
+
+func TestSQLRateLimitRepository_ConcurrentTake(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	repo := NewSQLRateLimitRepository(posts.db, "sqlite")
+
+	ctx := context.Background()
+	limit := RateLimit{Limit: 5, Period: time.Hour}
+	now := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := repo.Take(ctx, "client", limit, now)
+			if err != nil {
+				return
+			}
+			if result.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed > limit.Limit {
+		t.Fatalf("expected at most %d requests to be allowed, got %d", limit.Limit, allowed)
+	}
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// RevisionRepositoryはPostRevisionの永続化を抽象化するインターフェースです。
+// 履歴は追記のみで、更新・削除は行いません(Postの物理削除時に連動して削除されます)。
+type RevisionRepository interface {
+	Create(ctx context.Context, rev model.PostRevision) (model.PostRevision, error)
+	FindByPost(ctx context.Context, postID int64) ([]model.PostRevision, error)
+	Find(ctx context.Context, postID, revision int64) (model.PostRevision, error)
+}
+
+const revisionColumns = "id, post_id, revision, title, content, author, author_id, actor, created_at"
+
+// SQLRevisionRepositoryはRDBを利用したRevisionRepositoryの実装です。
+type SQLRevisionRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+func NewSQLRevisionRepository(db *sql.DB, driver string) *SQLRevisionRepository {
+	return &SQLRevisionRepository{
+		db:      db,
+		dialect: detectDialect(driver),
+	}
+}
+
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLRevisionRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+func (r *SQLRevisionRepository) Create(ctx context.Context, rev model.PostRevision) (model.PostRevision, error) {
+	authorID := sql.NullInt64{Int64: rev.AuthorID, Valid: rev.AuthorID != 0}
+	args := []any{rev.PostID, rev.Revision, rev.Title, rev.Content, rev.Author, authorID, rev.Actor, rev.CreatedAt}
+	switch r.dialect {
+	case "postgres":
+		query := `INSERT INTO post_revisions (post_id, revision, title, content, author, author_id, actor, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`
+		if err := r.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&rev.ID); err != nil {
+			return model.PostRevision{}, err
+		}
+		return rev, nil
+	default:
+		res, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO post_revisions (post_id, revision, title, content, author, author_id, actor, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, args...)
+		if err != nil {
+			return model.PostRevision{}, err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return model.PostRevision{}, err
+		}
+		rev.ID = id
+		return rev, nil
+	}
+}
+
+func (r *SQLRevisionRepository) FindByPost(ctx context.Context, postID int64) ([]model.PostRevision, error) {
+	query := fmt.Sprintf(`SELECT %s FROM post_revisions WHERE post_id = %s ORDER BY revision DESC`, revisionColumns, r.placeholder(1))
+	rows, err := r.conn(ctx).QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revs := make([]model.PostRevision, 0)
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revs, nil
+}
+
+func (r *SQLRevisionRepository) Find(ctx context.Context, postID, revision int64) (model.PostRevision, error) {
+	query := fmt.Sprintf(`SELECT %s FROM post_revisions WHERE post_id = %s AND revision = %s`, revisionColumns, r.placeholder(1), r.placeholder(2))
+	rev, err := scanRevision(r.conn(ctx).QueryRowContext(ctx, query, postID, revision))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.PostRevision{}, ErrRevisionNotFound
+		}
+		return model.PostRevision{}, err
+	}
+	return rev, nil
+}
+
+func (r *SQLRevisionRepository) placeholder(idx int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", idx)
+	}
+	return "?"
+}
+
+func scanRevision(row rowScanner) (model.PostRevision, error) {
+	var (
+		rev      model.PostRevision
+		authorID sql.NullInt64
+	)
+	if err := row.Scan(&rev.ID, &rev.PostID, &rev.Revision, &rev.Title, &rev.Content, &rev.Author, &authorID, &rev.Actor, &rev.CreatedAt); err != nil {
+		return model.PostRevision{}, err
+	}
+	rev.AuthorID = authorID.Int64
+	return rev, nil
+}
+
+// InMemoryRevisionRepositoryはRevisionRepositoryのメモリ上の実装です。
+type InMemoryRevisionRepository struct {
+	mu        sync.RWMutex
+	revisions map[int64][]model.PostRevision
+	nextID    int64
+}
+
+func NewInMemoryRevisionRepository() *InMemoryRevisionRepository {
+	return &InMemoryRevisionRepository{
+		revisions: make(map[int64][]model.PostRevision),
+	}
+}
+
+// SnapshotはInMemoryTxManagerで利用するため、現在の変更履歴を保存し、その状態に戻す関数を返します。
+func (r *InMemoryRevisionRepository) Snapshot() func() {
+	r.mu.RLock()
+	revisions := make(map[int64][]model.PostRevision, len(r.revisions))
+	for postID, revs := range r.revisions {
+		revisions[postID] = slices.Clone(revs)
+	}
+	nextID := r.nextID
+	r.mu.RUnlock()
+
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.revisions = revisions
+		r.nextID = nextID
+	}
+}
+
+func (r *InMemoryRevisionRepository) Create(_ context.Context, rev model.PostRevision) (model.PostRevision, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.revisions[rev.PostID] {
+		if existing.Revision == rev.Revision {
+			return model.PostRevision{}, fmt.Errorf("revision %d of post %d already exists", rev.Revision, rev.PostID)
+		}
+	}
+
+	r.nextID++
+	rev.ID = r.nextID
+	r.revisions[rev.PostID] = append(r.revisions[rev.PostID], rev)
+	return rev, nil
+}
+
+func (r *InMemoryRevisionRepository) FindByPost(_ context.Context, postID int64) ([]model.PostRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revs := make([]model.PostRevision, len(r.revisions[postID]))
+	copy(revs, r.revisions[postID])
+	sort.Slice(revs, func(i, j int) bool {
+		return revs[i].Revision > revs[j].Revision
+	})
+	return revs, nil
+}
+
+func (r *InMemoryRevisionRepository) Find(_ context.Context, postID, revision int64) (model.PostRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, rev := range r.revisions[postID] {
+		if rev.Revision == revision {
+			return rev, nil
+		}
+	}
+	return model.PostRevision{}, ErrRevisionNotFound
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+func TestSQLRevisionRepository_CreateAndFind(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	revisions := NewSQLRevisionRepository(posts.db, "sqlite")
+
+	ctx := context.Background()
+	post, err := posts.Create(ctx, model.Post{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	authors := NewSQLAuthorRepository(posts.db, "sqlite")
+	author, err := authors.Create(ctx, model.Author{Name: "author", CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("Create author returned error: %v", err)
+	}
+	for version := int64(1); version <= 2; version++ {
+		rev := model.PostRevision{PostID: post.ID, Revision: version, Title: "title", Content: "content", Author: "author", AuthorID: author.ID, Actor: "editor", CreatedAt: now}
+		if _, err := revisions.Create(ctx, rev); err != nil {
+			t.Fatalf("Create revision returned error: %v", err)
+		}
+	}
+
+	if _, err := revisions.Create(ctx, model.PostRevision{PostID: post.ID, Revision: 1, Title: "dup", Content: "dup", Author: "dup", Actor: "editor", CreatedAt: now}); err == nil {
+		t.Fatalf("expected duplicate revision to be rejected")
+	}
+
+	list, err := revisions.FindByPost(ctx, post.ID)
+	if err != nil {
+		t.Fatalf("FindByPost returned error: %v", err)
+	}
+	if len(list) != 2 || list[0].Revision != 2 || list[1].Revision != 1 {
+		t.Fatalf("unexpected revisions: %+v", list)
+	}
+
+	found, err := revisions.Find(ctx, post.ID, 1)
+	if err != nil {
+		t.Fatalf("Find returned error: %v", err)
+	}
+	if found.Actor != "editor" || found.AuthorID != author.ID || !found.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected revision: %+v", found)
+	}
+
+	// 著者を削除しても履歴は残り、AuthorIDだけが外れます。
+	if err := authors.Delete(ctx, author.ID); err != nil {
+		t.Fatalf("Delete author returned error: %v", err)
+	}
+	if found, err := revisions.Find(ctx, post.ID, 1); err != nil || found.AuthorID != 0 || found.Author != "author" {
+		t.Fatalf("expected the revision to keep only the author name, got %+v (err %v)", found, err)
+	}
+	if _, err := revisions.Find(ctx, post.ID, 99); err != ErrRevisionNotFound {
+		t.Fatalf("expected ErrRevisionNotFound, got %v", err)
+	}
+
+	if err := posts.Delete(ctx, post.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := posts.Purge(ctx, post.ID); err != nil {
+		t.Fatalf("Purge returned error: %v", err)
+	}
+	if list, err := revisions.FindByPost(ctx, post.ID); err != nil || len(list) != 0 {
+		t.Fatalf("expected revisions to be removed with the post, got %+v (err %v)", list, err)
+	}
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"github.com/kitakitabauer/gin-sample-app/logger"
+)
+
+const tracerName = "github.com/kitakitabauer/gin-sample-app/repository"
+
+// slowQueryThresholdはログに警告を記録するクエリの所要時間です。
+const slowQueryThreshold = 200 * time.Millisecond
+
+// tracedExecutorは発行したクエリごとにspanを記録し、時間のかかったクエリをログに記録するsqlExecutorです。
+// spanにはプレースホルダーを含むクエリのみを記録し、引数の値は記録しません。
+type tracedExecutor struct {
+	next    sqlExecutor
+	dialect string
+}
+
+func (e tracedExecutor) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := e.startSpan(ctx, query)
+	start := time.Now()
+
+	res, err := e.next.ExecContext(ctx, query, args...)
+	finishQuery(ctx, span, query, start, err)
+	return res, err
+}
+
+func (e tracedExecutor) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := e.startSpan(ctx, query)
+	start := time.Now()
+
+	rows, err := e.next.QueryContext(ctx, query, args...)
+	finishQuery(ctx, span, query, start, err)
+	return rows, err
+}
+
+func (e tracedExecutor) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := e.startSpan(ctx, query)
+	start := time.Now()
+
+	row := e.next.QueryRowContext(ctx, query, args...)
+	// 該当する行が無いことはエラーとして扱いません。
+	err := row.Err()
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	finishQuery(ctx, span, query, start, err)
+	return row
+}
+
+// startSpanはクエリの種類(SELECT・INSERTなど)を名前とするspanを開始します。
+func (e tracedExecutor) startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := "QUERY"
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	system := "sqlite"
+	if e.dialect == "postgres" {
+		system = "postgresql"
+	}
+	return otel.Tracer(tracerName).Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", system),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", query),
+		),
+	)
+}
+
+// finishQueryはerrがあればspanに記録してspanを終了します。
+// slowQueryThresholdより時間のかかったクエリは、リクエストIDとともに警告としてログに記録します。
+func finishQuery(ctx context.Context, span trace.Span, query string, start time.Time, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	if elapsed := time.Since(start); elapsed >= slowQueryThreshold {
+		logger.FromContext(ctx).Warn("slow query",
+			zap.String("query", query),
+			zap.Duration("duration", elapsed),
+		)
+	}
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// sqlExecutorは*sql.DBと*sql.Txに共通する操作です。
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// TxManagerは複数のリポジトリ操作を1つのトランザクション(Unit of Work)にまとめるためのインターフェースです。
+type TxManager interface {
+	// WithinTxはfnをトランザクション内で実行します。fnに渡されるctxを使ったリポジトリ操作はすべて
+	// 同じトランザクションで行われ、fnがエラーを返した場合やpanicした場合は取り消されます。
+	// 既にトランザクション中のctxで呼び出した場合は、そのトランザクションに参加します。
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// sqlTxKeyはSQLTxManagerがトランザクションをctxに格納する際のキーです。
+type sqlTxKey struct{}
+
+// sqlTxはctxに格納するトランザクションです。別のDBのリポジトリが誤って利用しないよう、開始したDBを合わせて保持します。
+type sqlTx struct {
+	db *sql.DB
+	tx *sql.Tx
+}
+
+// txFromContextはctxに格納されたdbのトランザクションを返します。
+func txFromContext(ctx context.Context, db *sql.DB) (*sql.Tx, bool) {
+	stx, ok := ctx.Value(sqlTxKey{}).(sqlTx)
+	if !ok || stx.db != db {
+		return nil, false
+	}
+	return stx.tx, true
+}
+
+// connForはクエリの発行先を返します。ctxにdbのトランザクションがある場合はそのトランザクションです。
+// 発行したクエリはそれぞれspanとして記録されます。
+func connFor(ctx context.Context, db *sql.DB, dialect string) sqlExecutor {
+	if tx, ok := txFromContext(ctx, db); ok {
+		return tracedExecutor{next: tx, dialect: dialect}
+	}
+	return tracedExecutor{next: db, dialect: dialect}
+}
+
+// runInTxはfnを1つのトランザクションで実行し、エラーが無ければコミットします。
+// ctxにdbのトランザクションがある場合はその中で実行し、コミットとロールバックは呼び出し元に任せます。
+func runInTx(ctx context.Context, db *sql.DB, dialect string, fn func(tx sqlExecutor) error) error {
+	if tx, ok := txFromContext(ctx, db); ok {
+		return fn(tracedExecutor{next: tx, dialect: dialect})
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tracedExecutor{next: tx, dialect: dialect}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SQLTxManagerはsql.Txを利用したTxManagerの実装です。
+// 同じdbを利用するSQLのリポジトリは、ctxにトランザクションがあれば自動的にその中で操作します。
+type SQLTxManager struct {
+	db *sql.DB
+}
+
+func NewSQLTxManager(db *sql.DB) *SQLTxManager {
+	return &SQLTxManager{db: db}
+}
+
+func (m *SQLTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := txFromContext(ctx, m.db); ok {
+		return fn(ctx)
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// fnがpanicした場合もロールバックされます。
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, sqlTxKey{}, sqlTx{db: m.db, tx: tx})); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Snapshotterはトランザクションの開始時点の状態を保存できるインメモリのリポジトリです。
+type Snapshotter interface {
+	// Snapshotは現在の状態を保存し、その状態に戻す関数を返します。
+	Snapshot() (restore func())
+}
+
+// inMemoryTxKeyはInMemoryTxManagerがトランザクション中であることをctxに記録する際のキーです。
+type inMemoryTxKey struct{}
+
+// InMemoryTxManagerはインメモリのリポジトリ向けのTxManagerの実装です。
+// 開始時に各リポジトリのスナップショットを取り、fnが失敗した場合はその状態に戻します。
+// トランザクション同士は直列に実行されますが、トランザクション外の操作からは分離されません。
+type InMemoryTxManager struct {
+	mu    sync.Mutex
+	repos []Snapshotter
+}
+
+func NewInMemoryTxManager(repos ...Snapshotter) *InMemoryTxManager {
+	return &InMemoryTxManager{repos: repos}
+}
+
+func (m *InMemoryTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if owner, ok := ctx.Value(inMemoryTxKey{}).(*InMemoryTxManager); ok && owner == m {
+		return fn(ctx)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restores := make([]func(), len(m.repos))
+	for i, repo := range m.repos {
+		restores[i] = repo.Snapshot()
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			for _, restore := range restores {
+				restore()
+			}
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, inMemoryTxKey{}, m)); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
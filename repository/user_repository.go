@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrUsernameTaken = errors.New("username is already in use")
+)
+
+// UserRepositoryはUserの永続化を抽象化するインターフェースです。
+// ユーザー名は大文字・小文字を区別せずに比較します。
+type UserRepository interface {
+	Create(ctx context.Context, user model.User) (model.User, error)
+	FindByID(ctx context.Context, id int64) (model.User, error)
+	FindByUsername(ctx context.Context, username string) (model.User, error)
+}
+
+const userColumns = "id, username, role, password_hash, created_at, updated_at"
+
+// SQLUserRepositoryはRDBを利用したUserRepositoryの実装です。
+type SQLUserRepository struct {
+	db      *sql.DB
+	dialect string
+}
+
+func NewSQLUserRepository(db *sql.DB, driver string) *SQLUserRepository {
+	return &SQLUserRepository{
+		db:      db,
+		dialect: detectDialect(driver),
+	}
+}
+
+// connはクエリの発行先を返します。ctxにTxManagerのトランザクションがある場合はそのトランザクションです。
+func (r *SQLUserRepository) conn(ctx context.Context) sqlExecutor {
+	return connFor(ctx, r.db, r.dialect)
+}
+
+func (r *SQLUserRepository) Create(ctx context.Context, user model.User) (model.User, error) {
+	args := []any{user.Username, user.Role, user.PasswordHash, user.CreatedAt, user.UpdatedAt}
+	switch r.dialect {
+	case "postgres":
+		query := `INSERT INTO users (username, role, password_hash, created_at, updated_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+		if err := r.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&user.ID); err != nil {
+			return model.User{}, usernameError(err)
+		}
+		return user, nil
+	default:
+		res, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO users (username, role, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`, args...)
+		if err != nil {
+			return model.User{}, usernameError(err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return model.User{}, err
+		}
+		user.ID = id
+		return user, nil
+	}
+}
+
+func (r *SQLUserRepository) FindByID(ctx context.Context, id int64) (model.User, error) {
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE id = %s`, userColumns, r.placeholder(1))
+	return r.findOne(ctx, query, id)
+}
+
+// FindByUsernameは大文字・小文字を区別せずにusernameと一致するUserを返します。
+func (r *SQLUserRepository) FindByUsername(ctx context.Context, username string) (model.User, error) {
+	// sqliteではusernameカラムのCOLLATE NOCASEにより大文字・小文字を区別せずに比較されます。
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = ?`
+	if r.dialect == "postgres" {
+		query = `SELECT ` + userColumns + ` FROM users WHERE LOWER(username) = LOWER($1)`
+	}
+	return r.findOne(ctx, query, username)
+}
+
+func (r *SQLUserRepository) findOne(ctx context.Context, query string, args ...any) (model.User, error) {
+	var user model.User
+	if err := r.conn(ctx).QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.Username, &user.Role, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, ErrUserNotFound
+		}
+		return model.User{}, err
+	}
+	return user, nil
+}
+
+func (r *SQLUserRepository) placeholder(idx int) string {
+	if r.dialect == "postgres" {
+		return fmt.Sprintf("$%d", idx)
+	}
+	return "?"
+}
+
+// usernameErrorは一意制約違反をErrUsernameTakenに変換します。
+func usernameError(err error) error {
+	if isUniqueViolation(err) {
+		return ErrUsernameTaken
+	}
+	return err
+}
+
+// InMemoryUserRepositoryはUserRepositoryのメモリ上の実装です。
+type InMemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[int64]model.User
+	nextID int64
+}
+
+func NewInMemoryUserRepository() *InMemoryUserRepository {
+	return &InMemoryUserRepository{
+		users: make(map[int64]model.User),
+	}
+}
+
+func (r *InMemoryUserRepository) Create(_ context.Context, user model.User) (model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.findByUsername(user.Username); ok {
+		return model.User{}, ErrUsernameTaken
+	}
+
+	r.nextID++
+	user.ID = r.nextID
+	r.users[user.ID] = user
+	return user, nil
+}
+
+func (r *InMemoryUserRepository) FindByID(_ context.Context, id int64) (model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return model.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *InMemoryUserRepository) FindByUsername(_ context.Context, username string) (model.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.findByUsername(username)
+	if !ok {
+		return model.User{}, ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (r *InMemoryUserRepository) findByUsername(username string) (model.User, bool) {
+	for _, user := range r.users {
+		if strings.EqualFold(user.Username, username) {
+			return user, true
+		}
+	}
+	return model.User{}, false
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+)
+
+func TestSQLUserRepository_CreateAndFind(t *testing.T) {
+	posts, cleanup := newTestSQLRepository(t)
+	defer cleanup()
+	users := NewSQLUserRepository(posts.db, "sqlite")
+
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	alice, err := users.Create(ctx, model.User{Username: "Alice", Role: model.UserRoleEditor, PasswordHash: "hash", CreatedAt: now, UpdatedAt: now})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := users.Create(ctx, model.User{Username: "alice", Role: model.UserRoleAuthor, PasswordHash: "hash", CreatedAt: now, UpdatedAt: now}); !errors.Is(err, ErrUsernameTaken) {
+		t.Fatalf("expected ErrUsernameTaken, got %v", err)
+	}
+
+	found, err := users.FindByUsername(ctx, "ALICE")
+	if err != nil {
+		t.Fatalf("FindByUsername returned error: %v", err)
+	}
+	if found.ID != alice.ID || found.PasswordHash != "hash" || found.Role != model.UserRoleEditor {
+		t.Fatalf("unexpected user: %+v", found)
+	}
+	if _, err := users.FindByID(ctx, alice.ID+1); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+
+	post, err := posts.Create(ctx, model.Post{Title: "title", Content: "content", Author: "author", OwnerID: alice.ID})
+	if err != nil {
+		t.Fatalf("Create post returned error: %v", err)
+	}
+	if post, err = posts.FindByID(ctx, post.ID); err != nil || post.OwnerID != alice.ID {
+		t.Fatalf("expected post owned by %d, got %+v (err=%v)", alice.ID, post, err)
+	}
+}
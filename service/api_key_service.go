@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+var (
+	ErrAPIKeyNameRequired = errors.New("api key name is required")
+	ErrScopesRequired     = errors.New("at least one scope is required")
+	ErrInvalidScope       = errors.New("scope must be one of posts:write, admin:log-level, admin:posts, admin:api-keys, admin:metrics")
+	ErrInvalidExpiry      = errors.New("expires_at must be in the future")
+	ErrInvalidAPIKey      = errors.New("invalid, expired or revoked api key")
+	ErrInvalidSignature   = errors.New("invalid request signature")
+)
+
+const (
+	// apiKeySecretPrefixはキー本体の先頭に付ける文字列です。漏えい時にこのAPIのキーだと判別できるようにします。
+	apiKeySecretPrefix = "gsk_"
+	apiKeySecretBytes  = 32
+	// apiKeyDisplayLengthは一覧に表示するキー先頭部分の長さです。
+	apiKeyDisplayLength = 12
+)
+
+// CreateAPIKeyInputはAPIキー作成の入力です。ExpiresAtがnilの場合は無期限です。
+type CreateAPIKeyInput struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type APIKeyService struct {
+	keys repository.APIKeyRepository
+	now  func() time.Time
+	// signingKeysはHMAC署名の鍵を保存時に暗号化します。SetEncryptionKeyで設定します。
+	signingKeys cipher.AEAD
+}
+
+// NewAPIKeyServiceはAPIKeyServiceを作成します。HMAC署名の鍵は起動ごとの乱数の鍵で暗号化するため、
+// 再起動後も署名を検証するにはSetEncryptionKeyで固定の鍵を設定してください。
+func NewAPIKeyService(keys repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{keys: keys, now: time.Now, signingKeys: signingKeyCipher([]byte(rand.Text()))}
+}
+
+// SetEncryptionKeyはHMAC署名の鍵を暗号化する鍵を設定します。鍵を変更すると、それ以前に発行したキーでは署名できなくなります。
+func (s *APIKeyService) SetEncryptionKey(secret []byte) {
+	s.signingKeys = signingKeyCipher(secret)
+}
+
+// signingKeyCipherはsecretのSHA-256を鍵とするAES-256-GCMを返します。
+// 鍵は常に32バイトのため、aes.NewCipherとcipher.NewGCMはエラーを返しません。
+func signingKeyCipher(secret []byte) cipher.AEAD {
+	sum := sha256.Sum256(secret)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		panic(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return aead
+}
+
+// HMACSigningKeyはキー本体からHMAC署名の鍵(16進数の文字列)を求めます。
+// クライアントも同じ計算で鍵を求めます。保存するハッシュ(HashAPIKey)とは異なる値のため、
+// データベースの内容だけでは署名できません。
+func HMACSigningKey(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("hmac-signing-key"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sealSigningKeyは署名の鍵を暗号化します。keyHashを追加データにするため、暗号文を別のキーへ移しても復号できません。
+func (s *APIKeyService) sealSigningKey(signingKey, keyHash string) (string, error) {
+	nonce := make([]byte, s.signingKeys.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.signingKeys.Seal(nonce, nonce, []byte(signingKey), []byte(keyHash))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openSigningKeyはsealSigningKeyで暗号化した署名の鍵を復号します。
+func (s *APIKeyService) openSigningKey(key model.APIKey) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(key.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	size := s.signingKeys.NonceSize()
+	if len(sealed) < size {
+		return nil, ErrInvalidSignature
+	}
+	return s.signingKeys.Open(nil, sealed[:size], sealed[size:], []byte(key.KeyHash))
+}
+
+// HashAPIKeyはキー本体を保存・検索用のハッシュに変換します。
+// キー本体は十分な長さの乱数のため、低速なハッシュは使わずSHA-256で比較します。
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateはAPIキーを作成し、キー本体を返します。キー本体は保存しないため、再表示はできません。
+func (s *APIKeyService) Create(ctx context.Context, input CreateAPIKeyInput) (model.APIKey, string, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return model.APIKey{}, "", ErrAPIKeyNameRequired
+	}
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	now := s.now().UTC()
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(now) {
+		return model.APIKey{}, "", ErrInvalidExpiry
+	}
+
+	raw := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return model.APIKey{}, "", err
+	}
+	secret := apiKeySecretPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	key := model.APIKey{
+		Name:      name,
+		Prefix:    secret[:apiKeyDisplayLength],
+		KeyHash:   HashAPIKey(secret),
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	key.SigningKey, err = s.sealSigningKey(HMACSigningKey(secret), key.KeyHash)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	if input.ExpiresAt != nil {
+		expiresAt := input.ExpiresAt.UTC()
+		key.ExpiresAt = &expiresAt
+	}
+	key, err = s.keys.Create(ctx, key)
+	if err != nil {
+		return model.APIKey{}, "", err
+	}
+	return key, secret, nil
+}
+
+// normalizeScopesは重複を除いたスコープを返します。未定義のスコープはErrInvalidScopeです。
+func normalizeScopes(scopes []string) ([]string, error) {
+	normalized := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !model.ValidAPIKeyScope(scope) {
+			return nil, ErrInvalidScope
+		}
+		if seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+	if len(normalized) == 0 {
+		return nil, ErrScopesRequired
+	}
+	return normalized, nil
+}
+
+// Listは失効済みのものも含めてAPIキーを作成順に返します。
+func (s *APIKeyService) List(ctx context.Context) ([]model.APIKey, error) {
+	return s.keys.FindAll(ctx)
+}
+
+// RevokeはAPIキーを失効させます。失効したキーは以降の認証で使えません。
+func (s *APIKeyService) Revoke(ctx context.Context, id int64) (model.APIKey, error) {
+	return s.keys.Revoke(ctx, id, s.now().UTC())
+}
+
+// AuthenticateAPIKeyはキー本体に一致する有効なAPIキーを返し、最終利用日時を記録します。
+// 存在しない・期限切れ・失効済みのキーはErrInvalidAPIKeyです。
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, secret string) (model.APIKey, error) {
+	hash := HashAPIKey(secret)
+	key, err := s.keys.FindByHash(ctx, hash)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return model.APIKey{}, err
+	}
+	// 検索はハッシュの一致で行いますが、比較自体も一定時間で行います。
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hash)) != 1 {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+
+	now := s.now().UTC()
+	if !key.Active(now) {
+		return model.APIKey{}, ErrInvalidAPIKey
+	}
+	if err := s.keys.Touch(ctx, key.ID, now); err != nil {
+		return model.APIKey{}, err
+	}
+	key.LastUsedAt = &now
+	return key, nil
+}
+
+// VerifySignatureはidのAPIキーでmessageに付けられたHMAC-SHA256の署名を検証し、APIキーを返します。
+// 署名の鍵はキー本体ではなくHMACSigningKeyの値(16進数の文字列)です。サーバーはこの値を暗号化して保存し、
+// クライアントはキー本体から同じ値を求めて署名します。
+// 存在しない・期限切れ・失効済みのキー、署名の鍵を持たない(署名に対応する前に発行した)キーと
+// 署名の不一致はErrInvalidSignatureです。
+func (s *APIKeyService) VerifySignature(ctx context.Context, id int64, message, signature []byte) (model.APIKey, error) {
+	key, err := s.keys.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrAPIKeyNotFound) {
+		return model.APIKey{}, ErrInvalidSignature
+	}
+	if err != nil {
+		return model.APIKey{}, err
+	}
+
+	if key.SigningKey == "" {
+		return model.APIKey{}, ErrInvalidSignature
+	}
+	signingKey, err := s.openSigningKey(key)
+	if err != nil {
+		// 暗号化の鍵が変わった場合も、署名の不一致と同じく扱います。
+		return model.APIKey{}, ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write(message)
+	if !hmac.Equal(mac.Sum(nil), signature) {
+		return model.APIKey{}, ErrInvalidSignature
+	}
+
+	now := s.now().UTC()
+	if !key.Active(now) {
+		return model.APIKey{}, ErrInvalidSignature
+	}
+	if err := s.keys.Touch(ctx, key.ID, now); err != nil {
+		return model.APIKey{}, err
+	}
+	key.LastUsedAt = &now
+	return key, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+func TestAPIKeyService_CreateValidation(t *testing.T) {
+	svc := NewAPIKeyService(repository.NewInMemoryAPIKeyRepository())
+	ctx := context.Background()
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		input CreateAPIKeyInput
+		want  error
+	}{
+		{"missing name", CreateAPIKeyInput{Name: " ", Scopes: []string{model.APIKeyScopePostsWrite}}, ErrAPIKeyNameRequired},
+		{"missing scopes", CreateAPIKeyInput{Name: "ci"}, ErrScopesRequired},
+		{"unknown scope", CreateAPIKeyInput{Name: "ci", Scopes: []string{"posts:read"}}, ErrInvalidScope},
+		{"expired", CreateAPIKeyInput{Name: "ci", Scopes: []string{model.APIKeyScopePostsWrite}, ExpiresAt: &past}, ErrInvalidExpiry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := svc.Create(ctx, tt.input); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestAPIKeyService_AuthenticateAndRevoke(t *testing.T) {
+	keys := repository.NewInMemoryAPIKeyRepository()
+	svc := NewAPIKeyService(keys)
+	ctx := context.Background()
+	now := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	expires := now.Add(time.Hour)
+	key, secret, err := svc.Create(ctx, CreateAPIKeyInput{
+		Name:      "ci",
+		Scopes:    []string{model.APIKeyScopePostsWrite, model.APIKeyScopePostsWrite},
+		ExpiresAt: &expires,
+	})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if !strings.HasPrefix(secret, key.Prefix) || key.KeyHash == secret || len(key.Scopes) != 1 {
+		t.Fatalf("unexpected key: %+v (secret=%q)", key, secret)
+	}
+
+	authenticated, err := svc.AuthenticateAPIKey(ctx, secret)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey returned error: %v", err)
+	}
+	if authenticated.ID != key.ID || authenticated.LastUsedAt == nil {
+		t.Fatalf("unexpected key: %+v", authenticated)
+	}
+	if stored, _ := keys.FindByID(ctx, key.ID); stored.LastUsedAt == nil || !stored.LastUsedAt.Equal(now) {
+		t.Fatalf("expected last_used_at to be stored, got %v", stored.LastUsedAt)
+	}
+	if _, err := svc.AuthenticateAPIKey(ctx, secret+"x"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected ErrInvalidAPIKey for unknown key, got %v", err)
+	}
+
+	svc.now = func() time.Time { return expires }
+	if _, err := svc.AuthenticateAPIKey(ctx, secret); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected ErrInvalidAPIKey for expired key, got %v", err)
+	}
+
+	svc.now = func() time.Time { return now }
+	if _, err := svc.Revoke(ctx, key.ID); err != nil {
+		t.Fatalf("Revoke returned error: %v", err)
+	}
+	if _, err := svc.AuthenticateAPIKey(ctx, secret); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Fatalf("expected ErrInvalidAPIKey for revoked key, got %v", err)
+	}
+}
+
+func TestAPIKeyService_VerifySignature(t *testing.T) {
+	keys := repository.NewInMemoryAPIKeyRepository()
+	svc := NewAPIKeyService(keys)
+	svc.SetEncryptionKey([]byte("encryption-key"))
+	ctx := context.Background()
+
+	key, secret, err := svc.Create(ctx, CreateAPIKeyInput{Name: "importer", Scopes: []string{model.APIKeyScopePostsWrite}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if key.SigningKey == "" || strings.Contains(key.SigningKey, HMACSigningKey(secret)) {
+		t.Fatalf("expected the signing key to be stored encrypted, got %q", key.SigningKey)
+	}
+
+	sign := func(signingKey string) []byte {
+		mac := hmac.New(sha256.New, []byte(signingKey))
+		mac.Write([]byte("message"))
+		return mac.Sum(nil)
+	}
+	if _, err := svc.VerifySignature(ctx, key.ID, []byte("message"), sign(HMACSigningKey(secret))); err != nil {
+		t.Fatalf("VerifySignature returned error: %v", err)
+	}
+	// The stored hash alone must not be enough to sign requests.
+	if _, err := svc.VerifySignature(ctx, key.ID, []byte("message"), sign(key.KeyHash)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature for the key hash, got %v", err)
+	}
+
+	svc.SetEncryptionKey([]byte("another-key"))
+	if _, err := svc.VerifySignature(ctx, key.ID, []byte("message"), sign(HMACSigningKey(secret))); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature after the encryption key changed, got %v", err)
+	}
+}
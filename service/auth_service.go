@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+var (
+	ErrUsernameRequired   = errors.New("username is required")
+	ErrInvalidPassword    = errors.New("password must be between 8 and 72 bytes")
+	ErrInvalidRole        = errors.New("role must be one of admin, editor, author")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+)
+
+const (
+	// MinPasswordLengthはパスワードの最小バイト数です。最大はbcryptの上限である72バイトです。
+	MinPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// トークンの用途です。アクセストークンとリフレッシュトークンを取り違えて使えないよう、クレームに記録します。
+const (
+	tokenUseAccess  = "access"
+	tokenUseRefresh = "refresh"
+)
+
+// AuthConfigはJWTの署名鍵と有効期間です。
+type AuthConfig struct {
+	Secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// TokenPairはログイン・リフレッシュで発行するトークンです。ExpiresInはアクセストークンの有効期間です。
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+type tokenClaims struct {
+	Use string `json:"token_use"`
+	jwt.RegisteredClaims
+}
+
+type AuthService struct {
+	users repository.UserRepository
+	cfg   AuthConfig
+	now   func() time.Time
+}
+
+func NewAuthService(users repository.UserRepository, cfg AuthConfig) *AuthService {
+	return &AuthService{users: users, cfg: cfg, now: time.Now}
+}
+
+// CreateUserはパスワードをbcryptでハッシュ化してUserを作成します。roleが空の場合はauthorになります。
+func (s *AuthService) CreateUser(ctx context.Context, username, password, role string) (model.User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return model.User{}, ErrUsernameRequired
+	}
+	if role == "" {
+		role = model.UserRoleAuthor
+	}
+	if !model.ValidUserRole(role) {
+		return model.User{}, ErrInvalidRole
+	}
+	if len(password) < MinPasswordLength || len(password) > maxPasswordLength {
+		return model.User{}, ErrInvalidPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	now := s.now().UTC()
+	return s.users.Create(ctx, model.User{
+		Username:     username,
+		Role:         role,
+		PasswordHash: string(hash),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// Loginはユーザー名とパスワードを検証してトークンを発行します。
+// ユーザーが存在しない場合もパスワードの比較を行い、応答時間からユーザーの有無を推測されないようにします。
+func (s *AuthService) Login(ctx context.Context, username, password string) (TokenPair, error) {
+	user, err := s.users.FindByUsername(ctx, strings.TrimSpace(username))
+	if errors.Is(err, repository.ErrUserNotFound) {
+		dummyHashOnce.Do(func() {
+			dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+		})
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return TokenPair{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return TokenPair{}, ErrInvalidCredentials
+	}
+	return s.issue(user)
+}
+
+// Refreshはリフレッシュトークンを検証し、新しいトークンを発行します。
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (TokenPair, error) {
+	user, err := s.userFromToken(ctx, refreshToken, tokenUseRefresh)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return s.issue(user)
+}
+
+// Authenticateはアクセストークンを検証し、トークンのUserを返します。
+func (s *AuthService) Authenticate(ctx context.Context, accessToken string) (model.User, error) {
+	return s.userFromToken(ctx, accessToken, tokenUseAccess)
+}
+
+func (s *AuthService) issue(user model.User) (TokenPair, error) {
+	now := s.now()
+	access, err := s.sign(user, tokenUseAccess, now, s.cfg.AccessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := s.sign(user, tokenUseRefresh, now, s.cfg.RefreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresIn: s.cfg.AccessTTL}, nil
+}
+
+func (s *AuthService) sign(user model.User, use string, now time.Time, ttl time.Duration) (string, error) {
+	claims := tokenClaims{
+		Use: use,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatInt(user.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.cfg.Secret)
+}
+
+// userFromTokenはトークンの署名・有効期限・用途を検証し、対象のUserを読み込みます。
+// 削除されたUserのトークンは無効として扱います。
+func (s *AuthService) userFromToken(ctx context.Context, token, use string) (model.User, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return s.cfg.Secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithTimeFunc(s.now))
+	if err != nil || claims.Use != use {
+		return model.User{}, ErrInvalidToken
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return model.User{}, ErrInvalidToken
+	}
+	user, err := s.users.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return model.User{}, ErrInvalidToken
+	}
+	return user, err
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+func newTestAuthService() *AuthService {
+	return NewAuthService(repository.NewInMemoryUserRepository(), AuthConfig{
+		Secret:     []byte("test-secret"),
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	})
+}
+
+func TestAuthService_CreateUserValidation(t *testing.T) {
+	svc := newTestAuthService()
+	ctx := context.Background()
+
+	if _, err := svc.CreateUser(ctx, " ", "password", ""); !errors.Is(err, ErrUsernameRequired) {
+		t.Fatalf("expected ErrUsernameRequired, got %v", err)
+	}
+	if _, err := svc.CreateUser(ctx, "alice", "short", ""); !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("expected ErrInvalidPassword, got %v", err)
+	}
+
+	user, err := svc.CreateUser(ctx, "alice", "password", "")
+	if err != nil {
+		t.Fatalf("CreateUser returned error: %v", err)
+	}
+	if user.PasswordHash == "" || user.PasswordHash == "password" {
+		t.Fatalf("expected password to be hashed, got %q", user.PasswordHash)
+	}
+	if _, err := svc.CreateUser(ctx, "Alice", "password", ""); !errors.Is(err, repository.ErrUsernameTaken) {
+		t.Fatalf("expected ErrUsernameTaken, got %v", err)
+	}
+}
+
+func TestAuthService_LoginRefreshAndAuthenticate(t *testing.T) {
+	svc := newTestAuthService()
+	ctx := context.Background()
+
+	user, err := svc.CreateUser(ctx, "alice", "password", "")
+	if err != nil {
+		t.Fatalf("CreateUser returned error: %v", err)
+	}
+
+	if _, err := svc.Login(ctx, "alice", "wrong-password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := svc.Login(ctx, "bob", "password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for unknown user, got %v", err)
+	}
+
+	tokens, err := svc.Login(ctx, "alice", "password")
+	if err != nil {
+		t.Fatalf("Login returned error: %v", err)
+	}
+	if tokens.ExpiresIn != time.Minute {
+		t.Fatalf("expected access token to expire in a minute, got %s", tokens.ExpiresIn)
+	}
+
+	authenticated, err := svc.Authenticate(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate returned error: %v", err)
+	}
+	if authenticated.ID != user.ID {
+		t.Fatalf("expected user %d, got %d", user.ID, authenticated.ID)
+	}
+
+	// アクセストークンとリフレッシュトークンは互いの代わりに使えません。
+	if _, err := svc.Authenticate(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected refresh token to be rejected as access token, got %v", err)
+	}
+	if _, err := svc.Refresh(ctx, tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected access token to be rejected as refresh token, got %v", err)
+	}
+
+	refreshed, err := svc.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, refreshed.AccessToken); err != nil {
+		t.Fatalf("Authenticate with refreshed token returned error: %v", err)
+	}
+
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := svc.Authenticate(ctx, tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected expired token to be rejected, got %v", err)
+	}
+
+	other := NewAuthService(repository.NewInMemoryUserRepository(), AuthConfig{Secret: []byte("other-secret"), AccessTTL: time.Minute})
+	if _, err := other.Authenticate(ctx, tokens.AccessToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected token signed with another secret to be rejected, got %v", err)
+	}
+}
+
+func TestAuthService_CreateUserRole(t *testing.T) {
+	svc := newTestAuthService()
+	ctx := context.Background()
+
+	if _, err := svc.CreateUser(ctx, "alice", "password", "owner"); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+	user, err := svc.CreateUser(ctx, "alice", "password", "")
+	if err != nil {
+		t.Fatalf("CreateUser returned error: %v", err)
+	}
+	if user.Role != model.UserRoleAuthor {
+		t.Fatalf("expected default role %q, got %q", model.UserRoleAuthor, user.Role)
+	}
+	editor, err := svc.CreateUser(ctx, "erin", "password", model.UserRoleEditor)
+	if err != nil || editor.Role != model.UserRoleEditor {
+		t.Fatalf("expected editor, got %+v (err=%v)", editor, err)
+	}
+}
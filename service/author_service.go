@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+	"unicode"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/validation"
+)
+
+var ErrAuthorNameTooLong = errors.New("author name must be at most 100 characters")
+
+// MaxAuthorNameLengthは著者名の最大文字数です。
+const MaxAuthorNameLength = 100
+
+// authorNameRuleは著者名の検証規則です。前後の空白を除き、連続する空白を1つにまとめます。
+var authorNameRule = validation.Text{
+	Required:      true,
+	MaxLength:     MaxAuthorNameLength,
+	Normalize:     true,
+	CollapseSpace: true,
+	Disallowed:    []*unicode.RangeTable{unicode.Bidi_Control},
+	Errors: map[string]error{
+		validation.CodeRequired: ErrAuthorRequired,
+		validation.CodeTooLong:  ErrAuthorNameTooLong,
+	},
+}
+
+// normalizeAuthorNameは著者名を正規化します。不正な著者名はfieldの違反としてvに記録します。
+func normalizeAuthorName(v *validation.Validator, field, name string) string {
+	return v.Text(field, name, authorNameRule)
+}
+
+type AuthorService struct {
+	authors repository.AuthorRepository
+}
+
+func NewAuthorService(authors repository.AuthorRepository) *AuthorService {
+	return &AuthorService{authors: authors}
+}
+
+func (s *AuthorService) Create(ctx context.Context, name string) (model.Author, error) {
+	var v validation.Validator
+	name = normalizeAuthorName(&v, "name", name)
+	if err := v.Err(); err != nil {
+		return model.Author{}, err
+	}
+
+	now := time.Now().UTC()
+	return s.authors.Create(ctx, model.Author{Name: name, CreatedAt: now, UpdatedAt: now})
+}
+
+// Listは著者を名前順に返します。
+func (s *AuthorService) List(ctx context.Context) ([]model.Author, error) {
+	return s.authors.FindAll(ctx)
+}
+
+func (s *AuthorService) Get(ctx context.Context, id int64) (model.Author, error) {
+	return s.authors.FindByID(ctx, id)
+}
+
+// Renameは著者名を変更します。著者の記事が返す著者名も新しい名前になります。
+func (s *AuthorService) Rename(ctx context.Context, id int64, name string) (model.Author, error) {
+	var v validation.Validator
+	name = normalizeAuthorName(&v, "name", name)
+	if err := v.Err(); err != nil {
+		return model.Author{}, err
+	}
+	return s.authors.Rename(ctx, id, name, time.Now().UTC())
+}
+
+// Deleteは著者を削除します。記事が残っている著者は削除できません。
+func (s *AuthorService) Delete(ctx context.Context, id int64) error {
+	return s.authors.Delete(ctx, id)
+}
+
+// resolveAuthorは記事に設定する著者を決定します。authorIDを指定した場合はその著者を返し、
+// それ以外は大文字・小文字を区別せずにnameと一致する著者を返します。一致する著者がいなければ作成します。
+func resolveAuthor(ctx context.Context, authors repository.AuthorRepository, authorID int64, name string) (model.Author, error) {
+	if authorID != 0 {
+		return authors.FindByID(ctx, authorID)
+	}
+
+	var v validation.Validator
+	name = normalizeAuthorName(&v, "author", name)
+	if err := v.Err(); err != nil {
+		return model.Author{}, err
+	}
+
+	author, err := authors.FindByName(ctx, name)
+	if !errors.Is(err, repository.ErrAuthorNotFound) {
+		return author, err
+	}
+
+	now := time.Now().UTC()
+	author, err = authors.Create(ctx, model.Author{Name: name, CreatedAt: now, UpdatedAt: now})
+	if errors.Is(err, repository.ErrAuthorNameTaken) {
+		// 同じ著者を同時に作成した場合は、先に作成された著者を利用します。
+		return authors.FindByName(ctx, name)
+	}
+	return author, err
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+func TestAuthorService_CreateNormalizesName(t *testing.T) {
+	svc := NewAuthorService(repository.NewInMemoryAuthorRepository(repository.NewInMemoryPostRepository()))
+	ctx := context.Background()
+
+	author, err := svc.Create(ctx, "  Alice   Smith ")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if author.Name != "Alice Smith" {
+		t.Fatalf("expected normalized name, got %q", author.Name)
+	}
+
+	if _, err := svc.Create(ctx, "alice smith"); !errors.Is(err, repository.ErrAuthorNameTaken) {
+		t.Fatalf("expected ErrAuthorNameTaken, got %v", err)
+	}
+	if _, err := svc.Create(ctx, "   "); !errors.Is(err, ErrAuthorRequired) {
+		t.Fatalf("expected ErrAuthorRequired, got %v", err)
+	}
+	if _, err := svc.Rename(ctx, author.ID, strings.Repeat("a", MaxAuthorNameLength+1)); !errors.Is(err, ErrAuthorNameTooLong) {
+		t.Fatalf("expected ErrAuthorNameTooLong, got %v", err)
+	}
+}
+
+func TestPostService_ResolvesAuthors(t *testing.T) {
+	posts := repository.NewInMemoryPostRepository()
+	authors := repository.NewInMemoryAuthorRepository(posts)
+	svc := NewPostService(posts, repository.NewInMemoryRevisionRepository(), authors)
+	ctx := context.Background()
+
+	first, err := svc.Create(ctx, CreatePostInput{Title: "first", Content: "content", Author: "Alice"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	second, err := svc.Create(ctx, CreatePostInput{Title: "second", Content: "content", Author: "alice "})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if first.AuthorID == 0 || second.AuthorID != first.AuthorID || second.Author != "Alice" {
+		t.Fatalf("expected both posts to share author %d, got %+v and %+v", first.AuthorID, first, second)
+	}
+
+	bob, err := NewAuthorService(authors).Create(ctx, "Bob")
+	if err != nil {
+		t.Fatalf("Create author returned error: %v", err)
+	}
+	updated, err := svc.Update(ctx, first.ID, UpdatePostInput{AuthorID: &bob.ID})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if updated.AuthorID != bob.ID || updated.Author != "Bob" {
+		t.Fatalf("expected post to move to Bob, got %+v", updated)
+	}
+
+	missing := int64(999)
+	if _, err := svc.Update(ctx, first.ID, UpdatePostInput{AuthorID: &missing}); !errors.Is(err, repository.ErrAuthorNotFound) {
+		t.Fatalf("expected ErrAuthorNotFound, got %v", err)
+	}
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "t", Content: "c", AuthorID: missing}); !errors.Is(err, repository.ErrAuthorNotFound) {
+		t.Fatalf("expected ErrAuthorNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+var (
+	ErrCommentBodyRequired = errors.New("body is required")
+	ErrCommentTooLong      = errors.New("body must be at most 2000 characters")
+	ErrInvalidParent       = errors.New("replies must target a top-level comment on the same post")
+)
+
+// MaxCommentLengthはコメント本文の最大文字数です。
+const MaxCommentLength = 2000
+
+type CommentService struct {
+	comments repository.CommentRepository
+	posts    repository.PostRepository
+}
+
+func NewCommentService(comments repository.CommentRepository, posts repository.PostRepository) *CommentService {
+	return &CommentService{comments: comments, posts: posts}
+}
+
+// CreateCommentInputはコメント作成の入力です。ParentIDを指定するとそのコメントへの返信になります。
+// PostStatusを指定すると、その公開状態のPostにのみコメントできます(それ以外はErrPostNotFound)。
+type CreateCommentInput struct {
+	PostID     int64
+	ParentID   *int64
+	Author     string
+	Body       string
+	PostStatus string
+}
+
+func (s *CommentService) Create(ctx context.Context, input CreateCommentInput) (model.Comment, error) {
+	author := strings.TrimSpace(input.Author)
+	body := strings.TrimSpace(input.Body)
+
+	if author == "" {
+		return model.Comment{}, ErrAuthorRequired
+	}
+	if body == "" {
+		return model.Comment{}, ErrCommentBodyRequired
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return model.Comment{}, ErrCommentTooLong
+	}
+
+	if err := s.checkPost(ctx, input.PostID, input.PostStatus); err != nil {
+		return model.Comment{}, err
+	}
+	if input.ParentID != nil {
+		parent, err := s.comments.FindByID(ctx, *input.ParentID)
+		if errors.Is(err, repository.ErrCommentNotFound) {
+			return model.Comment{}, ErrInvalidParent
+		}
+		if err != nil {
+			return model.Comment{}, err
+		}
+		if parent.PostID != input.PostID || parent.ParentID != nil {
+			return model.Comment{}, ErrInvalidParent
+		}
+	}
+
+	return s.comments.Create(ctx, model.Comment{
+		PostID:    input.PostID,
+		ParentID:  input.ParentID,
+		Author:    author,
+		Body:      body,
+		CreatedAt: time.Now().UTC(),
+	})
+}
+
+// ListはPostのトップレベルのコメントを古い順にページ単位で返します。
+// 各コメントには古い順にrepository.ReplyPreviewLimit件までの返信と返信の総数が含まれ、残りはListRepliesで取得します。
+// postStatusを指定すると、その公開状態のPostのコメントのみを返します。
+func (s *CommentService) List(ctx context.Context, query repository.CommentQuery, postStatus string) (repository.CommentPage, error) {
+	query.ParentID = 0
+	if query.Limit < 0 || query.Limit > MaxListLimit {
+		return repository.CommentPage{}, ErrInvalidLimit
+	}
+	if err := s.checkPost(ctx, query.PostID, postStatus); err != nil {
+		return repository.CommentPage{}, err
+	}
+	return s.comments.FindPage(ctx, query)
+}
+
+// ListRepliesはquery.ParentIDのコメントへの返信を古い順にページ単位で返します。
+// 親がquery.PostIDのトップレベルのコメントでない場合はErrCommentNotFoundを返します。
+func (s *CommentService) ListReplies(ctx context.Context, query repository.CommentQuery, postStatus string) (repository.CommentPage, error) {
+	if query.Limit < 0 || query.Limit > MaxListLimit {
+		return repository.CommentPage{}, ErrInvalidLimit
+	}
+	if err := s.checkPost(ctx, query.PostID, postStatus); err != nil {
+		return repository.CommentPage{}, err
+	}
+	parent, err := s.comments.FindByID(ctx, query.ParentID)
+	if err != nil {
+		return repository.CommentPage{}, err
+	}
+	if parent.PostID != query.PostID || parent.ParentID != nil {
+		return repository.CommentPage{}, repository.ErrCommentNotFound
+	}
+	return s.comments.FindPage(ctx, query)
+}
+
+// Deleteはコメントを削除します。トップレベルのコメントを削除すると返信も削除されます。
+func (s *CommentService) Delete(ctx context.Context, id int64) error {
+	return s.comments.Delete(ctx, id)
+}
+
+// checkPostはコメント対象のPostが存在し、statusが指定されていればその公開状態であることを確認します。
+func (s *CommentService) checkPost(ctx context.Context, postID int64, status string) error {
+	post, err := s.posts.FindByID(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if status != "" && post.Status != status {
+		return repository.ErrPostNotFound
+	}
+	return nil
+}
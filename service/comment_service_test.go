@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+func TestCommentService_CreateAndList(t *testing.T) {
+	posts := repository.NewInMemoryPostRepository()
+	svc := NewCommentService(repository.NewInMemoryCommentRepository(), posts)
+	ctx := context.Background()
+
+	post, err := posts.Create(ctx, model.Post{Title: "t", Content: "c", Author: "a", Status: model.PostStatusPublished})
+	if err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+	other, err := posts.Create(ctx, model.Post{Title: "t", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("failed to seed post: %v", err)
+	}
+
+	parent, err := svc.Create(ctx, CreateCommentInput{PostID: post.ID, Author: " reader ", Body: " nice post "})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if parent.Author != "reader" || parent.Body != "nice post" || parent.CreatedAt.IsZero() {
+		t.Fatalf("unexpected comment: %+v", parent)
+	}
+	reply, err := svc.Create(ctx, CreateCommentInput{PostID: post.ID, ParentID: &parent.ID, Author: "a", Body: "thanks"})
+	if err != nil {
+		t.Fatalf("Create reply returned error: %v", err)
+	}
+
+	cases := []struct {
+		input CreateCommentInput
+		want  error
+	}{
+		{CreateCommentInput{PostID: post.ID, Body: "b"}, ErrAuthorRequired},
+		{CreateCommentInput{PostID: post.ID, Author: "a", Body: "  "}, ErrCommentBodyRequired},
+		{CreateCommentInput{PostID: post.ID, Author: "a", Body: strings.Repeat("あ", MaxCommentLength+1)}, ErrCommentTooLong},
+		{CreateCommentInput{PostID: post.ID, ParentID: &reply.ID, Author: "a", Body: "b"}, ErrInvalidParent},
+		{CreateCommentInput{PostID: other.ID, ParentID: &parent.ID, Author: "a", Body: "b"}, ErrInvalidParent},
+		{CreateCommentInput{PostID: 999, Author: "a", Body: "b"}, repository.ErrPostNotFound},
+		{CreateCommentInput{PostID: other.ID, Author: "a", Body: "b", PostStatus: model.PostStatusPublished}, repository.ErrPostNotFound},
+	}
+	for i, tc := range cases {
+		if _, err := svc.Create(ctx, tc.input); err != tc.want {
+			t.Fatalf("case %d: expected %v, got %v", i, tc.want, err)
+		}
+	}
+
+	page, err := svc.List(ctx, repository.CommentQuery{PostID: post.ID}, model.PostStatusPublished)
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(page.Comments) != 1 || len(page.Comments[0].Replies) != 1 || page.Comments[0].Replies[0].ID != reply.ID {
+		t.Fatalf("unexpected comments: %+v", page.Comments)
+	}
+	if _, err := svc.List(ctx, repository.CommentQuery{PostID: post.ID, Limit: MaxListLimit + 1}, ""); err != ErrInvalidLimit {
+		t.Fatalf("expected ErrInvalidLimit, got %v", err)
+	}
+	if _, err := svc.List(ctx, repository.CommentQuery{PostID: other.ID}, model.PostStatusPublished); err != repository.ErrPostNotFound {
+		t.Fatalf("expected comments of unpublished posts to be hidden, got %v", err)
+	}
+
+	if err := svc.Delete(ctx, parent.ID); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	page, err = svc.List(ctx, repository.CommentQuery{PostID: post.ID}, "")
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(page.Comments) != 0 {
+		t.Fatalf("expected thread to be deleted, got %+v", page.Comments)
+	}
+}
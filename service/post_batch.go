@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kitakitabauer/gin-sample-app/logger"
+	"github.com/kitakitabauer/gin-sample-app/model"
+	"go.uber.org/zap"
+)
+
+// MaxBatchOperationsはBatch 1回あたりに指定できる操作の最大数です。
+const MaxBatchOperations = 100
+
+// Batchで指定できる操作の種類です。
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+var (
+	ErrEmptyBatch            = errors.New("batch must contain at least one operation")
+	ErrTooManyOperations     = fmt.Errorf("a batch can contain at most %d operations", MaxBatchOperations)
+	ErrInvalidBatchOperation = errors.New("operation type must be one of create, update, delete")
+	ErrAtomicNotSupported    = errors.New("atomic batches are not supported")
+)
+
+// BatchOperationはBatchで実行する1件の操作です。
+// createではCreate、updateではIDとUpdate、deleteではIDを利用します。
+type BatchOperation struct {
+	Type   string
+	ID     int64
+	Create CreatePostInput
+	Update UpdatePostInput
+}
+
+// BatchInputはBatchの入力です。Atomicを指定するとすべての操作を1つのトランザクションで実行し、
+// いずれかが失敗した時点ですべての変更を取り消します。
+// Authorizeはupdate・deleteの前に呼ばれ、エラーを返すとその操作は失敗します。nilの場合は確認しません。
+// Atomicの場合、ctxは操作と同じトランザクションを持つため、同じBatchで作成したPostも参照できます。
+type BatchInput struct {
+	Operations []BatchOperation
+	Atomic     bool
+	Authorize  func(ctx context.Context, posts *PostService, id int64) error
+}
+
+// BatchResultは1件の操作の結果です。成功した場合はPost(deleteでは空)を、失敗した場合はErrを持ちます。
+type BatchResult struct {
+	Post model.Post
+	Err  error
+}
+
+// BatchErrorはAtomicなBatchで失敗した操作の位置と原因です。
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batchは複数の操作を順に実行し、操作ごとの結果を指定順に返します。
+// Atomicでない場合は失敗した操作があっても残りの操作を続け、失敗はBatchResult.Errで返します。
+// Atomicの場合は最初に失敗した操作で処理を止め、すべての変更を取り消して*BatchErrorを返します。
+func (s *PostService) Batch(ctx context.Context, input BatchInput) (_ []BatchResult, err error) {
+	ctx, span := startSpan(ctx, "PostService.Batch")
+	defer endSpan(span, &err)
+
+	if len(input.Operations) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(input.Operations) > MaxBatchOperations {
+		return nil, ErrTooManyOperations
+	}
+	for _, op := range input.Operations {
+		switch op.Type {
+		case BatchCreate, BatchUpdate, BatchDelete:
+		default:
+			return nil, ErrInvalidBatchOperation
+		}
+	}
+
+	if !input.Atomic {
+		results := make([]BatchResult, len(input.Operations))
+		for i, op := range input.Operations {
+			post, err := s.applyBatchOperation(ctx, op, input.Authorize)
+			results[i] = BatchResult{Post: post, Err: err}
+		}
+		return results, nil
+	}
+
+	if s.tx == nil {
+		return nil, ErrAtomicNotSupported
+	}
+	var results []BatchResult
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		results = make([]BatchResult, len(input.Operations))
+		for i, op := range input.Operations {
+			post, err := s.applyBatchOperation(ctx, op, input.Authorize)
+			if err != nil {
+				return &BatchError{Index: i, Err: err}
+			}
+			results[i] = BatchResult{Post: post}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(ctx).Info("atomic batch rolled back",
+			zap.Int("operations", len(input.Operations)),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *PostService) applyBatchOperation(ctx context.Context, op BatchOperation, authorize func(context.Context, *PostService, int64) error) (model.Post, error) {
+	if op.Type == BatchCreate {
+		return s.Create(ctx, op.Create)
+	}
+	if authorize != nil {
+		if err := authorize(ctx, s, op.ID); err != nil {
+			return model.Post{}, err
+		}
+	}
+	if op.Type == BatchUpdate {
+		return s.Update(ctx, op.ID, op.Update)
+	}
+	return model.Post{}, s.Delete(ctx, op.ID)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+func TestPostService_Batch(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	existing, err := svc.Create(ctx, CreatePostInput{Title: "existing", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	errDenied := errors.New("denied")
+	title := "renamed"
+	results, err := svc.Batch(ctx, BatchInput{
+		Operations: []BatchOperation{
+			{Type: BatchCreate, Create: CreatePostInput{Title: "new", Content: "content", Author: "author"}},
+			{Type: BatchCreate, Create: CreatePostInput{Content: "content", Author: "author"}},
+			{Type: BatchUpdate, ID: existing.ID, Update: UpdatePostInput{Title: &title}},
+			{Type: BatchDelete, ID: 999},
+			{Type: BatchDelete, ID: existing.ID + 1},
+		},
+		// 作成したPostの削除だけを拒否します。
+		Authorize: func(ctx context.Context, posts *PostService, id int64) error {
+			if id == existing.ID+1 {
+				return errDenied
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Batch returned error: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	if results[0].Err != nil || results[0].Post.Title != "new" {
+		t.Fatalf("unexpected create result: %+v", results[0])
+	}
+	if !errors.Is(results[1].Err, ErrTitleRequired) {
+		t.Fatalf("expected ErrTitleRequired, got %v", results[1].Err)
+	}
+	if results[2].Err != nil || results[2].Post.Title != title {
+		t.Fatalf("unexpected update result: %+v", results[2])
+	}
+	if !errors.Is(results[3].Err, repository.ErrPostNotFound) {
+		t.Fatalf("expected ErrPostNotFound, got %v", results[3].Err)
+	}
+	if !errors.Is(results[4].Err, errDenied) {
+		t.Fatalf("expected the authorizer error, got %v", results[4].Err)
+	}
+	if _, err := svc.Get(ctx, existing.ID+1); err != nil {
+		t.Fatalf("expected the denied delete to keep the post, got %v", err)
+	}
+}
+
+func TestPostService_Batch_InvalidInput(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	tooMany := make([]BatchOperation, MaxBatchOperations+1)
+	for i := range tooMany {
+		tooMany[i] = BatchOperation{Type: BatchDelete, ID: 1}
+	}
+
+	cases := []struct {
+		input BatchInput
+		want  error
+	}{
+		{BatchInput{}, ErrEmptyBatch},
+		{BatchInput{Operations: tooMany}, ErrTooManyOperations},
+		{BatchInput{Operations: []BatchOperation{{Type: "archive", ID: 1}}}, ErrInvalidBatchOperation},
+		// TxManagerを設定していないため、Atomicは利用できません。
+		{BatchInput{Operations: []BatchOperation{{Type: BatchDelete, ID: 1}}, Atomic: true}, ErrAtomicNotSupported},
+	}
+	for _, tc := range cases {
+		if _, err := svc.Batch(ctx, tc.input); !errors.Is(err, tc.want) {
+			t.Fatalf("expected %v, got %v", tc.want, err)
+		}
+	}
+}
+
+func TestPostService_Batch_Atomic(t *testing.T) {
+	repo := repository.NewInMemoryPostRepository()
+	revisions := repository.NewInMemoryRevisionRepository()
+	authors := repository.NewInMemoryAuthorRepository(repo)
+	svc := NewPostService(repo, revisions, authors)
+	svc.SetTxManager(repository.NewInMemoryTxManager(repo, revisions, authors))
+	ctx := context.Background()
+
+	existing, err := svc.Create(ctx, CreatePostInput{Title: "existing", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	title := "renamed"
+	_, err = svc.Batch(ctx, BatchInput{
+		Operations: []BatchOperation{
+			{Type: BatchCreate, Create: CreatePostInput{Title: "new", Content: "content", Author: "new author"}},
+			{Type: BatchUpdate, ID: existing.ID, Update: UpdatePostInput{Title: &title}},
+			{Type: BatchDelete, ID: 999},
+		},
+		Atomic: true,
+	})
+	var berr *BatchError
+	if !errors.As(err, &berr) || berr.Index != 2 || !errors.Is(err, repository.ErrPostNotFound) {
+		t.Fatalf("expected a BatchError for the third operation, got %v", err)
+	}
+
+	posts, _ := repo.FindAll(ctx)
+	if len(posts) != 1 || posts[0].Title != "existing" {
+		t.Fatalf("expected the batch to be rolled back, got %+v", posts)
+	}
+	if revs, _ := svc.Revisions(ctx, existing.ID); len(revs) != 0 {
+		t.Fatalf("expected no revisions after rollback, got %+v", revs)
+	}
+	if _, err := authors.FindByName(ctx, "new author"); !errors.Is(err, repository.ErrAuthorNotFound) {
+		t.Fatalf("expected the created author to be rolled back, got %v", err)
+	}
+
+	results, err := svc.Batch(ctx, BatchInput{
+		Operations: []BatchOperation{
+			{Type: BatchCreate, Create: CreatePostInput{Title: "new", Content: "content", Author: "author"}},
+			{Type: BatchUpdate, ID: existing.ID, Update: UpdatePostInput{Title: &title}},
+		},
+		Atomic: true,
+	})
+	if err != nil || len(results) != 2 || results[1].Post.Title != title {
+		t.Fatalf("unexpected atomic batch result: %+v (err %v)", results, err)
+	}
+	if revs, _ := svc.Revisions(ctx, existing.ID); len(revs) != 1 {
+		t.Fatalf("expected the update to record a revision, got %+v", revs)
+	}
+}
@@ -3,11 +3,18 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/kitakitabauer/gin-sample-app/logger"
 	"github.com/kitakitabauer/gin-sample-app/model"
 	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/validation"
+	"go.uber.org/zap"
+	"golang.org/x/text/unicode/norm"
 )
 
 var (
@@ -15,82 +22,429 @@ var (
 	ErrContentRequired  = errors.New("content is required")
 	ErrAuthorRequired   = errors.New("author is required")
 	ErrNoFieldsToUpdate = errors.New("no fields provided to update")
+	ErrInvalidLimit     = errors.New("limit must be between 1 and 100")
+	ErrQueryRequired    = errors.New("search query is required")
+	ErrInvalidStatus    = errors.New("status must be one of draft, scheduled, published, archived")
+	ErrInvalidPublishAt = errors.New("publish_at must be in the future for scheduled posts")
+	ErrInvalidTag       = errors.New("tags must be between 1 and 32 characters without control characters")
+	ErrTooManyTags      = errors.New("a post can have at most 10 tags")
+	ErrInvalidSlug      = errors.New("slug must contain at least one letter or digit")
+	// ErrInvalidTransitionは現在の公開状態から指定した状態へ遷移できない場合のエラーです。
+	ErrInvalidTransition = errors.New("status transition is not allowed")
 )
 
+// SchedulerActorは予約投稿を自動公開した際にPost.UpdatedByとして記録される更新者です。
+const SchedulerActor = "scheduler"
+
+// postTransitionsは公開状態ごとに遷移可能な状態です。scheduledからscheduledへの遷移は予約日時の変更を表します。
+var postTransitions = map[string][]string{
+	model.PostStatusDraft:     {model.PostStatusScheduled, model.PostStatusPublished, model.PostStatusArchived},
+	model.PostStatusScheduled: {model.PostStatusDraft, model.PostStatusScheduled, model.PostStatusPublished, model.PostStatusArchived},
+	model.PostStatusPublished: {model.PostStatusDraft, model.PostStatusArchived},
+	model.PostStatusArchived:  {model.PostStatusDraft, model.PostStatusPublished},
+}
+
+func canTransition(from, to string) bool {
+	for _, next := range postTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePublishAtはstatusへ遷移する際に記録するpublish_atを決定します。
+// scheduledには未来の日時が必要で、publishedは現在時刻、draftは未設定になります。
+// archivedは元の値を引き継ぐためnilを返し、applyUpdateで補完します。
+func resolvePublishAt(status string, publishAt *time.Time, now time.Time) (*time.Time, error) {
+	switch status {
+	case model.PostStatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return nil, ErrInvalidPublishAt
+		}
+		at := publishAt.UTC()
+		return &at, nil
+	case model.PostStatusPublished:
+		return &now, nil
+	case model.PostStatusDraft, model.PostStatusArchived:
+		return nil, nil
+	default:
+		return nil, ErrInvalidStatus
+	}
+}
+
+// validatePublishAtはresolvePublishAtの結果を返し、不正なstatusやpublish_atをvに記録します。
+func validatePublishAt(v *validation.Validator, status string, publishAt *time.Time, now time.Time) *time.Time {
+	at, err := resolvePublishAt(status, publishAt, now)
+	switch {
+	case errors.Is(err, ErrInvalidStatus):
+		v.Add("status", validation.CodeInvalid, err)
+	case err != nil:
+		v.Add("publish_at", validation.CodeInvalid, err)
+	}
+	return at
+}
+
+// MaxListLimitはList 1回あたりに取得できる最大件数です。
+const MaxListLimit = 100
+
+const (
+	// MaxTagsPerPostは1つのPostに付与できるタグの最大数です。
+	MaxTagsPerPost = 10
+	// MaxTagLengthはタグ名の最大文字数です。
+	MaxTagLength = 32
+)
+
+// normalizeTagは絞り込みに指定されたタグ名を、保存時と同じくNFCに正規化して前後の空白を除き、小文字に揃えます。
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(norm.NFC.String(tag)))
+}
+
+// normalizeTagsはタグ名をruleで正規化して小文字に揃え、重複を除いて名前順に並べます。
+// 不正なタグはtagsの違反としてvに記録します。
+func normalizeTags(v *validation.Validator, rule validation.Text, tags []string) []string {
+	seen := make(map[string]struct{}, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		var tv validation.Validator
+		tag = strings.ToLower(tv.Text("tags", tag, rule))
+		if !tv.Valid() {
+			v.Add("tags", validation.CodeInvalid, ErrInvalidTag)
+			return nil
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxTagsPerPost {
+		v.Add("tags", validation.CodeTooMany, ErrTooManyTags)
+		return nil
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+const (
+	// MaxTitleLengthはタイトルの既定の最大文字数です。
+	MaxTitleLength = 200
+	// MaxContentLengthは本文の既定の最大文字数です。
+	MaxContentLength = 100000
+)
+
+// PostRulesはPostの作成・更新で入力に適用する検証規則です。
+type PostRules struct {
+	Title   validation.Text
+	Content validation.Text
+	Tag     validation.Text
+}
+
+// DefaultPostRulesは既定の検証規則を返します。
+// いずれもNFCに正規化し、タイトルとタグでは文字の表示順を変える双方向制御文字も拒否します。
+func DefaultPostRules() PostRules {
+	return PostRules{
+		Title: validation.Text{
+			Required:   true,
+			MaxLength:  MaxTitleLength,
+			Normalize:  true,
+			Disallowed: []*unicode.RangeTable{unicode.Bidi_Control},
+			Errors:     map[string]error{validation.CodeRequired: ErrTitleRequired},
+		},
+		Content: validation.Text{
+			Required:  true,
+			MaxLength: MaxContentLength,
+			Normalize: true,
+			Multiline: true,
+			Errors:    map[string]error{validation.CodeRequired: ErrContentRequired},
+		},
+		Tag: validation.Text{
+			Required:   true,
+			MaxLength:  MaxTagLength,
+			Normalize:  true,
+			Disallowed: []*unicode.RangeTable{unicode.Bidi_Control},
+		},
+	}
+}
+
 type PostService struct {
-	repo repository.PostRepository
+	repo      repository.PostRepository
+	revisions repository.RevisionRepository
+	authors   repository.AuthorRepository
+	// txは更新と変更履歴の記録やAtomicなBatchをまとめるために利用します。SetTxManagerで設定します。
+	tx repository.TxManager
+	// rulesは入力の検証規則です。SetRulesで変更できます。
+	rules PostRules
+}
+
+func NewPostService(repo repository.PostRepository, revisions repository.RevisionRepository, authors repository.AuthorRepository) *PostService {
+	return &PostService{repo: repo, revisions: revisions, authors: authors, rules: DefaultPostRules()}
+}
+
+// SetRulesは入力の検証規則を変更します。DefaultPostRulesの値を元に上限などを調整してください。
+func (s *PostService) SetRules(rules PostRules) {
+	s.rules = rules
+}
+
+// SetTxManagerはリポジトリと同じストレージのTxManagerを設定します。
+// 設定しない場合、更新と変更履歴の記録は別々に行われ、AtomicなBatchはErrAtomicNotSupportedを返します。
+func (s *PostService) SetTxManager(tx repository.TxManager) {
+	s.tx = tx
 }
 
-func NewPostService(repo repository.PostRepository) *PostService {
-	return &PostService{repo: repo}
+// withinTxはTxManagerが設定されていればfnをトランザクション内で実行し、設定されていなければそのまま実行します。
+func (s *PostService) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.WithinTx(ctx, fn)
 }
 
-func (s *PostService) Create(ctx context.Context, title, content, author string) (model.Post, error) {
-	title = strings.TrimSpace(title)
-	content = strings.TrimSpace(content)
-	author = strings.TrimSpace(author)
+// CreatePostInputはPost作成の入力です。Statusが空の場合は下書きとして作成します。
+// PublishAtはStatusがscheduledの場合にのみ利用されます。
+// Tagsは小文字に正規化され、重複を除いて保存されます。
+// 著者はAuthorIDか著者名(Author)で指定します。AuthorIDを指定した場合はAuthorより優先し、
+// 著者名に一致する著者がいない場合は新しく作成します。
+// OwnerIDは記事を作成したUserのIDで、APIキーで作成する場合は0です。
+type CreatePostInput struct {
+	Title     string
+	Content   string
+	Author    string
+	AuthorID  int64
+	Status    string
+	PublishAt *time.Time
+	Tags      []string
+	OwnerID   int64
+}
+
+func (s *PostService) Create(ctx context.Context, input CreatePostInput) (_ model.Post, err error) {
+	ctx, span := startSpan(ctx, "PostService.Create")
+	defer endSpan(span, &err)
+
+	status := strings.TrimSpace(input.Status)
+	if status == "" {
+		status = model.PostStatusDraft
+	}
+	now := time.Now().UTC()
 
-	if title == "" {
-		return model.Post{}, ErrTitleRequired
+	// 最初の違反で止めず、すべての違反をまとめて返します。
+	var v validation.Validator
+	title := v.Text("title", input.Title, s.rules.Title)
+	content := v.Text("content", input.Content, s.rules.Content)
+	name := input.Author
+	if input.AuthorID == 0 {
+		name = normalizeAuthorName(&v, "author", name)
 	}
-	if content == "" {
-		return model.Post{}, ErrContentRequired
+	publishAt := validatePublishAt(&v, status, input.PublishAt, now)
+	tags := normalizeTags(&v, s.rules.Tag, input.Tags)
+	if err := v.Err(); err != nil {
+		return model.Post{}, err
 	}
-	if author == "" {
-		return model.Post{}, ErrAuthorRequired
+
+	author, err := resolveAuthor(ctx, s.authors, input.AuthorID, name)
+	if err != nil {
+		return model.Post{}, err
+	}
+
+	base := slugify(title)
+	if base == "" {
+		base = fallbackSlug
 	}
 
 	post := model.Post{
 		Title:     title,
 		Content:   content,
-		Author:    author,
-		CreatedAt: time.Now().UTC(),
+		Author:    author.Name,
+		AuthorID:  author.ID,
+		OwnerID:   input.OwnerID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Status:    status,
+		PublishAt: publishAt,
+		Tags:      tags,
 	}
 
-	created, err := s.repo.Create(ctx, post)
-	if err != nil {
-		return model.Post{}, err
+	// 同じslugを同時に作成した場合は一意制約で失敗するため、slugを選び直して再試行します。
+	for attempt := 1; ; attempt++ {
+		if post.Slug, err = s.uniqueSlug(ctx, base, 0); err != nil {
+			return model.Post{}, err
+		}
+		created, err := s.repo.Create(ctx, post)
+		if errors.Is(err, repository.ErrSlugTaken) && attempt < updateAttempts {
+			logger.FromContext(ctx).Debug("slug was taken concurrently, retrying",
+				zap.String("slug", post.Slug),
+				zap.Int("attempt", attempt),
+			)
+			continue
+		}
+		if err != nil {
+			return model.Post{}, err
+		}
+		return created, nil
 	}
+}
+
+// ListはqueryのFilterとSortに従ってPost一覧をページ単位に返します。Limitが0の場合は既定件数を利用します。
+func (s *PostService) List(ctx context.Context, query repository.PostQuery) (_ repository.PostPage, err error) {
+	ctx, span := startSpan(ctx, "PostService.List")
+	defer endSpan(span, &err)
 
-	return created, nil
+	if query.Limit < 0 || query.Limit > MaxListLimit {
+		return repository.PostPage{}, ErrInvalidLimit
+	}
+	if _, err := repository.ParsePostSort(query.Sort.String()); err != nil {
+		return repository.PostPage{}, err
+	}
+	query.Filter.Author = strings.TrimSpace(query.Filter.Author)
+	query.Filter.Tag = normalizeTag(query.Filter.Tag)
+	if query.Filter.Status != "" && !model.ValidPostStatus(query.Filter.Status) {
+		return repository.PostPage{}, ErrInvalidStatus
+	}
+	return s.repo.FindPage(ctx, query)
+}
+
+// Searchはタイトルと本文を全文検索し、関連度の高い順に返します。Statusを指定するとその公開状態に限定します。
+func (s *PostService) Search(ctx context.Context, query repository.PostSearchQuery) (_ []model.PostSearchHit, err error) {
+	ctx, span := startSpan(ctx, "PostService.Search")
+	defer endSpan(span, &err)
+
+	query.Text = strings.TrimSpace(query.Text)
+	if query.Text == "" {
+		return nil, ErrQueryRequired
+	}
+	if query.Limit < 0 || query.Limit > MaxListLimit {
+		return nil, ErrInvalidLimit
+	}
+	if query.Status != "" && !model.ValidPostStatus(query.Status) {
+		return nil, ErrInvalidStatus
+	}
+	return s.repo.Search(ctx, query)
 }
 
-func (s *PostService) List(ctx context.Context) ([]model.Post, error) {
-	return s.repo.FindAll(ctx)
+// Tagsはタグごとの記事件数を件数の多い順に返します。statusを指定するとその公開状態の記事のみを数えます。
+func (s *PostService) Tags(ctx context.Context, status string) (_ []model.Tag, err error) {
+	ctx, span := startSpan(ctx, "PostService.Tags")
+	defer endSpan(span, &err)
+
+	if status != "" && !model.ValidPostStatus(status) {
+		return nil, ErrInvalidStatus
+	}
+	return s.repo.FindTags(ctx, status)
 }
 
-func (s *PostService) Get(ctx context.Context, id int64) (model.Post, error) {
+func (s *PostService) Get(ctx context.Context, id int64) (_ model.Post, err error) {
+	ctx, span := startSpan(ctx, "PostService.Get")
+	defer endSpan(span, &err)
+
 	return s.repo.FindByID(ctx, id)
 }
 
-func (s *PostService) Update(ctx context.Context, id int64, title, content, author *string) (model.Post, error) {
-	var update repository.PostUpdate
+// GetBySlugはslugに一致するPostを返します。変更前のslugを指定した場合も現在のPostを返すため、
+// 返されたPostのSlugがslugと異なるかどうかで転送が必要か判定できます。
+func (s *PostService) GetBySlug(ctx context.Context, slug string) (_ model.Post, err error) {
+	ctx, span := startSpan(ctx, "PostService.GetBySlug")
+	defer endSpan(span, &err)
+
+	return s.repo.FindBySlug(ctx, slug)
+}
+
+// UpdatePostInputは部分更新の入力です。nilのフィールドは変更しません。
+// IfVersionを指定すると、現在のバージョンと一致する場合にのみ更新します。
+// ActorはPost.UpdatedByとして記録される更新者です。
+// Statusを指定すると公開状態を遷移させます。PublishAtはStatusがscheduledの場合にのみ利用されます。
+// Tagsを指定すると付与済みのタグをすべて置き換えます。空のスライスはタグをすべて外します。
+// Slugを変更すると、変更前のslugは新しいslugへの転送用に残ります。
+// AuthorIDかAuthorを指定すると著者を変更します。著者の決定方法はCreatePostInputと同じです。
+type UpdatePostInput struct {
+	Title     *string
+	Slug      *string
+	Content   *string
+	Author    *string
+	AuthorID  *int64
+	Status    *string
+	PublishAt *time.Time
+	Tags      *[]string
+	IfVersion *int64
+	Actor     string
+}
+
+func (s *PostService) Update(ctx context.Context, id int64, input UpdatePostInput) (_ model.Post, err error) {
+	ctx, span := startSpan(ctx, "PostService.Update")
+	defer endSpan(span, &err)
+
+	update := repository.PostUpdate{
+		UpdatedAt:       time.Now().UTC(),
+		UpdatedBy:       strings.TrimSpace(input.Actor),
+		ExpectedVersion: input.IfVersion,
+	}
 	var hasUpdate bool
 
-	if title != nil {
-		trimmed := strings.TrimSpace(*title)
-		if trimmed == "" {
-			return model.Post{}, ErrTitleRequired
-		}
-		update.Title = &trimmed
+	// 指定されたフィールドをすべて検証してから、データベースを参照する検査を行います。
+	var v validation.Validator
+
+	if input.Title != nil {
+		title := v.Text("title", *input.Title, s.rules.Title)
+		update.Title = &title
 		hasUpdate = true
 	}
 
-	if content != nil {
-		trimmed := strings.TrimSpace(*content)
-		if trimmed == "" {
-			return model.Post{}, ErrContentRequired
+	if input.Slug != nil {
+		slug := slugify(*input.Slug)
+		if slug == "" {
+			v.Add("slug", validation.CodeInvalid, ErrInvalidSlug)
 		}
-		update.Content = &trimmed
+		update.Slug = &slug
+		hasUpdate = true
+	}
+
+	if input.Content != nil {
+		content := v.Text("content", *input.Content, s.rules.Content)
+		update.Content = &content
 		hasUpdate = true
 	}
 
-	if author != nil {
-		trimmed := strings.TrimSpace(*author)
-		if trimmed == "" {
-			return model.Post{}, ErrAuthorRequired
+	if input.Status != nil {
+		status := strings.TrimSpace(*input.Status)
+		update.Status = &status
+		update.PublishAt = validatePublishAt(&v, status, input.PublishAt, update.UpdatedAt)
+		hasUpdate = true
+	}
+
+	if input.Tags != nil {
+		tags := normalizeTags(&v, s.rules.Tag, *input.Tags)
+		update.Tags = &tags
+		hasUpdate = true
+	}
+
+	var authorID int64
+	var name string
+	if input.AuthorID != nil {
+		authorID = *input.AuthorID
+	} else if input.Author != nil {
+		name = normalizeAuthorName(&v, "author", *input.Author)
+	}
+
+	if err := v.Err(); err != nil {
+		return model.Post{}, err
+	}
+
+	if update.Slug != nil {
+		taken, err := s.repo.SlugTaken(ctx, *update.Slug, id)
+		if err != nil {
+			return model.Post{}, err
 		}
-		update.Author = &trimmed
+		if taken {
+			return model.Post{}, repository.ErrSlugTaken
+		}
+	}
+
+	if input.Author != nil || input.AuthorID != nil {
+		author, err := resolveAuthor(ctx, s.authors, authorID, name)
+		if err != nil {
+			return model.Post{}, err
+		}
+		update.Author = &author.Name
+		update.AuthorID = &author.ID
 		hasUpdate = true
 	}
 
@@ -98,9 +452,194 @@ func (s *PostService) Update(ctx context.Context, id int64, title, content, auth
 		return model.Post{}, ErrNoFieldsToUpdate
 	}
 
-	return s.repo.Update(ctx, id, update)
+	return s.applyUpdate(ctx, id, update)
+}
+
+// updateAttemptsはIfVersion未指定の更新が他の更新と競合した場合に再試行する回数です。
+const updateAttempts = 3
+
+// applyUpdateは更新前の内容をPostRevisionとして記録しながらPostを更新します。
+// 読み取ったバージョンを条件に更新するため、記録される履歴は必ず直前の内容と一致します。
+func (s *PostService) applyUpdate(ctx context.Context, id int64, update repository.PostUpdate) (model.Post, error) {
+	ifVersion := update.ExpectedVersion
+
+	for attempt := 1; ; attempt++ {
+		// Postの更新と変更履歴の記録は同じトランザクションで行います。
+		var updated model.Post
+		err := s.withinTx(ctx, func(ctx context.Context) error {
+			current, err := s.repo.FindByID(ctx, id)
+			if err != nil {
+				return err
+			}
+			if ifVersion != nil && current.Version != *ifVersion {
+				return repository.ErrVersionConflict
+			}
+			if update.Status != nil {
+				if !canTransition(current.Status, *update.Status) {
+					return ErrInvalidTransition
+				}
+				if *update.Status == model.PostStatusArchived {
+					update.PublishAt = current.PublishAt
+				}
+			}
+
+			update.ExpectedVersion = &current.Version
+			if updated, err = s.repo.Update(ctx, id, update); err != nil {
+				return err
+			}
+
+			if _, err := s.revisions.Create(ctx, model.PostRevision{
+				PostID:    id,
+				Revision:  current.Version,
+				Title:     current.Title,
+				Content:   current.Content,
+				Author:    current.Author,
+				AuthorID:  current.AuthorID,
+				Actor:     update.UpdatedBy,
+				CreatedAt: update.UpdatedAt,
+			}); err != nil {
+				return fmt.Errorf("failed to record revision: %w", err)
+			}
+			return nil
+		})
+		if errors.Is(err, repository.ErrVersionConflict) && ifVersion == nil && attempt < updateAttempts {
+			logger.FromContext(ctx).Debug("post was updated concurrently, retrying",
+				zap.Int64("post_id", id),
+				zap.Int("attempt", attempt),
+			)
+			continue
+		}
+		if err != nil {
+			return model.Post{}, err
+		}
+		return updated, nil
+	}
+}
+
+// PublishはPostを公開します。publishAtに未来の日時を指定した場合は予約投稿となり、
+// 指定日時を過ぎるとPublishDueによって公開されます。
+func (s *PostService) Publish(ctx context.Context, id int64, publishAt *time.Time, actor string) (_ model.Post, err error) {
+	ctx, span := startSpan(ctx, "PostService.Publish")
+	defer endSpan(span, &err)
+
+	now := time.Now().UTC()
+	status := model.PostStatusPublished
+	if publishAt != nil && publishAt.After(now) {
+		status = model.PostStatusScheduled
+	}
+	at, err := resolvePublishAt(status, publishAt, now)
+	if err != nil {
+		return model.Post{}, err
+	}
+
+	return s.applyUpdate(ctx, id, repository.PostUpdate{
+		Status:    &status,
+		PublishAt: at,
+		UpdatedAt: now,
+		UpdatedBy: strings.TrimSpace(actor),
+	})
+}
+
+// UnpublishはPostを下書きに戻します。予約投稿の場合は予約も取り消されます。
+func (s *PostService) Unpublish(ctx context.Context, id int64, actor string) (_ model.Post, err error) {
+	ctx, span := startSpan(ctx, "PostService.Unpublish")
+	defer endSpan(span, &err)
+
+	status := model.PostStatusDraft
+	return s.applyUpdate(ctx, id, repository.PostUpdate{
+		Status:    &status,
+		UpdatedAt: time.Now().UTC(),
+		UpdatedBy: strings.TrimSpace(actor),
+	})
+}
+
+// PublishDueは公開日時がnow以前の予約投稿をすべて公開し、公開した件数を返します。
+func (s *PostService) PublishDue(ctx context.Context, now time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "PostService.PublishDue")
+	defer endSpan(span, &err)
+
+	return s.repo.PublishDue(ctx, now.UTC(), SchedulerActor)
 }
 
-func (s *PostService) Delete(ctx context.Context, id int64) error {
+// RevisionsはPostの更新履歴を新しい順に返します。
+func (s *PostService) Revisions(ctx context.Context, id int64) (_ []model.PostRevision, err error) {
+	ctx, span := startSpan(ctx, "PostService.Revisions")
+	defer endSpan(span, &err)
+
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.revisions.FindByPost(ctx, id)
+}
+
+func (s *PostService) Revision(ctx context.Context, id, revision int64) (_ model.PostRevision, err error) {
+	ctx, span := startSpan(ctx, "PostService.Revision")
+	defer endSpan(span, &err)
+
+	if _, err := s.repo.FindByID(ctx, id); err != nil {
+		return model.PostRevision{}, err
+	}
+	return s.revisions.Find(ctx, id, revision)
+}
+
+// Revertは指定した履歴の内容でPostを更新します。Revert前の内容も新しい履歴として記録されます。
+// 著者は履歴のAuthorIDで解決するため、記録後に著者名が変更されていても同じ著者に戻ります。
+// 著者が削除されている場合や、AuthorIDを持たない古い履歴では著者名で解決します。
+func (s *PostService) Revert(ctx context.Context, id, revision int64, actor string) (_ model.Post, err error) {
+	ctx, span := startSpan(ctx, "PostService.Revert")
+	defer endSpan(span, &err)
+
+	rev, err := s.Revision(ctx, id, revision)
+	if err != nil {
+		return model.Post{}, err
+	}
+	author, err := resolveAuthor(ctx, s.authors, rev.AuthorID, rev.Author)
+	if errors.Is(err, repository.ErrAuthorNotFound) && rev.AuthorID != 0 {
+		author, err = resolveAuthor(ctx, s.authors, 0, rev.Author)
+	}
+	if err != nil {
+		return model.Post{}, err
+	}
+
+	return s.applyUpdate(ctx, id, repository.PostUpdate{
+		Title:     &rev.Title,
+		Content:   &rev.Content,
+		Author:    &author.Name,
+		AuthorID:  &author.ID,
+		UpdatedAt: time.Now().UTC(),
+		UpdatedBy: strings.TrimSpace(actor),
+	})
+}
+
+// DeleteはPostをゴミ箱へ移動します。Restoreで復元でき、Purgeで完全に削除されます。
+func (s *PostService) Delete(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "PostService.Delete")
+	defer endSpan(span, &err)
+
 	return s.repo.Delete(ctx, id)
 }
+
+// Trashはゴミ箱にあるPostを削除日時の新しい順にページ単位で返します。queryはLimitとCursorのみを使います。
+func (s *PostService) Trash(ctx context.Context, query repository.PostQuery) (_ repository.PostPage, err error) {
+	ctx, span := startSpan(ctx, "PostService.Trash")
+	defer endSpan(span, &err)
+
+	if query.Limit < 0 || query.Limit > MaxListLimit {
+		return repository.PostPage{}, ErrInvalidLimit
+	}
+	return s.repo.FindTrash(ctx, query)
+}
+
+func (s *PostService) Restore(ctx context.Context, id int64) (_ model.Post, err error) {
+	ctx, span := startSpan(ctx, "PostService.Restore")
+	defer endSpan(span, &err)
+
+	return s.repo.Restore(ctx, id)
+}
+
+func (s *PostService) Purge(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "PostService.Purge")
+	defer endSpan(span, &err)
+
+	return s.repo.Purge(ctx, id)
+}
@@ -2,20 +2,26 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/kitakitabauer/gin-sample-app/model"
 	"github.com/kitakitabauer/gin-sample-app/repository"
+	"github.com/kitakitabauer/gin-sample-app/validation"
 )
 
 func newTestService() *PostService {
 	repo := repository.NewInMemoryPostRepository()
-	return NewPostService(repo)
+	return NewPostService(repo, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(repo))
 }
 
 func TestPostService_Create_Success(t *testing.T) {
 	svc := newTestService()
 
-	post, err := svc.Create(context.Background(), "title", "content", "author")
+	post, err := svc.Create(context.Background(), CreatePostInput{Title: "title", Content: "content", Author: "author"})
 	if err != nil {
 		t.Fatalf("Create returned error: %v", err)
 	}
@@ -35,38 +41,97 @@ func TestPostService_Create_ValidationErrors(t *testing.T) {
 	svc := newTestService()
 	ctx := context.Background()
 
-	if _, err := svc.Create(ctx, "", "content", "author"); err != ErrTitleRequired {
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "", Content: "content", Author: "author"}); !errors.Is(err, ErrTitleRequired) {
 		t.Fatalf("expected title error, got %v", err)
 	}
-	if _, err := svc.Create(ctx, "title", "", "author"); err != ErrContentRequired {
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "", Author: "author"}); !errors.Is(err, ErrContentRequired) {
 		t.Fatalf("expected content error, got %v", err)
 	}
-	if _, err := svc.Create(ctx, "title", "content", ""); err != ErrAuthorRequired {
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: ""}); !errors.Is(err, ErrAuthorRequired) {
 		t.Fatalf("expected author error, got %v", err)
 	}
 }
 
+func TestPostService_Create_ReportsAllViolations(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	_, err := svc.Create(ctx, CreatePostInput{
+		Title:   strings.Repeat("t", MaxTitleLength+1),
+		Content: " ",
+		Author:  "a\u202eb",
+		Status:  "hidden",
+		Tags:    []string{""},
+	})
+	var fields []string
+	for _, v := range validation.Violations(err) {
+		fields = append(fields, v.Field+":"+v.Code)
+	}
+	want := "title:too_long content:required author:invalid_characters status:invalid tags:invalid"
+	if strings.Join(fields, " ") != want {
+		t.Fatalf("expected %s, got %v", want, err)
+	}
+	if !errors.Is(err, ErrContentRequired) || !errors.Is(err, ErrInvalidStatus) {
+		t.Fatalf("expected the violations to wrap the service errors, got %v", err)
+	}
+
+	created, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	title, slug := " ", "!!"
+	_, err = svc.Update(ctx, created.ID, UpdatePostInput{Title: &title, Slug: &slug})
+	if len(validation.Violations(err)) != 2 || !errors.Is(err, ErrTitleRequired) || !errors.Is(err, ErrInvalidSlug) {
+		t.Fatalf("expected title and slug violations, got %v", err)
+	}
+}
+
+func TestPostService_Create_NormalizesInput(t *testing.T) {
+	svc := newTestService()
+	rules := DefaultPostRules()
+	rules.Title.MaxLength = 5
+	svc.SetRules(rules)
+
+	// "e"と結合文字のアキュートアクセントはNFCで1文字の"é"になります。
+	post, err := svc.Create(context.Background(), CreatePostInput{Title: " cafe\u0301 ", Content: "line1\n\tline2", Author: "a  b"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if post.Title != "caf\u00e9" || post.Content != "line1\n\tline2" || post.Author != "a b" {
+		t.Fatalf("unexpected post: %+v", post)
+	}
+
+	_, err = svc.Create(context.Background(), CreatePostInput{Title: "titles", Content: "c", Author: "a"})
+	if violations := validation.Violations(err); len(violations) != 1 || violations[0].Code != validation.CodeTooLong {
+		t.Fatalf("expected the configured limit to apply, got %v", err)
+	}
+}
+
 func TestPostService_ListAndGet(t *testing.T) {
 	svc := newTestService()
 	ctx := context.Background()
 
-	first, err := svc.Create(ctx, "first", "content", "alice")
+	first, err := svc.Create(ctx, CreatePostInput{Title: "first", Content: "content", Author: "alice"})
 	if err != nil {
 		t.Fatalf("failed to create first post: %v", err)
 	}
-	second, err := svc.Create(ctx, "second", "content", "bob")
+	second, err := svc.Create(ctx, CreatePostInput{Title: "second", Content: "content", Author: "bob"})
 	if err != nil {
 		t.Fatalf("failed to create second post: %v", err)
 	}
 
-	posts, err := svc.List(ctx)
+	page, err := svc.List(ctx, repository.PostQuery{})
 	if err != nil {
 		t.Fatalf("List returned error: %v", err)
 	}
 
+	posts := page.Posts
 	if len(posts) != 2 || posts[0].ID != first.ID || posts[1].ID != second.ID {
 		t.Fatalf("unexpected posts order or length: %+v", posts)
 	}
+	if page.NextCursor != "" {
+		t.Fatalf("expected no next cursor, got %q", page.NextCursor)
+	}
 
 	got, err := svc.Get(ctx, first.ID)
 	if err != nil {
@@ -77,27 +142,123 @@ func TestPostService_ListAndGet(t *testing.T) {
 	}
 }
 
+func TestPostService_List_Pagination(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: "author"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	first, err := svc.List(ctx, repository.PostQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(first.Posts) != 2 || first.NextCursor == "" {
+		t.Fatalf("unexpected first page: %+v", first)
+	}
+
+	second, err := svc.List(ctx, repository.PostQuery{Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(second.Posts) != 1 || second.Posts[0].ID != 3 || second.NextCursor != "" {
+		t.Fatalf("unexpected second page: %+v", second)
+	}
+
+	if _, err := svc.List(ctx, repository.PostQuery{Limit: MaxListLimit + 1}); err != ErrInvalidLimit {
+		t.Fatalf("expected ErrInvalidLimit, got %v", err)
+	}
+	if _, err := svc.List(ctx, repository.PostQuery{Cursor: "not-a-cursor"}); err != repository.ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestPostService_List_FilterAndSort(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	for _, author := range []string{"alice", "bob", "alice"} {
+		if _, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: author}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	page, err := svc.List(ctx, repository.PostQuery{
+		Filter: repository.PostFilter{Author: " alice "},
+		Sort:   repository.PostSort{Field: repository.SortFieldID, Desc: true},
+	})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(page.Posts) != 2 || page.Posts[0].ID != 3 || page.Posts[1].ID != 1 {
+		t.Fatalf("unexpected posts: %+v", page.Posts)
+	}
+
+	if _, err := svc.List(ctx, repository.PostQuery{Sort: repository.PostSort{Field: "title"}}); err != repository.ErrInvalidSort {
+		t.Fatalf("expected ErrInvalidSort, got %v", err)
+	}
+}
+
+func TestPostService_Search(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	first, err := svc.Create(ctx, CreatePostInput{Title: "Gin routing", Content: "Routing groups in Gin.", Author: "alice"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "Zap logging", Content: "Structured logs for Gin apps.", Author: "bob"}); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	hits, err := svc.Search(ctx, repository.PostSearchQuery{Text: "GIN routing"})
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if len(hits) != 1 || hits[0].Post.ID != first.ID {
+		t.Fatalf("unexpected hits: %+v", hits)
+	}
+	if hits[0].Highlight.Title != "<mark>Gin</mark> <mark>routing</mark>" {
+		t.Fatalf("unexpected title highlight: %q", hits[0].Highlight.Title)
+	}
+
+	hits, err = svc.Search(ctx, repository.PostSearchQuery{Text: "gin"})
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+	if len(hits) != 2 || hits[0].Post.ID != first.ID {
+		t.Fatalf("expected title match to rank first, got %+v", hits)
+	}
+
+	if _, err := svc.Search(ctx, repository.PostSearchQuery{Text: "  "}); err != ErrQueryRequired {
+		t.Fatalf("expected ErrQueryRequired, got %v", err)
+	}
+}
+
 func TestPostService_Update(t *testing.T) {
 	svc := newTestService()
 	ctx := context.Background()
 
-	created, err := svc.Create(ctx, "title", "content", "author")
+	created, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: "author"})
 	if err != nil {
 		t.Fatalf("Create returned error: %v", err)
 	}
 
-	if _, err := svc.Update(ctx, created.ID, nil, nil, nil); err != ErrNoFieldsToUpdate {
+	if _, err := svc.Update(ctx, created.ID, UpdatePostInput{}); err != ErrNoFieldsToUpdate {
 		t.Fatalf("expected ErrNoFieldsToUpdate, got %v", err)
 	}
 
 	empty := ""
-	if _, err := svc.Update(ctx, created.ID, &empty, nil, nil); err != ErrTitleRequired {
+	if _, err := svc.Update(ctx, created.ID, UpdatePostInput{Title: &empty}); !errors.Is(err, ErrTitleRequired) {
 		t.Fatalf("expected ErrTitleRequired, got %v", err)
 	}
 
 	newTitle := "new title"
 	newAuthor := "new author"
-	updated, err := svc.Update(ctx, created.ID, &newTitle, nil, &newAuthor)
+	updated, err := svc.Update(ctx, created.ID, UpdatePostInput{Title: &newTitle, Author: &newAuthor})
 	if err != nil {
 		t.Fatalf("Update returned error: %v", err)
 	}
@@ -106,16 +267,171 @@ func TestPostService_Update(t *testing.T) {
 		t.Fatalf("unexpected updated post: %+v", updated)
 	}
 
-	if _, err := svc.Update(ctx, created.ID+99, &newTitle, nil, nil); err != repository.ErrPostNotFound {
+	if _, err := svc.Update(ctx, created.ID+99, UpdatePostInput{Title: &newTitle}); err != repository.ErrPostNotFound {
 		t.Fatalf("expected repository.ErrPostNotFound, got %v", err)
 	}
 }
 
+func TestPostService_Update_TracksModification(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if !created.UpdatedAt.Equal(created.CreatedAt) || created.UpdatedBy != "" {
+		t.Fatalf("unexpected modification fields on create: %+v", created)
+	}
+
+	newTitle := "edited"
+	updated, err := svc.Update(ctx, created.ID, UpdatePostInput{Title: &newTitle, Actor: "editor"})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if updated.UpdatedBy != "editor" {
+		t.Fatalf("expected UpdatedBy editor, got %q", updated.UpdatedBy)
+	}
+	if updated.UpdatedAt.Before(created.UpdatedAt) || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("unexpected timestamps after update: %+v", updated)
+	}
+
+	page, err := svc.List(ctx, repository.PostQuery{Filter: repository.PostFilter{UpdatedSince: &updated.UpdatedAt}})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != created.ID {
+		t.Fatalf("expected updated post to be returned, got %+v", page.Posts)
+	}
+
+	later := updated.UpdatedAt.Add(time.Second)
+	page, err = svc.List(ctx, repository.PostQuery{Filter: repository.PostFilter{UpdatedSince: &later}})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(page.Posts) != 0 {
+		t.Fatalf("expected no posts updated after %v, got %+v", later, page.Posts)
+	}
+}
+
+func TestPostService_Update_IfVersion(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	first := "first editor"
+	updated, err := svc.Update(ctx, created.ID, UpdatePostInput{Title: &first, IfVersion: &created.Version})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if updated.Version != created.Version+1 {
+		t.Fatalf("expected version %d, got %d", created.Version+1, updated.Version)
+	}
+
+	second := "second editor"
+	if _, err := svc.Update(ctx, created.ID, UpdatePostInput{Title: &second, IfVersion: &created.Version}); err != repository.ErrVersionConflict {
+		t.Fatalf("expected ErrVersionConflict, got %v", err)
+	}
+
+	got, err := svc.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if got.Title != first {
+		t.Fatalf("expected stale update to be rejected, got title %q", got.Title)
+	}
+}
+
+func TestPostService_RevisionsAndRevert(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, CreatePostInput{Title: "v1 title", Content: "v1 content", Author: "alice"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	title := "v2 title"
+	if _, err := svc.Update(ctx, created.ID, UpdatePostInput{Title: &title, Actor: "bob"}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	revisions, err := svc.Revisions(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Revisions returned error: %v", err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("expected 1 revision, got %+v", revisions)
+	}
+	rev := revisions[0]
+	if rev.Revision != created.Version || rev.Title != "v1 title" || rev.Author != "alice" || rev.Actor != "bob" {
+		t.Fatalf("unexpected revision: %+v", rev)
+	}
+
+	reverted, err := svc.Revert(ctx, created.ID, rev.Revision, "carol")
+	if err != nil {
+		t.Fatalf("Revert returned error: %v", err)
+	}
+	if reverted.Title != "v1 title" || reverted.UpdatedBy != "carol" || reverted.Version != 3 {
+		t.Fatalf("unexpected reverted post: %+v", reverted)
+	}
+
+	revisions, err = svc.Revisions(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Revisions returned error: %v", err)
+	}
+	if len(revisions) != 2 || revisions[0].Title != "v2 title" || revisions[0].Actor != "carol" {
+		t.Fatalf("expected revert to record the replaced content, got %+v", revisions)
+	}
+
+	if _, err := svc.Revert(ctx, created.ID, 99, "carol"); err != repository.ErrRevisionNotFound {
+		t.Fatalf("expected ErrRevisionNotFound, got %v", err)
+	}
+	if _, err := svc.Revisions(ctx, created.ID+99); err != repository.ErrPostNotFound {
+		t.Fatalf("expected ErrPostNotFound, got %v", err)
+	}
+}
+
+func TestPostService_RevertRestoresRenamedAuthor(t *testing.T) {
+	posts := repository.NewInMemoryPostRepository()
+	authors := repository.NewInMemoryAuthorRepository(posts)
+	svc := NewPostService(posts, repository.NewInMemoryRevisionRepository(), authors)
+	authorService := NewAuthorService(authors)
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: "alice"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	other := "bob"
+	if _, err := svc.Update(ctx, created.ID, UpdatePostInput{Author: &other, Actor: "editor"}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if _, err := authorService.Rename(ctx, created.AuthorID, "Alice Smith"); err != nil {
+		t.Fatalf("Rename returned error: %v", err)
+	}
+
+	reverted, err := svc.Revert(ctx, created.ID, created.Version, "editor")
+	if err != nil {
+		t.Fatalf("Revert returned error: %v", err)
+	}
+	if reverted.AuthorID != created.AuthorID || reverted.Author != "Alice Smith" {
+		t.Fatalf("expected revert to restore the renamed author, got %+v", reverted)
+	}
+	if _, err := authors.FindByName(ctx, "alice"); !errors.Is(err, repository.ErrAuthorNotFound) {
+		t.Fatalf("expected no author to be created for the old name, got %v", err)
+	}
+}
+
 func TestPostService_Delete(t *testing.T) {
 	svc := newTestService()
 	ctx := context.Background()
 
-	created, err := svc.Create(ctx, "title", "content", "author")
+	created, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: "author"})
 	if err != nil {
 		t.Fatalf("Create returned error: %v", err)
 	}
@@ -128,3 +444,182 @@ func TestPostService_Delete(t *testing.T) {
 		t.Fatalf("expected ErrPostNotFound after deletion, got %v", err)
 	}
 }
+
+func TestPostService_StatusWorkflow(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, CreatePostInput{Title: "title", Content: "content", Author: "author"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if created.Status != model.PostStatusDraft {
+		t.Fatalf("expected new post to be a draft, got %q", created.Status)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "t", Content: "c", Author: "a", Status: model.PostStatusScheduled, PublishAt: &past}); !errors.Is(err, ErrInvalidPublishAt) {
+		t.Fatalf("expected ErrInvalidPublishAt, got %v", err)
+	}
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "t", Content: "c", Author: "a", Status: "hidden"}); !errors.Is(err, ErrInvalidStatus) {
+		t.Fatalf("expected ErrInvalidStatus, got %v", err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	scheduled, err := svc.Publish(ctx, created.ID, &future, "editor")
+	if err != nil {
+		t.Fatalf("Publish returned error: %v", err)
+	}
+	if scheduled.Status != model.PostStatusScheduled || scheduled.PublishAt == nil {
+		t.Fatalf("expected scheduled post, got %+v", scheduled)
+	}
+
+	published, err := svc.PublishDue(ctx, future.Add(time.Second))
+	if err != nil || published != 1 {
+		t.Fatalf("expected 1 post to be published, got %d (err %v)", published, err)
+	}
+	current, err := svc.Get(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if current.Status != model.PostStatusPublished || current.UpdatedBy != SchedulerActor {
+		t.Fatalf("expected scheduler to publish post, got %+v", current)
+	}
+
+	if _, err := svc.Publish(ctx, created.ID, nil, "editor"); err != ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition when publishing twice, got %v", err)
+	}
+
+	archived := model.PostStatusArchived
+	current, err = svc.Update(ctx, created.ID, UpdatePostInput{Status: &archived, Actor: "editor"})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if current.Status != model.PostStatusArchived || current.PublishAt == nil {
+		t.Fatalf("expected archived post to keep publish_at, got %+v", current)
+	}
+
+	scheduledStatus := model.PostStatusScheduled
+	if _, err := svc.Update(ctx, created.ID, UpdatePostInput{Status: &scheduledStatus, PublishAt: &future}); err != ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition from archived to scheduled, got %v", err)
+	}
+
+	current, err = svc.Unpublish(ctx, created.ID, "editor")
+	if err != nil {
+		t.Fatalf("Unpublish returned error: %v", err)
+	}
+	if current.Status != model.PostStatusDraft || current.PublishAt != nil {
+		t.Fatalf("expected draft without publish_at, got %+v", current)
+	}
+	if _, err := svc.Unpublish(ctx, created.ID, "editor"); err != ErrInvalidTransition {
+		t.Fatalf("expected ErrInvalidTransition when unpublishing a draft, got %v", err)
+	}
+}
+
+func TestPostService_Tags(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	created, err := svc.Create(ctx, CreatePostInput{Title: "t", Content: "c", Author: "a", Tags: []string{" Go ", "gin", "go"}})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if len(created.Tags) != 2 || created.Tags[0] != "gin" || created.Tags[1] != "go" {
+		t.Fatalf("expected normalized tags, got %v", created.Tags)
+	}
+
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "t", Content: "c", Author: "a", Tags: []string{" "}}); !errors.Is(err, ErrInvalidTag) {
+		t.Fatalf("expected ErrInvalidTag, got %v", err)
+	}
+	tooMany := make([]string, MaxTagsPerPost+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+	if _, err := svc.Create(ctx, CreatePostInput{Title: "t", Content: "c", Author: "a", Tags: tooMany}); !errors.Is(err, ErrTooManyTags) {
+		t.Fatalf("expected ErrTooManyTags, got %v", err)
+	}
+
+	page, err := svc.List(ctx, repository.PostQuery{Filter: repository.PostFilter{Tag: "GO"}})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != created.ID {
+		t.Fatalf("expected tag filter to be case-insensitive, got %+v", page.Posts)
+	}
+
+	cleared := []string{}
+	updated, err := svc.Update(ctx, created.ID, UpdatePostInput{Tags: &cleared})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if len(updated.Tags) != 0 {
+		t.Fatalf("expected tags to be cleared, got %v", updated.Tags)
+	}
+	if tags, err := svc.Tags(ctx, ""); err != nil || len(tags) != 0 {
+		t.Fatalf("expected no tags, got %+v (err %v)", tags, err)
+	}
+}
+
+func TestPostService_Slugs(t *testing.T) {
+	svc := newTestService()
+	ctx := context.Background()
+
+	first, err := svc.Create(ctx, CreatePostInput{Title: "Hello World", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	second, err := svc.Create(ctx, CreatePostInput{Title: "hello, world", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	symbols, err := svc.Create(ctx, CreatePostInput{Title: "???", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if first.Slug != "hello-world" || second.Slug != "hello-world-2" || symbols.Slug != "post" {
+		t.Fatalf("unexpected slugs: %q, %q, %q", first.Slug, second.Slug, symbols.Slug)
+	}
+
+	renamed := "Greetings"
+	updated, err := svc.Update(ctx, first.ID, UpdatePostInput{Slug: &renamed})
+	if err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	if updated.Slug != "greetings" {
+		t.Fatalf("expected normalized slug, got %q", updated.Slug)
+	}
+
+	found, err := svc.GetBySlug(ctx, "hello-world")
+	if err != nil {
+		t.Fatalf("GetBySlug returned error: %v", err)
+	}
+	if found.ID != first.ID || found.Slug != "greetings" {
+		t.Fatalf("expected old slug to resolve to the renamed post, got %+v", found)
+	}
+
+	// 変更前のslugは他のPostが使えないため、新規作成時は連番が付きます。
+	third, err := svc.Create(ctx, CreatePostInput{Title: "Hello World", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if third.Slug != "hello-world-3" {
+		t.Fatalf("expected retired slug to stay reserved, got %q", third.Slug)
+	}
+
+	taken := "hello-world-2"
+	if _, err := svc.Update(ctx, first.ID, UpdatePostInput{Slug: &taken}); err != repository.ErrSlugTaken {
+		t.Fatalf("expected ErrSlugTaken, got %v", err)
+	}
+	invalid := "---"
+	if _, err := svc.Update(ctx, first.ID, UpdatePostInput{Slug: &invalid}); !errors.Is(err, ErrInvalidSlug) {
+		t.Fatalf("expected ErrInvalidSlug, got %v", err)
+	}
+
+	original := "hello-world"
+	if updated, err = svc.Update(ctx, first.ID, UpdatePostInput{Slug: &original}); err != nil {
+		t.Fatalf("expected a post to take back its old slug, got %v", err)
+	}
+	if updated.Slug != "hello-world" {
+		t.Fatalf("unexpected slug: %q", updated.Slug)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+// MaxSlugLengthはslugの最大文字数です。
+const MaxSlugLength = 80
+
+// fallbackSlugはタイトルからslugを作れない場合に使う名前です。
+const fallbackSlug = "post"
+
+// maxSlugNumberはuniqueSlugが連番を試す上限です。base、"-2"…"-10"がすべて使用中の場合はランダムな接尾辞を付けます。
+const maxSlugNumber = 10
+
+// slugifyはsをURLに使えるslugに変換します。
+// NFKCで全角英数字を半角に揃えて小文字にし、文字・数字以外の連続を1つのハイフンにまとめます。
+// 日本語などの非ASCII文字はそのまま残します。変換後に何も残らない場合は空文字を返します。
+func slugify(s string) string {
+	s = strings.ToLower(norm.NFKC.String(s))
+
+	var b strings.Builder
+	var length int
+	pendingHyphen := false
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			pendingHyphen = b.Len() > 0
+			continue
+		}
+		if length >= MaxSlugLength {
+			break
+		}
+		if pendingHyphen {
+			if length+2 > MaxSlugLength {
+				break
+			}
+			b.WriteByte('-')
+			length++
+			pendingHyphen = false
+		}
+		b.WriteRune(r)
+		length++
+	}
+	return b.String()
+}
+
+// slugWithSuffixはbaseに"-n"を付けたslugを返します。最大文字数を超える場合はbaseを切り詰めます。
+func slugWithSuffix(base string, n int) string {
+	return appendSlugSuffix(base, strconv.Itoa(n))
+}
+
+// appendSlugSuffixはbaseに"-"とsuffixを付けたslugを返します。最大文字数を超える場合はbaseを切り詰めます。
+func appendSlugSuffix(base, suffix string) string {
+	suffix = "-" + suffix
+	runes := []rune(base)
+	if max := MaxSlugLength - len(suffix); len(runes) > max {
+		runes = runes[:max]
+	}
+	return strings.TrimRight(string(runes), "-") + suffix
+}
+
+// uniqueSlugはbaseが使用中であれば"-2"、"-3"…と連番を付けて未使用のslugを探します。
+// 問い合わせの回数を抑えるため、連番はmaxSlugNumberまでとし、それも使用中の場合は"-k3m9x2ab"のような
+// ランダムな接尾辞を付けます。ランダムなslugまで使用中の場合はErrSlugTakenを返します。
+func (s *PostService) uniqueSlug(ctx context.Context, base string, exceptID int64) (string, error) {
+	for n := 1; n <= maxSlugNumber; n++ {
+		candidate := base
+		if n > 1 {
+			candidate = slugWithSuffix(base, n)
+		}
+		taken, err := s.repo.SlugTaken(ctx, candidate, exceptID)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+
+	candidate := appendSlugSuffix(base, strings.ToLower(rand.Text()[:8]))
+	taken, err := s.repo.SlugTaken(ctx, candidate, exceptID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", repository.ErrSlugTaken
+	}
+	return candidate, nil
+}
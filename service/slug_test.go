@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitakitabauer/gin-sample-app/repository"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"My First Post", "my-first-post"},
+		{"  Hello, World!  ", "hello-world"},
+		{"Ｇｏ言語でＡＰＩ入門", "go言語でapi入門"},
+		{"はじめての投稿　その２", "はじめての投稿-その2"},
+		{"C++ & Go", "c-go"},
+		{"!!!", ""},
+		{strings.Repeat("a", MaxSlugLength+10), strings.Repeat("a", MaxSlugLength)},
+	}
+
+	for _, tc := range cases {
+		if got := slugify(tc.in); got != tc.want {
+			t.Errorf("slugify(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestSlugWithSuffix(t *testing.T) {
+	if got := slugWithSuffix("my-post", 2); got != "my-post-2" {
+		t.Fatalf("unexpected slug: %q", got)
+	}
+
+	long := strings.Repeat("あ", MaxSlugLength)
+	got := slugWithSuffix(long, 12)
+	if n := len([]rune(got)); n != MaxSlugLength || !strings.HasSuffix(got, "-12") {
+		t.Fatalf("expected %d runes ending in -12, got %d: %q", MaxSlugLength, n, got)
+	}
+}
+
+func TestPostService_UniqueSlugIsBounded(t *testing.T) {
+	var lookups int
+	posts := repository.NewInMemoryPostRepository()
+	repo := repository.NewInstrumentedPostRepository(posts, func(operation string, _ time.Duration) {
+		if operation == "slug_taken" {
+			lookups++
+		}
+	})
+	svc := NewPostService(repo, repository.NewInMemoryRevisionRepository(), repository.NewInMemoryAuthorRepository(posts))
+	ctx := context.Background()
+
+	for i := 1; i <= maxSlugNumber; i++ {
+		if _, err := svc.Create(ctx, CreatePostInput{Title: "Hello", Content: "c", Author: "a"}); err != nil {
+			t.Fatalf("Create returned error: %v", err)
+		}
+	}
+
+	// 連番がすべて使用中の場合は、問い合わせを増やさずにランダムな接尾辞を付けます。
+	lookups = 0
+	post, err := svc.Create(ctx, CreatePostInput{Title: "Hello", Content: "c", Author: "a"})
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if !regexp.MustCompile(`^hello-[a-z2-7]{8}$`).MatchString(post.Slug) {
+		t.Fatalf("expected a random suffix, got %q", post.Slug)
+	}
+	if lookups != maxSlugNumber+1 {
+		t.Fatalf("expected %d slug lookups, got %d", maxSlugNumber+1, lookups)
+	}
+}
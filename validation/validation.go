@@ -0,0 +1,147 @@
+// Package validationは入力の文字列を宣言的な規則で正規化・検証します。
+// 規則に違反したフィールドはValidatorにすべて集められ、1つのErrorとして返されます。
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 違反の種類を表すcodeです。クライアントが判別に使う固定の文字列です。
+const (
+	CodeRequired          = "required"
+	CodeTooLong           = "too_long"
+	CodeTooMany           = "too_many"
+	CodeInvalid           = "invalid"
+	CodeInvalidCharacters = "invalid_characters"
+	CodeInvalidEncoding   = "invalid_encoding"
+)
+
+// Violationは1つのフィールドの規則違反です。Errは違反の内容で、errors.Isで判別できます。
+type Violation struct {
+	Field string
+	Code  string
+	Err   error
+}
+
+// Errorは入力のすべての規則違反です。
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrapは各違反のErrを返します。errors.Is(err, service.ErrTitleRequired)のように個々の違反を判別できます。
+func (e *Error) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, v := range e.Violations {
+		errs[i] = v.Err
+	}
+	return errs
+}
+
+// Textは文字列の規則です。規則はUTF-8の検査、Unicodeの正規化、空白の整理、文字の検査、長さの検査の順に適用します。
+type Text struct {
+	// Requiredは空白を除いて空の値を許可しない場合にtrueにします。
+	Required bool
+	// MaxLengthは正規化後の最大の文字数(rune数)です。0の場合は制限しません。
+	MaxLength int
+	// NormalizeはUnicodeの正規化形式NFCに揃える場合にtrueにします。
+	Normalize bool
+	// CollapseSpaceは連続する空白を1つにまとめる場合にtrueにします。falseの場合は前後の空白だけを除きます。
+	CollapseSpace bool
+	// Multilineは改行とタブを許可する場合にtrueにします。それ以外の制御文字は常に拒否します。
+	Multiline bool
+	// Disallowedは制御文字に加えて拒否する文字です。
+	Disallowed []*unicode.RangeTable
+	// Errorsはcodeごとの違反のエラーです。指定しないcodeは"title is required"のような既定のメッセージになります。
+	Errors map[string]error
+}
+
+// Validatorは規則違反を集めます。ゼロ値で利用できます。
+type Validator struct {
+	violations []Violation
+}
+
+// Textはvalueにruleを適用し、正規化した値を返します。違反した場合はfieldの違反として記録します。
+func (v *Validator) Text(field, value string, rule Text) string {
+	if !utf8.ValidString(value) {
+		v.add(field, CodeInvalidEncoding, rule, "%s must be valid UTF-8", field)
+		return ""
+	}
+	if rule.Normalize {
+		value = norm.NFC.String(value)
+	}
+	if rule.CollapseSpace {
+		value = strings.Join(strings.Fields(value), " ")
+	} else {
+		value = strings.TrimSpace(value)
+	}
+
+	if value == "" {
+		if rule.Required {
+			v.add(field, CodeRequired, rule, "%s is required", field)
+		}
+		return value
+	}
+	if strings.IndexFunc(value, rule.disallowed) >= 0 {
+		v.add(field, CodeInvalidCharacters, rule, "%s contains characters that are not allowed", field)
+	}
+	if rule.MaxLength > 0 && utf8.RuneCountInString(value) > rule.MaxLength {
+		v.add(field, CodeTooLong, rule, "%s must be at most %d characters", field, rule.MaxLength)
+	}
+	return value
+}
+
+func (r Text) disallowed(c rune) bool {
+	if unicode.IsControl(c) {
+		return !r.Multiline || (c != '\n' && c != '\r' && c != '\t')
+	}
+	return unicode.IsOneOf(r.Disallowed, c)
+}
+
+func (v *Validator) add(field, code string, rule Text, format string, args ...any) {
+	err, ok := rule.Errors[code]
+	if !ok {
+		err = fmt.Errorf(format, args...)
+	}
+	v.Add(field, code, err)
+}
+
+// Addは規則以外の検査で見つかったfieldの違反を記録します。
+func (v *Validator) Add(field, code string, err error) {
+	v.violations = append(v.violations, Violation{Field: field, Code: code, Err: err})
+}
+
+// Validは違反が記録されていない場合にtrueを返します。
+func (v *Validator) Valid() bool {
+	return len(v.violations) == 0
+}
+
+// Errは違反が記録されている場合に*Errorを、それ以外はnilを返します。
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	return &Error{Violations: v.violations}
+}
+
+// Violationsはerrに含まれる違反を返します。errが*Errorでない場合はnilを返します。
+func Violations(err error) []Violation {
+	var verr *Error
+	if !errors.As(err, &verr) {
+		return nil
+	}
+	return verr.Violations
+}
@@ -0,0 +1,88 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+	"unicode"
+)
+
+func TestValidator_Text(t *testing.T) {
+	errRequired := errors.New("name is missing")
+	rule := Text{
+		Required:      true,
+		MaxLength:     3,
+		Normalize:     true,
+		CollapseSpace: true,
+		Disallowed:    []*unicode.RangeTable{unicode.Bidi_Control},
+		Errors:        map[string]error{CodeRequired: errRequired},
+	}
+
+	cases := []struct {
+		value string
+		want  string
+		code  string
+	}{
+		{" a   b ", "a b", ""},
+		{"é", "é", ""},
+		{"   ", "", CodeRequired},
+		{"abcd", "abcd", CodeTooLong},
+		{"a\x00", "a\x00", CodeInvalidCharacters},
+		{"a‮", "a‮", CodeInvalidCharacters},
+		{"\xff", "", CodeInvalidEncoding},
+	}
+	for _, tc := range cases {
+		var v Validator
+		got := v.Text("name", tc.value, rule)
+		if got != tc.want {
+			t.Fatalf("%q: expected %q, got %q", tc.value, tc.want, got)
+		}
+		violations := Violations(v.Err())
+		if tc.code == "" {
+			if !v.Valid() {
+				t.Fatalf("%q: expected no violations, got %v", tc.value, v.Err())
+			}
+			continue
+		}
+		if len(violations) != 1 || violations[0].Field != "name" || violations[0].Code != tc.code {
+			t.Fatalf("%q: expected %s, got %+v", tc.value, tc.code, violations)
+		}
+	}
+
+	var v Validator
+	v.Text("name", "", rule)
+	if !errors.Is(v.Err(), errRequired) {
+		t.Fatalf("expected the custom error, got %v", v.Err())
+	}
+}
+
+func TestValidator_Multiline(t *testing.T) {
+	var v Validator
+	if got := v.Text("body", "a\r\n\tb", Text{Multiline: true}); got != "a\r\n\tb" || !v.Valid() {
+		t.Fatalf("expected line breaks and tabs to be allowed, got %q (%v)", got, v.Err())
+	}
+	v.Text("body", "a\nb", Text{})
+	if v.Valid() {
+		t.Fatal("expected a line break to be rejected without Multiline")
+	}
+}
+
+func TestError_CollectsAllViolations(t *testing.T) {
+	errTitle := errors.New("title is required")
+	errTags := errors.New("too many tags")
+
+	var v Validator
+	v.Text("title", "", Text{Required: true, Errors: map[string]error{CodeRequired: errTitle}})
+	v.Text("content", "ok", Text{Required: true})
+	v.Add("tags", CodeTooMany, errTags)
+
+	err := v.Err()
+	if err == nil || err.Error() != "title is required; too many tags" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(err, errTitle) || !errors.Is(err, errTags) {
+		t.Fatalf("expected the error to wrap every violation, got %v", err)
+	}
+	if Violations(errors.New("other")) != nil {
+		t.Fatal("expected no violations for other errors")
+	}
+}